@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -141,6 +142,25 @@ func (app *ApiServer) requireAuthMiddleware(c *fiber.Ctx) error {
 	return c.Next()
 }
 
+// Middleware for debugging endpoints: open in dev and test,
+// otherwise the request must be signed by one of the admin wallets
+func (app *ApiServer) requireAdminOrDevMiddleware(c *fiber.Ctx) error {
+	switch app.env {
+	case "", "dev", "development", "test":
+		return c.Next()
+	}
+
+	authedWallet := app.getAuthedWallet(c)
+	if authedWallet == "" {
+		return fiber.NewError(fiber.StatusUnauthorized, "You must be logged in to make this request")
+	}
+	if !slices.Contains(app.adminWallets, authedWallet) {
+		return fiber.NewError(fiber.StatusForbidden, "You are not authorized to make this request")
+	}
+
+	return c.Next()
+}
+
 // Get a user from their wallet address.
 //
 // Note: Do NOT use this with `getAuthedWallet()` to infer the current user.
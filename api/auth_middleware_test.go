@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"io"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 
@@ -178,3 +179,40 @@ func TestWalletCache(t *testing.T) {
 		}
 	}
 }
+
+func TestRequireAdminOrDev(t *testing.T) {
+	app := emptyTestApp(t)
+
+	testApp := fiber.New()
+	testApp.Get("/", app.authMiddleware, app.requireAdminOrDevMiddleware, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	signed := func() *http.Request {
+		// wallet: 0x7d273271690538cf855e5b3002a0dd8c154bb060
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Encoded-Data-Message", "signature:1744763856446")
+		req.Header.Set("Encoded-Data-Signature", "0xbb202be3a7f3a0aa22c1458ef6a3f2f8360fb86791c7b137e8562df0707825c11fa1db01096efd2abc5e6613c4d1e8d4ae1e2b993abdd555fe270c1b17bff0d21c")
+		return req
+	}
+
+	// open in test
+	res, err := testApp.Test(httptest.NewRequest("GET", "/", nil), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, res.StatusCode)
+
+	app.env = "prod"
+
+	res, err = testApp.Test(httptest.NewRequest("GET", "/", nil), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, res.StatusCode)
+
+	res, err = testApp.Test(signed(), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, res.StatusCode)
+
+	app.adminWallets = []string{"0x7d273271690538cf855e5b3002a0dd8c154bb060"}
+	res, err = testApp.Test(signed(), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, res.StatusCode)
+}
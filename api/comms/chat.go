@@ -158,6 +158,7 @@ func chatUpdateLatestFields(db dbv1.DBTX, ctx context.Context, chatId string) er
 		where msg.created_at > COALESCE(member.last_active_at, '1970-01-01'::timestamp)
 		and msg.user_id != member.user_id
 		and msg.chat_id = member.chat_id
+		and msg.deleted_at is null
 	)
 	WHERE member.chat_id = $1
 	`, chatId)
@@ -188,6 +189,90 @@ func chatSendMessage(db dbv1.DBTX, ctx context.Context, userId int32, chatId str
 	return err
 }
 
+func chatInsertMessageAttachments(db dbv1.DBTX, ctx context.Context, messageId string, attachments []ChatMessageAttachment) error {
+	for ordinal, attachment := range attachments {
+		var entityId *int32
+		if attachment.ID != nil {
+			id, err := trashid.DecodeHashId(*attachment.ID)
+			if err != nil {
+				return err
+			}
+			entityId = new(int32)
+			*entityId = int32(id)
+		}
+
+		_, err := db.Exec(ctx, `
+		insert into chat_message_attachments
+			(message_id, ordinal, type, cid, mime_type, entity_id)
+		values
+			($1, $2, $3, $4, $5, $6)
+		on conflict do nothing`,
+			messageId, ordinal, attachment.Type, attachment.CID, attachment.MimeType, entityId)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func chatEditMessage(db dbv1.DBTX, ctx context.Context, userId int32, chatId string, messageId string, ciphertext string, editTimestamp time.Time) error {
+	// the replaced version goes into edit_history with when it was written.
+	// Edits applied out of order are ignored if a later one already was,
+	// as are edits past MessageEditWindow or MaxMessageEdits.
+	_, err := db.Exec(ctx, `
+	update chat_message
+	set
+		edit_history = edit_history || jsonb_build_array(jsonb_build_object(
+			'ciphertext', ciphertext,
+			'created_at', coalesce(edited_at, created_at)
+		)),
+		ciphertext = $4,
+		edited_at = $5
+	where message_id = $1
+		and chat_id = $2
+		and user_id = $3
+		and blast_id is null
+		and deleted_at is null
+		and (edited_at is null or edited_at < $5)
+		and created_at >= $6
+		and jsonb_array_length(edit_history) < $7`,
+		messageId, chatId, userId, ciphertext, editTimestamp.UTC(), editTimestamp.Add(-MessageEditWindow).UTC(), MaxMessageEdits)
+	if err != nil {
+		return err
+	}
+
+	// the edited message may be the chat's last message
+	return chatUpdateLatestFields(db, ctx, chatId)
+}
+
+func chatDeleteMessage(db dbv1.DBTX, ctx context.Context, userId int32, chatId string, messageId string, deleteTimestamp time.Time) error {
+	// the row stays behind so the message shows as unsent.
+	// Unsends past MessageEditWindow are ignored.
+	result, err := db.Exec(ctx, `
+	update chat_message
+	set ciphertext = null, edit_history = '[]'::jsonb, deleted_at = $4
+	where message_id = $1
+		and chat_id = $2
+		and user_id = $3
+		and blast_id is null
+		and deleted_at is null
+		and created_at >= $5`,
+		messageId, chatId, userId, deleteTimestamp.UTC(), deleteTimestamp.Add(-MessageEditWindow).UTC())
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return nil
+	}
+
+	_, err = db.Exec(ctx, "delete from chat_message_attachments where message_id = $1", messageId)
+	if err != nil {
+		return err
+	}
+
+	return chatUpdateLatestFields(db, ctx, chatId)
+}
+
 func chatReactMessage(db dbv1.DBTX, ctx context.Context, userId int32, chatId string, messageId string, reaction *string, messageTimestamp time.Time) error {
 	var err error
 	if reaction != nil {
@@ -0,0 +1,81 @@
+package comms
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bridgerton.audius.co/database"
+	"bridgerton.audius.co/trashid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestValidateChatMessageAttachments(t *testing.T) {
+	cid := "bafkreiexample"
+	png := "image/png"
+	pdf := "application/pdf"
+	trackId := trashid.MustEncodeHashID(10)
+	badId := "not-a-hash-id"
+
+	assert.NoError(t, validateChatMessageAttachments(nil))
+	assert.NoError(t, validateChatMessageAttachments([]ChatMessageAttachment{
+		{Type: AttachmentMedia, CID: &cid, MimeType: &png},
+		{Type: AttachmentTrack, ID: &trackId},
+	}))
+
+	tooMany := make([]ChatMessageAttachment, maxAttachmentsPerMessage+1)
+	for i := range tooMany {
+		tooMany[i] = ChatMessageAttachment{Type: AttachmentUser, ID: &trackId}
+	}
+
+	for name, attachments := range map[string][]ChatMessageAttachment{
+		"too many":           tooMany,
+		"media without cid":  {{Type: AttachmentMedia, MimeType: &png}},
+		"not image or audio": {{Type: AttachmentMedia, CID: &cid, MimeType: &pdf}},
+		"track without id":   {{Type: AttachmentTrack}},
+		"bad id":             {{Type: AttachmentPlaylist, ID: &badId}},
+		"track with a cid":   {{Type: AttachmentTrack, ID: &trackId, CID: &cid}},
+		"unknown type":       {{Type: "video", CID: &cid}},
+	} {
+		assert.Error(t, validateChatMessageAttachments(attachments), name)
+	}
+}
+
+func TestChatMessageAttachments(t *testing.T) {
+	pool := database.CreateTestDatabase(t, "test_comms")
+	defer pool.Close()
+
+	ctx := context.Background()
+
+	chatId := trashid.ChatID(1, 2)
+	SetupChatWithMembers(t, pool, ctx, chatId, 1, 2, "invite1", "invite2")
+
+	cid := "bafkreiexample"
+	mimeType := "audio/mpeg"
+	trackId := trashid.MustEncodeHashID(10)
+	attachments := []ChatMessageAttachment{
+		{Type: AttachmentTrack, ID: &trackId},
+		{Type: AttachmentMedia, CID: &cid, MimeType: &mimeType},
+	}
+
+	sentAt := time.Now().UTC()
+	require.NoError(t, chatSendMessage(pool, ctx, 1, chatId, "m1", sentAt, "listen"))
+	require.NoError(t, chatInsertMessageAttachments(pool, ctx, "m1", attachments))
+
+	// replayed over websockets just as they were sent
+	proc := &RPCProcessor{writePool: pool}
+	events, err := proc.missedWebsocketEvents(ctx, 2, sentAt.Add(-time.Second))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.JSONEq(t,
+		`[{"type": "track", "id": "`+trackId+`"}, {"type": "media", "cid": "bafkreiexample", "mime_type": "audio/mpeg"}]`,
+		gjson.GetBytes(events[0].rpcJson, "params.attachments").Raw)
+
+	// and gone once the message is unsent
+	require.NoError(t, chatDeleteMessage(pool, ctx, 1, chatId, "m1", sentAt.Add(time.Second)))
+	var count int
+	require.NoError(t, pool.QueryRow(ctx, `select count(*) from chat_message_attachments where message_id = 'm1'`).Scan(&count))
+	assert.Equal(t, 0, count)
+}
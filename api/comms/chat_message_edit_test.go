@@ -0,0 +1,160 @@
+package comms
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"bridgerton.audius.co/database"
+	"bridgerton.audius.co/trashid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatMessageEdit(t *testing.T) {
+	pool := database.CreateTestDatabase(t, "test_comms")
+	defer pool.Close()
+
+	ctx := context.Background()
+
+	chatId := trashid.ChatID(1, 2)
+	SetupChatWithMembers(t, pool, ctx, chatId, 1, 2, "invite1", "invite2")
+
+	sentAt := time.Now().Add(-time.Minute).UTC().Round(time.Microsecond)
+	require.NoError(t, chatSendMessage(pool, ctx, 1, chatId, "m1", sentAt, "helo"))
+	require.NoError(t, chatSendMessage(pool, ctx, 1, chatId, "old", sentAt.Add(-MessageEditWindow*2), "long ago"))
+
+	validator := CreateTestValidator(t, pool, DefaultRateLimitConfig, DefaultTestValidatorConfig)
+
+	signedAt := sentAt.Add(time.Minute)
+	editRpcAt := func(messageId string, signedAt time.Time) RawRPC {
+		return RawRPC{
+			Params:    []byte(fmt.Sprintf(`{"chat_id": "%s", "message_id": "%s", "message": "hello"}`, chatId, messageId)),
+			Timestamp: signedAt.UnixMilli(),
+		}
+	}
+	editRpc := func(messageId string) RawRPC {
+		return editRpcAt(messageId, signedAt)
+	}
+
+	// only the sender, only recently
+	assert.NoError(t, validator.validateChatMessageEdit(ctx, 1, editRpc("m1")))
+	err := validator.validateChatMessageEdit(ctx, 2, editRpc("m1"))
+	assert.ErrorContains(t, err, "only the sender")
+	err = validator.validateChatMessageEdit(ctx, 1, editRpc("old"))
+	assert.ErrorContains(t, err, "can only be changed")
+	err = validator.validateChatMessageEdit(ctx, 1, editRpc("nope"))
+	assert.ErrorContains(t, err, "does not exist")
+
+	// recently is as of when the rpc was signed, which has to be about now
+	err = validator.validateChatMessageEdit(ctx, 1, editRpcAt("old", sentAt.Add(-MessageEditWindow*2+time.Minute)))
+	assert.ErrorContains(t, err, "from the current time")
+	err = validator.validateChatMessageEdit(ctx, 1, editRpcAt("m1", sentAt.Add(MessageEditWindow+time.Second)))
+	assert.ErrorContains(t, err, "from the current time")
+	err = validator.validateChatMessageEdit(ctx, 1, RawRPC{Params: editRpc("m1").Params})
+	assert.Error(t, err)
+
+	type messageState struct {
+		Ciphertext  pgtype.Text
+		EditedAt    pgtype.Timestamp
+		DeletedAt   pgtype.Timestamp
+		EditHistory []map[string]any
+	}
+	getMessage := func(messageId string) messageState {
+		var m messageState
+		err := pool.QueryRow(ctx, `select ciphertext, edited_at, deleted_at, edit_history from chat_message where message_id = $1`, messageId).
+			Scan(&m.Ciphertext, &m.EditedAt, &m.DeletedAt, &m.EditHistory)
+		require.NoError(t, err)
+		return m
+	}
+
+	// edits keep what they replaced
+	editedAt := sentAt.Add(10 * time.Second)
+	require.NoError(t, chatEditMessage(pool, ctx, 1, chatId, "m1", "hello", editedAt))
+	require.NoError(t, chatEditMessage(pool, ctx, 1, chatId, "m1", "hello!", editedAt.Add(time.Second)))
+
+	// an edit applied late doesn't overwrite a later one
+	require.NoError(t, chatEditMessage(pool, ctx, 1, chatId, "m1", "hullo", editedAt.Add(-time.Second)))
+
+	// nor can anyone else's
+	require.NoError(t, chatEditMessage(pool, ctx, 2, chatId, "m1", "pwned", editedAt.Add(2*time.Second)))
+
+	m := getMessage("m1")
+	assert.Equal(t, "hello!", m.Ciphertext.String)
+	assert.Equal(t, editedAt.Add(time.Second), m.EditedAt.Time)
+	require.Len(t, m.EditHistory, 2)
+	assert.Equal(t, "helo", m.EditHistory[0]["ciphertext"])
+	assert.Equal(t, "hello", m.EditHistory[1]["ciphertext"])
+
+	// edits past the window aren't applied
+	require.NoError(t, chatEditMessage(pool, ctx, 1, chatId, "m1", "too late", sentAt.Add(MessageEditWindow+time.Second)))
+	assert.Equal(t, "hello!", getMessage("m1").Ciphertext.String)
+
+	var lastMessage string
+	require.NoError(t, pool.QueryRow(ctx, `select last_message from chat where chat_id = $1`, chatId).Scan(&lastMessage))
+	assert.Equal(t, "hello!", lastMessage)
+
+	// unsending leaves a tombstone without the text
+	deleteRpc := RawRPC{
+		Params:    []byte(fmt.Sprintf(`{"chat_id": "%s", "message_id": "m1"}`, chatId)),
+		Timestamp: signedAt.UnixMilli(),
+	}
+	assert.NoError(t, validator.validateChatMessageDelete(ctx, 1, deleteRpc))
+	assert.Error(t, validator.validateChatMessageDelete(ctx, 2, deleteRpc))
+
+	// only recent messages, however the rpc is dated
+	oldDeleteRpc := RawRPC{
+		Params:    []byte(fmt.Sprintf(`{"chat_id": "%s", "message_id": "old"}`, chatId)),
+		Timestamp: sentAt.Add(-MessageEditWindow * 2).Add(time.Minute).UnixMilli(),
+	}
+	assert.ErrorContains(t, validator.validateChatMessageDelete(ctx, 1, oldDeleteRpc), "from the current time")
+	oldDeleteRpc.Timestamp = signedAt.UnixMilli()
+	assert.ErrorContains(t, validator.validateChatMessageDelete(ctx, 1, oldDeleteRpc), "can only be changed")
+	require.NoError(t, chatDeleteMessage(pool, ctx, 1, chatId, "old", signedAt))
+	assert.Equal(t, "long ago", getMessage("old").Ciphertext.String)
+
+	deletedAt := editedAt.Add(5 * time.Second)
+	require.NoError(t, chatDeleteMessage(pool, ctx, 1, chatId, "m1", deletedAt))
+
+	m = getMessage("m1")
+	assert.False(t, m.Ciphertext.Valid)
+	assert.Equal(t, deletedAt, m.DeletedAt.Time)
+	assert.Empty(t, m.EditHistory)
+
+	err = validator.validateChatMessageEdit(ctx, 1, editRpc("m1"))
+	assert.ErrorContains(t, err, "deleted")
+
+	// and isn't unread
+	var unreadCount int
+	require.NoError(t, pool.QueryRow(ctx, `select unread_count from chat_member where chat_id = $1 and user_id = 2`, chatId).Scan(&unreadCount))
+	assert.Equal(t, 1, unreadCount)
+
+	rpcJson, ts, err := chatMessageEditRPCJson(editedChatMessage{
+		MessageID: "m1",
+		ChatID:    chatId,
+		UserID:    1,
+		DeletedAt: pgtype.Timestamp{Time: deletedAt, Valid: true},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, fmt.Sprintf(`{"method": "chat.message.delete", "params": {"chat_id": "%s", "message_id": "m1"}}`, chatId), string(rpcJson))
+	assert.Equal(t, deletedAt, ts)
+
+	// edits stop at MaxMessageEdits
+	require.NoError(t, chatSendMessage(pool, ctx, 1, chatId, "busy", sentAt, "v0"))
+	for i := 1; i <= MaxMessageEdits+1; i++ {
+		require.NoError(t, chatEditMessage(pool, ctx, 1, chatId, "busy", fmt.Sprintf("v%d", i), sentAt.Add(time.Duration(i)*time.Second)))
+	}
+	busy := getMessage("busy")
+	assert.Equal(t, fmt.Sprintf("v%d", MaxMessageEdits), busy.Ciphertext.String)
+	assert.Len(t, busy.EditHistory, MaxMessageEdits)
+	err = validator.validateChatMessageEdit(ctx, 1, editRpc("busy"))
+	assert.ErrorContains(t, err, "can only be edited")
+
+	// nor before it was sent
+	justSentAt := time.Now().UTC().Round(time.Microsecond)
+	require.NoError(t, chatSendMessage(pool, ctx, 1, chatId, "new", justSentAt, "hi"))
+	err = validator.validateChatMessageEdit(ctx, 1, editRpcAt("new", justSentAt.Add(-10*time.Second)))
+	assert.ErrorContains(t, err, "before the message was sent")
+}
@@ -1,6 +1,16 @@
 package comms
 
+import "time"
+
 var (
 	SigHeader             = "x-sig"
 	SignatureTimeToLiveMs = int64(1000 * 60 * 60 * 12) // 12 hours
+	MessageEditWindow     = 15 * time.Minute           // how long the sender can edit or unsend a message
+	MaxMessageEdits       = 10                         // how many times the sender can edit a message
+	MaxRpcClockSkew       = time.Minute                // how far the signed timestamp of an edit or unsend can be from the relay's clock
+)
+
+const (
+	maxAttachmentsPerMessage = 10
+	maxAttachmentCIDLength   = 128
 )
@@ -28,12 +28,17 @@ var (
 	chatMessageInsertedChannel = "chat_message_inserted"
 	chatBlastInsertedChannel   = "chat_blast_inserted"
 	chatMessageReactionChanged = "chat_message_reaction_changed"
+	chatMessageEditedChannel   = "chat_message_edited"
 )
 
 type chatMessageInsertedNotification struct {
 	MessageID string `json:"message_id"`
 }
 
+type chatMessageEditedNotification struct {
+	MessageID string `json:"message_id"`
+}
+
 type chatBlastInsertedNotification struct {
 	BlastID string `json:"blast_id"`
 }
@@ -69,7 +74,11 @@ func NewProcessor(pool *dbv1.DBPools, writePool *pgxpool.Pool, config *config.Co
 	var cancel context.CancelFunc
 	if config.CommsMessagePush {
 		ctx, cancel = context.WithCancel(context.Background())
-		websocketManager = NewCommsWebsocketManager(logger)
+		fanout := NewMemoryFanout()
+		if config.CommsFanout == "postgres" {
+			fanout = NewPostgresFanout(writePool, logger)
+		}
+		websocketManager = NewCommsWebsocketManager(pool, logger, fanout)
 	}
 
 	proc := &RPCProcessor{
@@ -85,6 +94,14 @@ func NewProcessor(pool *dbv1.DBPools, writePool *pgxpool.Pool, config *config.Co
 
 	if config.CommsMessagePush {
 		proc.startPgNotifyListeners()
+
+		proc.listenWg.Add(1)
+		go func() {
+			defer proc.listenWg.Done()
+			if err := websocketManager.Run(ctx); err != nil {
+				logger.Error("Comms websocket fanout failed", zap.Error(err))
+			}
+		}()
 	}
 
 	return proc, nil
@@ -105,7 +122,7 @@ func (proc *RPCProcessor) Shutdown() {
 
 	// Wait for the listener goroutine to finish
 	proc.listenWg.Wait()
-	proc.logger.Info("Stopped listening for comms chat_message_inserted, chat_blast_inserted, and chat_message_reaction_inserted notifications")
+	proc.logger.Info("Stopped listening for comms chat_message_inserted, chat_message_edited, chat_blast_inserted, and chat_message_reaction_inserted notifications")
 }
 
 func (proc *RPCProcessor) Validate(ctx context.Context, userId int32, rawRpc RawRPC) error {
@@ -236,6 +253,30 @@ func (proc *RPCProcessor) Apply(ctx context.Context, rpcLog *RpcLog) error {
 			if err != nil {
 				return err
 			}
+			err = chatInsertMessageAttachments(tx, ctx, params.MessageID, params.Attachments)
+			if err != nil {
+				return err
+			}
+		case RPCMethodChatMessageEdit:
+			var params ChatMessageEditRPCParams
+			err = json.Unmarshal(rawRpc.Params, &params)
+			if err != nil {
+				return err
+			}
+			err = chatEditMessage(tx, ctx, userId, params.ChatID, params.MessageID, params.Message, messageTs)
+			if err != nil {
+				return err
+			}
+		case RPCMethodChatMessageDelete:
+			var params ChatMessageDeleteRPCParams
+			err = json.Unmarshal(rawRpc.Params, &params)
+			if err != nil {
+				return err
+			}
+			err = chatDeleteMessage(tx, ctx, userId, params.ChatID, params.MessageID, messageTs)
+			if err != nil {
+				return err
+			}
 		case RPCMethodChatReact:
 			var params ChatReactRPCParams
 			err = json.Unmarshal(rawRpc.Params, &params)
@@ -425,6 +466,7 @@ func (proc *RPCProcessor) startPgNotifyListeners() error {
 	proc.listener.Handle(chatMessageInsertedChannel, pgxlisten.HandlerFunc(proc.handleChatMessageInserted))
 	proc.listener.Handle(chatBlastInsertedChannel, pgxlisten.HandlerFunc(proc.handleChatBlastInserted))
 	proc.listener.Handle(chatMessageReactionChanged, pgxlisten.HandlerFunc(proc.handleChatMessageReactionChanged))
+	proc.listener.Handle(chatMessageEditedChannel, pgxlisten.HandlerFunc(proc.handleChatMessageEdited))
 
 	// Start listening in a goroutine
 	proc.listenWg.Add(1)
@@ -435,7 +477,7 @@ func (proc *RPCProcessor) startPgNotifyListeners() error {
 		}
 	}()
 
-	proc.logger.Info("Started listening for comms chat_message_inserted, chat_blast_inserted, and chat_message_reaction_inserted notifications")
+	proc.logger.Info("Started listening for comms chat_message_inserted, chat_message_edited, chat_blast_inserted, and chat_message_reaction_inserted notifications")
 	return nil
 }
 
@@ -451,12 +493,13 @@ func (proc *RPCProcessor) handleChatMessageInserted(ctx context.Context, notific
 	}
 
 	type InsertedChatMessage struct {
-		MessageID   string      `db:"message_id"`
-		ChatID      string      `db:"chat_id"`
-		UserID      int32       `db:"user_id"`
-		CreatedAt   time.Time   `db:"created_at"`
-		Ciphertext  pgtype.Text `db:"ciphertext"`
-		IsPlaintext bool        `db:"is_plaintext"`
+		MessageID   string                  `db:"message_id"`
+		ChatID      string                  `db:"chat_id"`
+		UserID      int32                   `db:"user_id"`
+		CreatedAt   time.Time               `db:"created_at"`
+		Ciphertext  pgtype.Text             `db:"ciphertext"`
+		IsPlaintext bool                    `db:"is_plaintext"`
+		Attachments []ChatMessageAttachment `db:"attachments"`
 	}
 	// Joins on blasts to get message text if the origin was a blast
 	row, err := proc.writePool.Query(ctx, `
@@ -466,7 +509,8 @@ func (proc *RPCProcessor) handleChatMessageInserted(ctx context.Context, notific
 			chat_message.user_id,
 			chat_message.created_at,
 			COALESCE(chat_message.ciphertext, chat_blast.plaintext) AS ciphertext,
-			chat_blast.plaintext IS NOT NULL as is_plaintext
+			chat_blast.plaintext IS NOT NULL as is_plaintext,
+			`+chatMessageAttachmentsSQL+` AS attachments
 		FROM chat_message
 		JOIN chat_member ON chat_message.chat_id = chat_member.chat_id
 		LEFT JOIN chat_blast USING (blast_id)
@@ -493,17 +537,7 @@ func (proc *RPCProcessor) handleChatMessageInserted(ctx context.Context, notific
 		return err
 	}
 
-	messageData := ChatMessageRPC{
-		Method: MethodChatMessage,
-		Params: ChatMessageRPCParams{
-			ChatID:      chatMessage.ChatID,
-			MessageID:   chatMessage.MessageID,
-			IsPlaintext: &chatMessage.IsPlaintext,
-			Message:     chatMessage.Ciphertext.String,
-		},
-	}
-
-	messageJson, err := json.Marshal(messageData)
+	messageJson, err := chatMessageRPCJson(chatMessage.ChatID, chatMessage.MessageID, chatMessage.Ciphertext.String, chatMessage.IsPlaintext, chatMessage.Attachments)
 	if err != nil {
 		proc.logger.Error("Failed to marshal message data", zap.Error(err))
 		return err
@@ -521,6 +555,59 @@ func (proc *RPCProcessor) handleChatMessageInserted(ctx context.Context, notific
 	return nil
 }
 
+func (proc *RPCProcessor) handleChatMessageEdited(ctx context.Context, notification *pgconn.Notification, conn *pgx.Conn) error {
+	proc.logger.Debug("Received PostgreSQL notification for chat message edit",
+		zap.String("channel", notification.Channel),
+		zap.String("payload", notification.Payload))
+
+	var payload chatMessageEditedNotification
+	if err := json.Unmarshal([]byte(notification.Payload), &payload); err != nil {
+		proc.logger.Error("Failed to parse chat message edit notification payload", zap.Error(err))
+		return err
+	}
+
+	row, err := proc.writePool.Query(ctx, `
+		SELECT message_id, chat_id, user_id, ciphertext, edited_at, deleted_at
+		FROM chat_message
+		WHERE message_id = $1`, payload.MessageID)
+	if err != nil {
+		proc.logger.Error("Failed to query chat message", zap.Error(err))
+		return err
+	}
+	chatMessage, err := pgx.CollectOneRow(row, pgx.RowToStructByName[editedChatMessage])
+	if err != nil {
+		proc.logger.Error("Failed to collect chat message", zap.Error(err))
+		return err
+	}
+
+	// Get chat members to notify
+	userRows, err := proc.writePool.Query(ctx, `select user_id from chat_member where chat_id = $1 and is_hidden = false`, chatMessage.ChatID)
+	if err != nil {
+		proc.logger.Error("failed to load chat members for websocket push " + err.Error())
+		return err
+	}
+	userIds, err := pgx.CollectRows(userRows, pgx.RowTo[int32])
+	if err != nil {
+		proc.logger.Error("failed to collect user ids for websocket push " + err.Error())
+		return err
+	}
+
+	editJson, editTs, err := chatMessageEditRPCJson(chatMessage)
+	if err != nil {
+		proc.logger.Error("Failed to marshal message edit data", zap.Error(err))
+		return err
+	}
+
+	// Send to all chat members except the sender
+	for _, receiverUserId := range userIds {
+		if receiverUserId != chatMessage.UserID {
+			proc.websocketManager.WebsocketPush(chatMessage.UserID, receiverUserId, editJson, editTs)
+		}
+	}
+
+	return nil
+}
+
 func (proc *RPCProcessor) handleChatBlastInserted(ctx context.Context, notification *pgconn.Notification, conn *pgx.Conn) error {
 	proc.logger.Debug("Received PostgreSQL notification for chat blast",
 		zap.String("channel", notification.Channel),
@@ -546,29 +633,7 @@ func (proc *RPCProcessor) handleChatBlastInserted(ctx context.Context, notificat
 		return err
 	}
 
-	blastData := ChatBlastRPC{
-		Method: MethodChatBlast,
-		Params: ChatBlastRPCParams{
-			BlastID:  chatBlast.BlastID,
-			Audience: ChatBlastAudience(chatBlast.Audience),
-			Message:  chatBlast.Plaintext,
-		},
-	}
-
-	if chatBlast.AudienceContentID.Valid {
-		audienceContentID, err := trashid.EncodeHashId(int(chatBlast.AudienceContentID.Int32))
-		if err != nil {
-			proc.logger.Error("Failed to encode audience content id", zap.Error(err))
-			return err
-		}
-		blastData.Params.AudienceContentID = &audienceContentID
-	}
-	if chatBlast.AudienceContentType.Valid {
-		audienceContentType := AudienceContentType(chatBlast.AudienceContentType.String)
-		blastData.Params.AudienceContentType = &audienceContentType
-	}
-
-	blastJson, err := json.Marshal(blastData)
+	blastJson, err := chatBlastRPCJson(chatBlast)
 	if err != nil {
 		proc.logger.Error("Failed to marshal blast data", zap.Error(err))
 		return err
@@ -616,16 +681,7 @@ func (proc *RPCProcessor) handleChatMessageReactionChanged(ctx context.Context,
 		return err
 	}
 
-	reactionData := ChatReactRPC{
-		Method: MethodChatReact,
-		Params: ChatReactRPCParams{
-			ChatID:    chatID,
-			MessageID: payload.MessageID,
-			Reaction:  payload.Reaction,
-		},
-	}
-
-	reactionJson, err := json.Marshal(reactionData)
+	reactionJson, err := chatReactRPCJson(chatID, payload.MessageID, payload.Reaction)
 	if err != nil {
 		proc.logger.Error("Failed to marshal reaction data", zap.Error(err))
 		return err
@@ -644,8 +700,126 @@ func (proc *RPCProcessor) handleChatMessageReactionChanged(ctx context.Context,
 	return nil
 }
 
-func (proc *RPCProcessor) RegisterWebsocket(userId int32, conn *websocket.Conn) {
-	proc.websocketManager.RegisterWebsocket(userId, conn)
+// The rpcs pushed over websockets, built the same way for live pushes and replays
+
+// A chat_message's attachments, as chat.message rpcs carry them
+const chatMessageAttachmentsSQL = `(
+	SELECT COALESCE(json_agg(json_build_object(
+		'type', a.type,
+		'cid', a.cid,
+		'mime_type', a.mime_type,
+		'id', CASE WHEN a.entity_id IS NOT NULL THEN id_encode(a.entity_id) END
+	) ORDER BY a.ordinal), '[]'::json)
+	FROM chat_message_attachments a
+	WHERE a.message_id = chat_message.message_id
+)`
+
+func chatMessageRPCJson(chatId, messageId, message string, isPlaintext bool, attachments []ChatMessageAttachment) (json.RawMessage, error) {
+	return json.Marshal(ChatMessageRPC{
+		Method: MethodChatMessage,
+		Params: ChatMessageRPCParams{
+			ChatID:      chatId,
+			MessageID:   messageId,
+			IsPlaintext: &isPlaintext,
+			Message:     message,
+			Attachments: attachments,
+		},
+	})
+}
+
+// A message's latest edit or its deletion
+type editedChatMessage struct {
+	MessageID  string           `db:"message_id"`
+	ChatID     string           `db:"chat_id"`
+	UserID     int32            `db:"user_id"`
+	Ciphertext pgtype.Text      `db:"ciphertext"`
+	EditedAt   pgtype.Timestamp `db:"edited_at"`
+	DeletedAt  pgtype.Timestamp `db:"deleted_at"`
+}
+
+// The chat.message.delete of a deleted message, otherwise the chat.message.edit, and when it happened
+func chatMessageEditRPCJson(m editedChatMessage) (json.RawMessage, time.Time, error) {
+	if m.DeletedAt.Valid {
+		rpcJson, err := json.Marshal(ChatMessageDeleteRPC{
+			Method: MethodChatMessageDelete,
+			Params: ChatMessageDeleteRPCParams{
+				ChatID:    m.ChatID,
+				MessageID: m.MessageID,
+			},
+		})
+		return rpcJson, m.DeletedAt.Time.UTC(), err
+	}
+
+	rpcJson, err := json.Marshal(ChatMessageEditRPC{
+		Method: MethodChatMessageEdit,
+		Params: ChatMessageEditRPCParams{
+			ChatID:    m.ChatID,
+			MessageID: m.MessageID,
+			Message:   m.Ciphertext.String,
+		},
+	})
+	return rpcJson, m.EditedAt.Time.UTC(), err
+}
+
+func chatReactRPCJson(chatId, messageId string, reaction *string) (json.RawMessage, error) {
+	return json.Marshal(ChatReactRPC{
+		Method: MethodChatReact,
+		Params: ChatReactRPCParams{
+			ChatID:    chatId,
+			MessageID: messageId,
+			Reaction:  reaction,
+		},
+	})
+}
+
+func chatBlastRPCJson(chatBlast dbv1.ChatBlast) (json.RawMessage, error) {
+	blastData := ChatBlastRPC{
+		Method: MethodChatBlast,
+		Params: ChatBlastRPCParams{
+			BlastID:  chatBlast.BlastID,
+			Audience: ChatBlastAudience(chatBlast.Audience),
+			Message:  chatBlast.Plaintext,
+		},
+	}
+
+	if chatBlast.AudienceContentID.Valid {
+		audienceContentID, err := trashid.EncodeHashId(int(chatBlast.AudienceContentID.Int32))
+		if err != nil {
+			return nil, err
+		}
+		blastData.Params.AudienceContentID = &audienceContentID
+	}
+	if chatBlast.AudienceContentType.Valid {
+		audienceContentType := AudienceContentType(chatBlast.AudienceContentType.String)
+		blastData.Params.AudienceContentType = &audienceContentType
+	}
+
+	return json.Marshal(blastData)
+}
+
+// RegisterWebsocket replays what the user missed since since, if it's set,
+// and pushes to them until the websocket closes
+func (proc *RPCProcessor) RegisterWebsocket(userId int32, conn *websocket.Conn, since *time.Time) {
+	var missed func(ctx context.Context) ([]websocketEvent, error)
+	if since != nil {
+		missed = func(ctx context.Context) ([]websocketEvent, error) {
+			return proc.missedWebsocketEvents(ctx, userId, *since)
+		}
+	}
+	proc.websocketManager.RegisterWebsocket(userId, conn, missed)
+}
+
+// Presence returns whether each of the users is online, away or offline.
+// Without websockets, everyone is offline.
+func (proc *RPCProcessor) Presence(ctx context.Context, userIds []int32) (map[int32]PresenceStatus, error) {
+	if proc.websocketManager == nil {
+		presence := make(map[int32]PresenceStatus, len(userIds))
+		for _, userId := range userIds {
+			presence[userId] = PresenceOffline
+		}
+		return presence, nil
+	}
+	return proc.websocketManager.Presence(ctx, userIds)
 }
 
 func (proc *RPCProcessor) SetPubkeyForUser(userId int32, pubkey *ecdsa.PublicKey) {
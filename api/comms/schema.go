@@ -67,12 +67,42 @@ type ChatMessageRPC struct {
 }
 
 type ChatMessageRPCParams struct {
-	ChatID          string             `json:"chat_id"`
-	IsPlaintext     *bool              `json:"is_plaintext,omitempty"`
-	Message         string             `json:"message"`
-	MessageID       string             `json:"message_id"`
-	ParentMessageID *string            `json:"parent_message_id,omitempty"`
-	Audience        *ChatBlastAudience `json:"audience,omitempty"`
+	ChatID          string                  `json:"chat_id"`
+	IsPlaintext     *bool                   `json:"is_plaintext,omitempty"`
+	Message         string                  `json:"message"`
+	MessageID       string                  `json:"message_id"`
+	ParentMessageID *string                 `json:"parent_message_id,omitempty"`
+	Audience        *ChatBlastAudience      `json:"audience,omitempty"`
+	Attachments     []ChatMessageAttachment `json:"attachments,omitempty"`
+}
+
+// Media has a cid and mime_type, the others the hash id of what's attached
+type ChatMessageAttachment struct {
+	Type     ChatMessageAttachmentType `json:"type"`
+	CID      *string                   `json:"cid,omitempty"`
+	MimeType *string                   `json:"mime_type,omitempty"`
+	ID       *string                   `json:"id,omitempty"`
+}
+
+type ChatMessageEditRPC struct {
+	Method ChatMessageEditRPCMethod `json:"method"`
+	Params ChatMessageEditRPCParams `json:"params"`
+}
+
+type ChatMessageEditRPCParams struct {
+	ChatID    string `json:"chat_id"`
+	Message   string `json:"message"`
+	MessageID string `json:"message_id"`
+}
+
+type ChatMessageDeleteRPC struct {
+	Method ChatMessageDeleteRPCMethod `json:"method"`
+	Params ChatMessageDeleteRPCParams `json:"params"`
+}
+
+type ChatMessageDeleteRPCParams struct {
+	ChatID    string `json:"chat_id"`
+	MessageID string `json:"message_id"`
 }
 
 type ChatReactRPC struct {
@@ -355,6 +385,27 @@ const (
 	MethodChatMessage ChatMessageRPCMethod = "chat.message"
 )
 
+type ChatMessageEditRPCMethod string
+
+const (
+	MethodChatMessageEdit ChatMessageEditRPCMethod = "chat.message.edit"
+)
+
+type ChatMessageDeleteRPCMethod string
+
+const (
+	MethodChatMessageDelete ChatMessageDeleteRPCMethod = "chat.message.delete"
+)
+
+type ChatMessageAttachmentType string
+
+const (
+	AttachmentMedia    ChatMessageAttachmentType = "media"
+	AttachmentTrack    ChatMessageAttachmentType = "track"
+	AttachmentPlaylist ChatMessageAttachmentType = "playlist"
+	AttachmentUser     ChatMessageAttachmentType = "user"
+)
+
 type ChatReactRPCMethod string
 
 const (
@@ -407,6 +458,8 @@ const (
 	RPCMethodChatDelete          RPCMethod = "chat.delete"
 	RPCMethodChatInvite          RPCMethod = "chat.invite"
 	RPCMethodChatMessage         RPCMethod = "chat.message"
+	RPCMethodChatMessageDelete   RPCMethod = "chat.message.delete"
+	RPCMethodChatMessageEdit     RPCMethod = "chat.message.edit"
 	RPCMethodChatPermit          RPCMethod = "chat.permit"
 	RPCMethodChatReact           RPCMethod = "chat.react"
 	RPCMethodChatRead            RPCMethod = "chat.read"
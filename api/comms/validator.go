@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"bridgerton.audius.co/api/dbv1"
@@ -64,6 +65,10 @@ func (vtor *Validator) Validate(ctx context.Context, userId int32, rawRpc RawRPC
 		return vtor.validateChatDelete(userId, rawRpc)
 	case RPCMethodChatMessage:
 		return vtor.validateChatMessage(ctx, userId, rawRpc)
+	case RPCMethodChatMessageEdit:
+		return vtor.validateChatMessageEdit(ctx, userId, rawRpc)
+	case RPCMethodChatMessageDelete:
+		return vtor.validateChatMessageDelete(ctx, userId, rawRpc)
 	case RPCMethodChatReact:
 		return vtor.validateChatReact(vtor.pool, ctx, userId, rawRpc)
 	case RPCMethodChatRead:
@@ -183,6 +188,11 @@ func (vtor *Validator) validateChatMessage(ctx context.Context, userId int32, rp
 		return err
 	}
 
+	err = validateChatMessageAttachments(params.Attachments)
+	if err != nil {
+		return err
+	}
+
 	// validate not blocked and can chat according to receiver's inbox permission settings
 	err = validatePermittedToMessage(vtor.pool, ctx, userId, params.ChatID)
 	if err != nil {
@@ -198,6 +208,65 @@ func (vtor *Validator) validateChatMessage(ctx context.Context, userId int32, rp
 	return nil
 }
 
+func (vtor *Validator) validateChatMessageEdit(ctx context.Context, userId int32, rpc RawRPC) error {
+	// validate rpc.params valid
+	var params ChatMessageEditRPCParams
+	err := json.Unmarshal(rpc.Params, &params)
+	if err != nil {
+		return err
+	}
+
+	// validate userId is a member of chatId in good standing
+	err = validateChatMembership(vtor.pool, ctx, userId, params.ChatID)
+	if err != nil {
+		return err
+	}
+
+	err = validateOwnRecentMessage(vtor.pool, ctx, userId, params.ChatID, params.MessageID, time.UnixMilli(rpc.Timestamp))
+	if err != nil {
+		return err
+	}
+
+	// validate the message hasn't been edited too many times already
+	var numEdits int
+	err = vtor.pool.QueryRow(ctx, `
+		select jsonb_array_length(edit_history)
+		from chat_message
+		where chat_id = $1 and message_id = $2`, params.ChatID, params.MessageID).Scan(&numEdits)
+	if err != nil {
+		return err
+	}
+	if numEdits >= MaxMessageEdits {
+		return fmt.Errorf("messages can only be edited %d times", MaxMessageEdits)
+	}
+
+	// validate not blocked and can chat according to receiver's inbox permission settings
+	err = validatePermittedToMessage(vtor.pool, ctx, userId, params.ChatID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (vtor *Validator) validateChatMessageDelete(ctx context.Context, userId int32, rpc RawRPC) error {
+	// validate rpc.params valid
+	var params ChatMessageDeleteRPCParams
+	err := json.Unmarshal(rpc.Params, &params)
+	if err != nil {
+		return err
+	}
+
+	// validate userId is a member of chatId in good standing
+	err = validateChatMembership(vtor.pool, ctx, userId, params.ChatID)
+	if err != nil {
+		return err
+	}
+
+	// unsending is allowed even once blocked
+	return validateOwnRecentMessage(vtor.pool, ctx, userId, params.ChatID, params.MessageID, time.UnixMilli(rpc.Timestamp))
+}
+
 func (vtor *Validator) validateChatReact(pool *dbv1.DBPools, ctx context.Context, userId int32, rpc RawRPC) error {
 	// validate rpc.params valid
 	var params ChatReactRPCParams
@@ -457,6 +526,80 @@ func validateChatMembership(pool *dbv1.DBPools, ctx context.Context, userId int3
 	return nil
 }
 
+func validateChatMessageAttachments(attachments []ChatMessageAttachment) error {
+	if len(attachments) > maxAttachmentsPerMessage {
+		return fmt.Errorf("messages can have at most %d attachments", maxAttachmentsPerMessage)
+	}
+
+	for _, a := range attachments {
+		switch a.Type {
+		case AttachmentMedia:
+			if a.CID == nil || *a.CID == "" || len(*a.CID) > maxAttachmentCIDLength {
+				return errors.New("media attachments need a valid cid")
+			}
+			if a.MimeType == nil || !(strings.HasPrefix(*a.MimeType, "image/") || strings.HasPrefix(*a.MimeType, "audio/")) {
+				return errors.New("media attachments must be images or audio")
+			}
+			if a.ID != nil {
+				return errors.New("media attachments can't have an id")
+			}
+		case AttachmentTrack, AttachmentPlaylist, AttachmentUser:
+			if a.ID == nil {
+				return fmt.Errorf("%s attachments need an id", a.Type)
+			}
+			if id, err := trashid.DecodeHashId(*a.ID); err != nil || id <= 0 {
+				return fmt.Errorf("invalid %s id %q", a.Type, *a.ID)
+			}
+			if a.CID != nil || a.MimeType != nil {
+				return fmt.Errorf("%s attachments can't have a cid", a.Type)
+			}
+		default:
+			return fmt.Errorf("unknown attachment type %q", a.Type)
+		}
+	}
+	return nil
+}
+
+// Only the sender can edit or unsend a message, and only for MessageEditWindow.
+// The window is measured to signedAt, the rpc's signed timestamp,
+// which has to be within MaxRpcClockSkew of now, so it can't be backdated into the window.
+func validateOwnRecentMessage(pool *dbv1.DBPools, ctx context.Context, userId int32, chatId string, messageId string, signedAt time.Time) error {
+	if skew := time.Since(signedAt).Abs(); skew > MaxRpcClockSkew {
+		return fmt.Errorf("rpc timestamp is %s from the current time", skew.Round(time.Second))
+	}
+
+	var senderId int32
+	var createdAt time.Time
+	var isBlast, isDeleted bool
+	err := pool.QueryRow(ctx, `
+		select user_id, created_at, blast_id is not null, deleted_at is not null
+		from chat_message
+		where chat_id = $1 and message_id = $2`, chatId, messageId).Scan(&senderId, &createdAt, &isBlast, &isDeleted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.New("message does not exist in chat")
+	}
+	if err != nil {
+		return err
+	}
+
+	if senderId != userId {
+		return errors.New("only the sender can change a message")
+	}
+	if isBlast {
+		return errors.New("blast messages can't be changed")
+	}
+	if isDeleted {
+		return errors.New("message was deleted")
+	}
+	if signedAt.Before(createdAt) {
+		return errors.New("rpc timestamp is before the message was sent")
+	}
+	if signedAt.Sub(createdAt) > MessageEditWindow {
+		return fmt.Errorf("messages can only be changed for %s after sending", MessageEditWindow)
+	}
+	return nil
+}
+
 func validatePermissions(pool *dbv1.DBPools, ctx context.Context, sender int32, receiver int32) error {
 	permissionFailure := errors.New("Not permitted to send messages to this user")
 
@@ -1,12 +1,20 @@
 package comms
 
 import (
+	"context"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strings"
 	"sync"
 	"time"
 
+	"bridgerton.audius.co/api/dbv1"
 	"bridgerton.audius.co/trashid"
 	"github.com/gofiber/contrib/websocket"
+	"github.com/tidwall/gjson"
 	"go.uber.org/zap"
 )
 
@@ -17,13 +25,18 @@ const (
 	writeDeadline      = 10 * time.Second // Timeout for pushing a message to a client
 	recentTTL          = 10 * time.Second
 	maxIncomingMsgSize = 1 << 20 // 1MB limit to incoming messages
+
+	presenceHeartbeatInterval = 15 * time.Second
+	fanoutTimeout             = 5 * time.Second  // Timeout for a call to the fanout backend
+	resumeTimeout             = 30 * time.Second // Timeout for loading what a resuming client missed
 )
 
 type CommsWebsocketManager struct {
-	mu             sync.RWMutex
-	clients        map[int32]map[*Client]struct{} // userId -> set of clients (could be connected from multiple devices)
-	recentMessages []*recentMessage
-	logger         *zap.Logger
+	mu      sync.RWMutex
+	clients map[int32]map[*Client]struct{} // userId -> set of clients (could be connected from multiple devices)
+	fanout  WebsocketFanout
+	pool    *dbv1.DBPools // to check who client events go to
+	logger  *zap.Logger
 }
 
 type Client struct {
@@ -32,26 +45,99 @@ type Client struct {
 	send   chan []byte
 	quit   chan struct{}
 
+	// live pushes held back while missed ones are replayed
+	mu      sync.Mutex
+	pending [][]byte
+	away    bool
+
+	// to throttle the events it sends (see websocket_events.go)
+	eventWindowStart time.Time
+	eventCount       int
+	typing           map[string]typingState
+
 	manager *CommsWebsocketManager
 }
 
-type recentMessage struct {
-	userId  int32
-	sentAt  time.Time
-	payload []byte
+func NewCommsWebsocketManager(pool *dbv1.DBPools, logger *zap.Logger, fanout WebsocketFanout) *CommsWebsocketManager {
+	return &CommsWebsocketManager{
+		clients: make(map[int32]map[*Client]struct{}),
+		fanout:  fanout,
+		pool:    pool,
+		logger:  logger,
+	}
 }
 
-func NewCommsWebsocketManager(logger *zap.Logger) *CommsWebsocketManager {
-	return &CommsWebsocketManager{
-		clients:        make(map[int32]map[*Client]struct{}),
-		recentMessages: []*recentMessage{},
-		logger:         logger,
+// Run delivers what other replicas publish and keeps presence fresh, until ctx is done
+func (m *CommsWebsocketManager) Run(ctx context.Context) error {
+	go func() {
+		ticker := time.NewTicker(presenceHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.mu.RLock()
+				userIds := make([]int32, 0, len(m.clients))
+				for userId := range m.clients {
+					userIds = append(userIds, userId)
+				}
+				m.mu.RUnlock()
+
+				fanoutCtx, cancel := context.WithTimeout(ctx, fanoutTimeout)
+				if err := m.fanout.Heartbeat(fanoutCtx, userIds); err != nil {
+					m.logger.Warn("ws presence heartbeat failed", zap.Error(err))
+				}
+				cancel()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return m.fanout.Subscribe(ctx, func(userId int32, payload []byte) {
+		m.pushLocal(userId, payload)
+	})
+}
+
+// Publish pushes a payload to a user's clients on every replica
+func (m *CommsWebsocketManager) Publish(userId int32, payload []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), fanoutTimeout)
+	defer cancel()
+	if err := m.fanout.Publish(ctx, userId, payload); err != nil {
+		m.logger.Warn("ws publish failed", zap.Int32("userId", userId), zap.Error(err))
+	}
+}
+
+// Presence returns whether each of the users is online, away or offline
+func (m *CommsWebsocketManager) Presence(ctx context.Context, userIds []int32) (map[int32]PresenceStatus, error) {
+	connected, err := m.fanout.Presence(ctx, userIds)
+	if err != nil {
+		return nil, err
+	}
+	presence := make(map[int32]PresenceStatus, len(userIds))
+	for _, userId := range userIds {
+		presence[userId] = PresenceOffline
+		if status, ok := connected[userId]; ok {
+			presence[userId] = status
+		}
+	}
+	return presence, nil
+}
+
+func (m *CommsWebsocketManager) setOnline(userId int32, online bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), fanoutTimeout)
+	defer cancel()
+	if err := m.fanout.SetOnline(ctx, userId, online); err != nil {
+		m.logger.Warn("ws presence update failed", zap.Int32("userId", userId), zap.Error(err))
 	}
 }
 
 // RegisterWebsocket wires up a long-lived read/write loop.
 // Do NOT write directly to conn here; only the write pump writes.
-func (m *CommsWebsocketManager) RegisterWebsocket(userId int32, conn *websocket.Conn) {
+//
+// With missed, the client resumes: what it returns is replayed in order,
+// then the live pushes that came in meanwhile, without the ones replayed.
+// Without it, the client gets what was pushed to the user in the last recentTTL.
+func (m *CommsWebsocketManager) RegisterWebsocket(userId int32, conn *websocket.Conn, missed func(ctx context.Context) ([]websocketEvent, error)) {
 	cl := &Client{
 		userId:  userId,
 		conn:    conn,
@@ -59,49 +145,175 @@ func (m *CommsWebsocketManager) RegisterWebsocket(userId int32, conn *websocket.
 		quit:    make(chan struct{}),
 		manager: m,
 	}
+	if missed != nil {
+		cl.pending = [][]byte{}
+	}
 
 	// Add to manager
 	m.mu.Lock()
-	if m.clients[userId] == nil {
+	firstClient := m.clients[userId] == nil
+	if firstClient {
 		m.clients[userId] = make(map[*Client]struct{})
 	}
 	m.clients[userId][cl] = struct{}{}
 	m.mu.Unlock()
 
-	// Replay very recent messages for this user by enqueuing them
-	now := time.Now()
-	m.mu.RLock()
-	for _, r := range m.recentMessages {
-		if r.userId == userId && now.Sub(r.sentAt) < recentTTL {
-			select {
-			case cl.send <- r.payload:
-			default:
-				// If they connect with a full buffer immediately, just drop replay.
-				m.logger.Info("ws replay dropped due to full buffer", zap.Int32("userId", userId))
-			}
-		}
+	if firstClient {
+		m.markOnline(userId)
+	} else {
+		// A new client isn't away, even if the others are
+		m.updateAway(userId)
 	}
-	m.mu.RUnlock()
 
-	// Start pumps and block so the connection is not closed
+	// Start pumps
 	done := make(chan struct{})
 	go func() {
 		cl.readPump()
 		close(done)
 	}()
 	go cl.writePump()
+
+	if missed != nil {
+		m.resume(cl, missed)
+	} else {
+		// Replay very recent messages for this user by enqueuing them,
+		// including ones pushed while they were connected to another replica
+		ctx, cancel := context.WithTimeout(context.Background(), fanoutTimeout)
+		recent, err := m.fanout.Recent(ctx, userId, recentTTL)
+		cancel()
+		if err != nil {
+			m.logger.Warn("ws replay failed", zap.Int32("userId", userId), zap.Error(err))
+		}
+		for _, payload := range recent {
+			if !cl.enqueue(payload) {
+				// If they connect with a full buffer immediately, just drop replay.
+				m.logger.Info("ws replay dropped due to full buffer", zap.Int32("userId", userId))
+			}
+		}
+	}
+
+	// Block so the connection is not closed
 	<-done
 }
 
+func (m *CommsWebsocketManager) resume(cl *Client, missed func(ctx context.Context) ([]websocketEvent, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), resumeTimeout)
+	events, err := missed(ctx)
+	cancel()
+	if err != nil {
+		// Without the replay there'd be a gap, so make the client reconnect
+		m.logger.Warn("ws resume failed", zap.Int32("userId", cl.userId), zap.Error(err))
+		m.removeClient(cl)
+		return
+	}
+
+	replayed := map[string]bool{}
+	for _, event := range events {
+		payload, err := websocketPayload(event.senderUserId, cl.userId, event.rpcJson, event.timestamp)
+		if err != nil {
+			m.logger.Warn("invalid websocket json " + err.Error())
+			continue
+		}
+		replayed[pushKey(payload)] = true
+		if !cl.sendOrQuit(payload) {
+			return
+		}
+	}
+
+	// Then what was pushed live meanwhile, until nothing is left to hold back
+	for {
+		cl.mu.Lock()
+		pending := cl.pending
+		if len(pending) == 0 {
+			cl.pending = nil
+			cl.mu.Unlock()
+			break
+		}
+		cl.pending = [][]byte{}
+		cl.mu.Unlock()
+
+		for _, payload := range pending {
+			if replayed[pushKey(payload)] {
+				continue
+			}
+			if !cl.sendOrQuit(payload) {
+				return
+			}
+		}
+	}
+
+	m.logger.Debug("websocket resumed",
+		zap.Int32("userId", cl.userId),
+		zap.Int("numReplayed", len(events)))
+}
+
+// What a push is about, the same whether it's live or replayed
+func pushKey(payload []byte) string {
+	parts := []string{}
+	for _, result := range gjson.GetManyBytes(payload,
+		"rpc.method",
+		"rpc.params.chat_id",
+		"rpc.params.message_id",
+		"rpc.params.blast_id",
+		"rpc.params.reaction",
+		"metadata.senderUserId",
+	) {
+		parts = append(parts, result.Raw)
+	}
+	return strings.Join(parts, "|")
+}
+
+// Queues a push, or holds it back while the client resumes.
+// False if the client's buffer is full.
+func (cl *Client) enqueue(payload []byte) bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.pending != nil {
+		if len(cl.pending) >= sendQueueSize*8 {
+			return false
+		}
+		cl.pending = append(cl.pending, payload)
+		return true
+	}
+	select {
+	case cl.send <- payload:
+		return true
+	default:
+		return false
+	}
+}
+
+// Waits for room in the client's buffer. False if the client went away first.
+func (cl *Client) sendOrQuit(payload []byte) bool {
+	select {
+	case cl.send <- payload:
+		return true
+	case <-cl.quit:
+		return false
+	}
+}
+
 func (m *CommsWebsocketManager) removeClient(cl *Client) {
+	removed, lastClient := false, false
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	defer func() {
+		m.mu.Unlock()
+		if lastClient {
+			m.setOnline(cl.userId, false)
+			go m.publishOffline(cl.userId)
+		} else if removed {
+			// The clients left may all be away
+			m.updateAway(cl.userId)
+		}
+	}()
 	set := m.clients[cl.userId]
 	if set != nil {
 		if _, ok := set[cl]; ok {
 			delete(set, cl)
+			removed = true
 			if len(set) == 0 {
 				delete(m.clients, cl.userId)
+				lastClient = true
 			}
 		}
 	}
@@ -123,8 +335,8 @@ func (cl *Client) readPump() {
 		return cl.conn.SetReadDeadline(time.Now().Add(readIdleTimeout))
 	})
 
-	// We don't expect app-level inbound messages, but we still need to read to
-	// receive pings/closes and keep deadlines fresh.
+	// Besides keeping the socket healthy, clients send small events
+	// (see websocket_events.go), which are handled in order.
 	for {
 		mt, r, err := cl.conn.NextReader()
 		if err != nil {
@@ -134,17 +346,22 @@ func (cl *Client) readPump() {
 			cl.manager.removeClient(cl)
 			return
 		}
-		// Discard payload if any; we only care about keeping the socket healthy.
-		switch mt {
-		case websocket.TextMessage, websocket.BinaryMessage:
-			// Efficiently drain without allocating:
-			buf := make([]byte, 1024)
-			for {
-				_, derr := r.Read(buf)
-				if derr != nil {
-					break
-				}
-			}
+		if mt != websocket.TextMessage {
+			// The rest of the frame is discarded by the next NextReader
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(r, maxClientEventSize+1))
+		if err != nil || len(data) > maxClientEventSize {
+			cl.manager.logger.Debug("ws client event dropped",
+				zap.Int32("userId", cl.userId),
+				zap.Int("size", len(data)),
+				zap.Error(err))
+			continue
+		}
+		if err := cl.manager.handleClientEvent(cl, data); err != nil {
+			cl.manager.logger.Debug("ws client event rejected",
+				zap.Int32("userId", cl.userId),
+				zap.Error(err))
 		}
 	}
 }
@@ -192,6 +409,30 @@ func (cl *Client) writePump() {
 
 // Push to a single receiver (all connected clients)
 func (m *CommsWebsocketManager) WebsocketPush(senderUserId int32, receiverUserId int32, rpcJson json.RawMessage, timestamp time.Time) {
+	payload, err := websocketPayload(senderUserId, receiverUserId, rpcJson, timestamp)
+	if err != nil {
+		m.logger.Warn("invalid websocket json " + err.Error())
+		return
+	}
+
+	numClients := m.pushLocal(receiverUserId, payload)
+
+	// Every replica pushes the same rpc, so it's remembered once by its hash
+	h := fnv.New64a()
+	fmt.Fprint(h, senderUserId)
+	h.Write(rpcJson)
+	ctx, cancel := context.WithTimeout(context.Background(), fanoutTimeout)
+	defer cancel()
+	if err := m.fanout.Remember(ctx, receiverUserId, hex.EncodeToString(h.Sum(nil)), payload); err != nil {
+		m.logger.Warn("ws remember failed", zap.Int32("userId", receiverUserId), zap.Error(err))
+	}
+
+	m.logger.Debug("websocket push",
+		zap.Int32("userId", receiverUserId),
+		zap.Int("numClients", numClients))
+}
+
+func websocketPayload(senderUserId int32, receiverUserId int32, rpcJson json.RawMessage, timestamp time.Time) ([]byte, error) {
 	encodedSenderUserId, _ := trashid.EncodeHashId(int(senderUserId))
 	encodedReceiverUserId, _ := trashid.EncodeHashId(int(receiverUserId))
 
@@ -207,50 +448,33 @@ func (m *CommsWebsocketManager) WebsocketPush(senderUserId int32, receiverUserId
 			UserID:         encodedSenderUserId,
 		},
 	}
+	return json.Marshal(data)
+}
 
-	payload, err := json.Marshal(data)
-	if err != nil {
-		m.logger.Warn("invalid websocket json " + err.Error())
-		return
-	}
-
-	// Fanout
+// Push to the user's clients connected to this replica
+func (m *CommsWebsocketManager) pushLocal(receiverUserId int32, payload []byte) int {
+	// The set changes as clients come and go, so push to a copy of it
 	m.mu.RLock()
-	targets := m.clients[receiverUserId]
+	targets := make([]*Client, 0, len(m.clients[receiverUserId]))
+	for cl := range m.clients[receiverUserId] {
+		targets = append(targets, cl)
+	}
 	m.mu.RUnlock()
 
-	for cl := range targets {
-		select {
-		case cl.send <- payload:
-			// ok
-		default:
+	dropped := []*Client{}
+	for _, cl := range targets {
+		if !cl.enqueue(payload) {
 			// If we get here, the client buffer is full (too slow in processing)
 			// and we will drop them for now. They can re-connect if needed.
 			m.logger.Info("ws buffer full; dropping client",
 				zap.Int32("userId", receiverUserId))
-			m.removeClient(cl)
+			dropped = append(dropped, cl)
 		}
 	}
-
-	// Clear expired messages out of the recentMessages buffer
-	m.mu.Lock()
-	now := time.Now()
-	kept := m.recentMessages[:0]
-	for _, r := range m.recentMessages {
-		if now.Sub(r.sentAt) < recentTTL {
-			kept = append(kept, r)
-		}
+	for _, cl := range dropped {
+		m.removeClient(cl)
 	}
-	m.recentMessages = append(kept, &recentMessage{
-		userId:  receiverUserId,
-		sentAt:  now,
-		payload: payload,
-	})
-	m.mu.Unlock()
-
-	m.logger.Debug("websocket push",
-		zap.Int32("userId", receiverUserId),
-		zap.Int("numClients", len(targets)))
+	return len(targets)
 }
 
 // Push to all connected clients
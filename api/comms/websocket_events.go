@@ -0,0 +1,284 @@
+package comms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Events clients send over their websocket.
+// Unlike rpcs they're never stored: they're pushed to the other members
+// of the sender's chats, on every replica, and forgotten.
+
+type ClientEventMethod string
+
+const (
+	MethodChatTyping   ClientEventMethod = "chat.typing"
+	MethodUserPresence ClientEventMethod = "user.presence"
+)
+
+type PresenceStatus string
+
+const (
+	PresenceOnline  PresenceStatus = "online"
+	PresenceAway    PresenceStatus = "away"
+	PresenceOffline PresenceStatus = "offline"
+)
+
+const (
+	maxClientEventSize = 4096
+	clientEventTimeout = 5 * time.Second
+
+	// most events a client can send per clientEventWindow
+	maxClientEventsPerWindow = 10
+	clientEventWindow        = time.Second
+	// an unchanged typing status is only sent again after this long
+	typingRepeatInterval = 3 * time.Second
+)
+
+type ClientEvent struct {
+	Method ClientEventMethod `json:"method"`
+	Params json.RawMessage   `json:"params"`
+}
+
+type ChatTypingParams struct {
+	ChatID   string `json:"chat_id"`
+	IsTyping bool   `json:"is_typing"`
+}
+
+type UserPresenceParams struct {
+	Status PresenceStatus `json:"status"`
+}
+
+var (
+	ErrUnknownClientEvent     = errors.New("unknown websocket event")
+	ErrClientEventRateLimited = errors.New("too many websocket events")
+)
+
+// the typing status last sent by a client to a chat
+type typingState struct {
+	isTyping bool
+	sentAt   time.Time
+}
+
+func (m *CommsWebsocketManager) handleClientEvent(cl *Client, data []byte) error {
+	var event ClientEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !cl.allowEvent(now) {
+		return ErrClientEventRateLimited
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clientEventTimeout)
+	defer cancel()
+
+	switch event.Method {
+	case MethodChatTyping:
+		var params ChatTypingParams
+		if err := json.Unmarshal(event.Params, &params); err != nil {
+			return err
+		}
+		if params.ChatID == "" {
+			return errors.New("chat_id is required")
+		}
+		if err := validateChatMembership(m.pool, ctx, cl.userId, params.ChatID); err != nil {
+			return err
+		}
+		if !cl.shouldSendTyping(params.ChatID, params.IsTyping, now) {
+			return nil
+		}
+
+		rows, err := m.pool.Query(ctx, `
+			SELECT user_id FROM chat_member
+			WHERE chat_id = $1 AND user_id != $2 AND is_hidden = false`, params.ChatID, cl.userId)
+		if err != nil {
+			return err
+		}
+		receivers, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+		if err != nil {
+			return err
+		}
+		return m.publishEvent(cl.userId, receivers, MethodChatTyping, params)
+
+	case MethodUserPresence:
+		var params UserPresenceParams
+		if err := json.Unmarshal(event.Params, &params); err != nil {
+			return err
+		}
+		if params.Status != PresenceOnline && params.Status != PresenceAway {
+			return fmt.Errorf("invalid status %q", params.Status)
+		}
+
+		away := params.Status == PresenceAway
+		cl.mu.Lock()
+		unchanged := cl.away == away
+		cl.away = away
+		cl.mu.Unlock()
+		if unchanged {
+			return nil
+		}
+
+		// The user is only away once all their clients are
+		before, err := m.Presence(ctx, []int32{cl.userId})
+		if err != nil {
+			return err
+		}
+		m.updateAway(cl.userId)
+		after, err := m.Presence(ctx, []int32{cl.userId})
+		if err != nil {
+			return err
+		}
+		if after[cl.userId] == before[cl.userId] {
+			return nil
+		}
+		return m.publishPresence(ctx, cl.userId, after[cl.userId])
+
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownClientEvent, event.Method)
+	}
+}
+
+// Counts an event against the client's rate limit. False if it's over.
+func (cl *Client) allowEvent(now time.Time) bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if now.Sub(cl.eventWindowStart) >= clientEventWindow {
+		cl.eventWindowStart = now
+		cl.eventCount = 0
+	}
+	cl.eventCount++
+	return cl.eventCount <= maxClientEventsPerWindow
+}
+
+// Whether a typing status is worth sending to a chat:
+// it changed, or it was last sent a while ago
+func (cl *Client) shouldSendTyping(chatId string, isTyping bool, now time.Time) bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.typing == nil {
+		cl.typing = map[string]typingState{}
+	}
+	last, ok := cl.typing[chatId]
+	if ok && last.isTyping == isTyping && now.Sub(last.sentAt) < typingRepeatInterval {
+		return false
+	}
+	cl.typing[chatId] = typingState{isTyping: isTyping, sentAt: now}
+	return true
+}
+
+// Pushes the user's presence to the other members of their chats
+func (m *CommsWebsocketManager) publishPresence(ctx context.Context, userId int32, status PresenceStatus) error {
+	rows, err := m.pool.Query(ctx, `
+		SELECT DISTINCT other.user_id
+		FROM chat_member
+		JOIN chat_member other ON other.chat_id = chat_member.chat_id
+			AND other.user_id != chat_member.user_id
+			AND other.is_hidden = false
+		WHERE chat_member.user_id = $1`, userId)
+	if err != nil {
+		return err
+	}
+	receivers, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+	if err != nil {
+		return err
+	}
+	return m.publishEvent(userId, receivers, MethodUserPresence, UserPresenceParams{Status: status})
+}
+
+// Marks the user online, once their first client on this replica connects,
+// and pushes that they came online, unless they were already connected to another one
+func (m *CommsWebsocketManager) markOnline(userId int32) {
+	ctx, cancel := context.WithTimeout(context.Background(), fanoutTimeout)
+	presence, err := m.Presence(ctx, []int32{userId})
+	cancel()
+	if err != nil {
+		m.logger.Warn("ws presence lookup failed", zap.Int32("userId", userId), zap.Error(err))
+	}
+
+	m.setOnline(userId, true)
+
+	if err == nil && presence[userId] == PresenceOffline {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), clientEventTimeout)
+			defer cancel()
+			if err := m.publishPresence(ctx, userId, PresenceOnline); err != nil {
+				m.logger.Warn("ws online push failed", zap.Int32("userId", userId), zap.Error(err))
+			}
+		}()
+	}
+}
+
+// Pushes that the user went offline, once their last client on this replica is gone,
+// unless they are still connected to another one
+func (m *CommsWebsocketManager) publishOffline(userId int32) {
+	ctx, cancel := context.WithTimeout(context.Background(), clientEventTimeout)
+	defer cancel()
+
+	presence, err := m.Presence(ctx, []int32{userId})
+	if err == nil && presence[userId] == PresenceOffline {
+		err = m.publishPresence(ctx, userId, PresenceOffline)
+	}
+	if err != nil {
+		m.logger.Warn("ws offline push failed", zap.Int32("userId", userId), zap.Error(err))
+	}
+}
+
+// Pushes an event from senderUserId to each receiver, shaped like the rpcs pushed to them
+func (m *CommsWebsocketManager) publishEvent(senderUserId int32, receivers []int32, method ClientEventMethod, params any) error {
+	paramsJson, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	eventJson, err := json.Marshal(ClientEvent{Method: method, Params: paramsJson})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, receiverUserId := range receivers {
+		payload, err := websocketPayload(senderUserId, receiverUserId, eventJson, now)
+		if err != nil {
+			return err
+		}
+		m.Publish(receiverUserId, payload)
+	}
+
+	m.logger.Debug("websocket event",
+		zap.Int32("userId", senderUserId),
+		zap.String("method", string(method)),
+		zap.Int("numReceivers", len(receivers)))
+	return nil
+}
+
+// Marks the user away on this replica when all their clients here are
+func (m *CommsWebsocketManager) updateAway(userId int32) {
+	m.mu.RLock()
+	numClients := len(m.clients[userId])
+	away := true
+	for cl := range m.clients[userId] {
+		cl.mu.Lock()
+		if !cl.away {
+			away = false
+		}
+		cl.mu.Unlock()
+	}
+	m.mu.RUnlock()
+
+	if numClients == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fanoutTimeout)
+	defer cancel()
+	if err := m.fanout.SetAway(ctx, userId, away); err != nil {
+		m.logger.Warn("ws away update failed", zap.Int32("userId", userId), zap.Error(err))
+	}
+}
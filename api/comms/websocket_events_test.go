@@ -0,0 +1,132 @@
+package comms
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bridgerton.audius.co/api/dbv1"
+	"bridgerton.audius.co/database"
+	"bridgerton.audius.co/trashid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"go.uber.org/zap"
+)
+
+func TestHandleClientEvent(t *testing.T) {
+	pool := database.CreateTestDatabase(t, "test_comms")
+	defer pool.Close()
+	ctx := t.Context()
+
+	chatId := trashid.ChatID(1, 2)
+	SetupChatWithMembers(t, pool, ctx, chatId, 1, 2, "invite1", "invite2")
+
+	m := NewCommsWebsocketManager(&dbv1.DBPools{Replicas: []*pgxpool.Pool{pool}}, zap.NewNop(), NewMemoryFanout())
+
+	type published struct {
+		userId  int32
+		payload []byte
+	}
+	delivered := make(chan published, 10)
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go m.fanout.Subscribe(subCtx, func(userId int32, payload []byte) {
+		delivered <- published{userId, payload}
+	})
+
+	// a client of user 1, without a connection, since nothing is written to it
+	cl := &Client{userId: 1, manager: m}
+	m.clients[1] = map[*Client]struct{}{cl: {}}
+	require.NoError(t, m.fanout.SetOnline(ctx, 1, true))
+
+	t.Run("typing goes to the other member", func(t *testing.T) {
+		err := m.handleClientEvent(cl, []byte(`{"method":"chat.typing","params":{"chat_id":"`+chatId+`","is_typing":true}}`))
+		require.NoError(t, err)
+
+		pub := <-delivered
+		assert.Equal(t, int32(2), pub.userId)
+		assert.Equal(t, "chat.typing", gjson.GetBytes(pub.payload, "rpc.method").String())
+		assert.Equal(t, chatId, gjson.GetBytes(pub.payload, "rpc.params.chat_id").String())
+		assert.True(t, gjson.GetBytes(pub.payload, "rpc.params.is_typing").Bool())
+		assert.Equal(t, trashid.MustEncodeHashID(1), gjson.GetBytes(pub.payload, "metadata.senderUserId").String())
+	})
+
+	t.Run("the same typing status isn't sent again right away", func(t *testing.T) {
+		err := m.handleClientEvent(cl, []byte(`{"method":"chat.typing","params":{"chat_id":"`+chatId+`","is_typing":true}}`))
+		require.NoError(t, err)
+		err = m.handleClientEvent(cl, []byte(`{"method":"chat.typing","params":{"chat_id":"`+chatId+`","is_typing":false}}`))
+		require.NoError(t, err)
+
+		pub := <-delivered
+		assert.False(t, gjson.GetBytes(pub.payload, "rpc.params.is_typing").Bool())
+		assert.Empty(t, delivered)
+	})
+
+	t.Run("typing in someone else's chat", func(t *testing.T) {
+		err := m.handleClientEvent(cl, []byte(`{"method":"chat.typing","params":{"chat_id":"`+trashid.ChatID(2, 3)+`","is_typing":true}}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("away", func(t *testing.T) {
+		err := m.handleClientEvent(cl, []byte(`{"method":"user.presence","params":{"status":"away"}}`))
+		require.NoError(t, err)
+
+		pub := <-delivered
+		assert.Equal(t, int32(2), pub.userId)
+		assert.Equal(t, "user.presence", gjson.GetBytes(pub.payload, "rpc.method").String())
+		assert.Equal(t, "away", gjson.GetBytes(pub.payload, "rpc.params.status").String())
+
+		presence, err := m.Presence(ctx, []int32{1, 2})
+		require.NoError(t, err)
+		assert.Equal(t, map[int32]PresenceStatus{1: PresenceAway, 2: PresenceOffline}, presence)
+	})
+
+	t.Run("an unchanged status isn't sent", func(t *testing.T) {
+		err := m.handleClientEvent(cl, []byte(`{"method":"user.presence","params":{"status":"away"}}`))
+		require.NoError(t, err)
+		assert.Empty(t, delivered)
+	})
+
+	t.Run("offline once the last client is gone", func(t *testing.T) {
+		// as removeClient does for the last one
+		require.NoError(t, m.fanout.SetOnline(ctx, 1, false))
+		m.publishOffline(1)
+
+		pub := <-delivered
+		assert.Equal(t, int32(2), pub.userId)
+		assert.Equal(t, "offline", gjson.GetBytes(pub.payload, "rpc.params.status").String())
+	})
+
+	t.Run("online again when they reconnect", func(t *testing.T) {
+		// as RegisterWebsocket does for the first client
+		m.markOnline(1)
+
+		pub := <-delivered
+		assert.Equal(t, int32(2), pub.userId)
+		assert.Equal(t, "online", gjson.GetBytes(pub.payload, "rpc.params.status").String())
+
+		// but not when they were online already
+		m.markOnline(1)
+		assert.Never(t, func() bool { return len(delivered) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+	})
+
+	t.Run("invalid events", func(t *testing.T) {
+		err := m.handleClientEvent(cl, []byte(`{"method":"user.presence","params":{"status":"busy"}}`))
+		assert.Error(t, err)
+		err = m.handleClientEvent(cl, []byte(`{"method":"chat.message","params":{}}`))
+		assert.ErrorIs(t, err, ErrUnknownClientEvent)
+		err = m.handleClientEvent(cl, []byte(`not json`))
+		assert.Error(t, err)
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		spammer := &Client{userId: 1, manager: m}
+		var err error
+		for range maxClientEventsPerWindow + 1 {
+			err = m.handleClientEvent(spammer, []byte(`{"method":"user.presence","params":{"status":"online"}}`))
+		}
+		assert.ErrorIs(t, err, ErrClientEventRateLimited)
+	})
+}
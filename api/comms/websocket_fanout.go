@@ -0,0 +1,188 @@
+package comms
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// WebsocketFanout is what CommsWebsocketManager shares with the other API replicas.
+//
+// Every replica LISTENs for chat messages, reactions and blasts itself,
+// and pushes them to the sockets connected to it. What it can't see alone
+// is what was pushed before a socket reconnected to it from another replica,
+// who is connected to the others, and events that start at a replica rather than the database.
+type WebsocketFanout interface {
+	// Keeps a push to userId for replay, for recentTTL.
+	// Replicas remember the same push under the same key, and it's kept once.
+	Remember(ctx context.Context, userId int32, key string, payload []byte) error
+
+	// The pushes remembered for userId in the last maxAge, oldest first
+	Recent(ctx context.Context, userId int32, maxAge time.Duration) ([][]byte, error)
+
+	// Sends a payload to userId's sockets on every replica, this one included
+	Publish(ctx context.Context, userId int32, payload []byte) error
+
+	// Calls deliver with what any replica publishes, until ctx is done
+	Subscribe(ctx context.Context, deliver func(userId int32, payload []byte)) error
+
+	// Marks userId as connected to this replica, or not
+	SetOnline(ctx context.Context, userId int32, online bool) error
+
+	// Marks userId, connected to this replica, as away or back
+	SetAway(ctx context.Context, userId int32, away bool) error
+
+	// Replaces the users connected to this replica, correcting missed updates
+	// and keeping them from expiring. Called every presenceHeartbeatInterval.
+	Heartbeat(ctx context.Context, userIds []int32) error
+
+	// Which of userIds are connected to any replica: away if they're away on all of them,
+	// otherwise online. Users who aren't connected are left out.
+	Presence(ctx context.Context, userIds []int32) (map[int32]PresenceStatus, error)
+}
+
+// memoryFanout is the WebsocketFanout of a single replica
+type memoryFanout struct {
+	mu          sync.Mutex
+	recent      []*recentMessage
+	away        map[int32]bool // the users connected, and whether they're away
+	subscribers map[int]func(userId int32, payload []byte)
+	nextId      int
+}
+
+type recentMessage struct {
+	userId  int32
+	key     string
+	sentAt  time.Time
+	payload []byte
+}
+
+func NewMemoryFanout() WebsocketFanout {
+	return &memoryFanout{
+		recent:      []*recentMessage{},
+		away:        map[int32]bool{},
+		subscribers: map[int]func(int32, []byte){},
+	}
+}
+
+func (f *memoryFanout) Remember(ctx context.Context, userId int32, key string, payload []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Clear expired messages out of the buffer
+	now := time.Now()
+	kept := f.recent[:0]
+	for _, r := range f.recent {
+		if now.Sub(r.sentAt) < recentTTL {
+			kept = append(kept, r)
+		}
+	}
+	f.recent = kept
+
+	if slices.ContainsFunc(f.recent, func(r *recentMessage) bool {
+		return r.userId == userId && r.key == key
+	}) {
+		return nil
+	}
+	f.recent = append(f.recent, &recentMessage{
+		userId:  userId,
+		key:     key,
+		sentAt:  now,
+		payload: payload,
+	})
+	return nil
+}
+
+func (f *memoryFanout) Recent(ctx context.Context, userId int32, maxAge time.Duration) ([][]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	payloads := [][]byte{}
+	for _, r := range f.recent {
+		if r.userId == userId && now.Sub(r.sentAt) < maxAge {
+			payloads = append(payloads, r.payload)
+		}
+	}
+	return payloads, nil
+}
+
+func (f *memoryFanout) Publish(ctx context.Context, userId int32, payload []byte) error {
+	f.mu.Lock()
+	subscribers := make([]func(int32, []byte), 0, len(f.subscribers))
+	for _, deliver := range f.subscribers {
+		subscribers = append(subscribers, deliver)
+	}
+	f.mu.Unlock()
+
+	for _, deliver := range subscribers {
+		deliver(userId, payload)
+	}
+	return nil
+}
+
+func (f *memoryFanout) Subscribe(ctx context.Context, deliver func(userId int32, payload []byte)) error {
+	f.mu.Lock()
+	id := f.nextId
+	f.nextId++
+	f.subscribers[id] = deliver
+	f.mu.Unlock()
+
+	<-ctx.Done()
+
+	f.mu.Lock()
+	delete(f.subscribers, id)
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *memoryFanout) SetOnline(ctx context.Context, userId int32, online bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if online {
+		f.away[userId] = false
+	} else {
+		delete(f.away, userId)
+	}
+	return nil
+}
+
+func (f *memoryFanout) SetAway(ctx context.Context, userId int32, away bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.away[userId]; ok {
+		f.away[userId] = away
+	}
+	return nil
+}
+
+func (f *memoryFanout) Heartbeat(ctx context.Context, userIds []int32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	away := make(map[int32]bool, len(userIds))
+	for _, userId := range userIds {
+		away[userId] = f.away[userId]
+	}
+	f.away = away
+	return nil
+}
+
+func (f *memoryFanout) Presence(ctx context.Context, userIds []int32) (map[int32]PresenceStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	presence := map[int32]PresenceStatus{}
+	for _, userId := range userIds {
+		away, ok := f.away[userId]
+		if !ok {
+			continue
+		}
+		presence[userId] = PresenceOnline
+		if away {
+			presence[userId] = PresenceAway
+		}
+	}
+	return presence, nil
+}
@@ -0,0 +1,209 @@
+package comms
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgxlisten"
+	"go.uber.org/zap"
+)
+
+// postgresFanout shares replay and presence between replicas through
+// the unlogged comms_ws_recent and comms_ws_presence tables,
+// and publishes with NOTIFY, which limits payloads to 8000 bytes.
+type postgresFanout struct {
+	pool      *pgxpool.Pool
+	replicaId string
+	logger    *zap.Logger
+}
+
+const (
+	websocketFanoutChannel = "comms_ws_fanout"
+
+	// a replica that hasn't sent a heartbeat for this long is presumed gone
+	presenceTTL = 3 * presenceHeartbeatInterval
+)
+
+type websocketFanoutNotification struct {
+	UserID  int32           `json:"user_id"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Uses the write pool, since every replica must see the same rows
+func NewPostgresFanout(writePool *pgxpool.Pool, logger *zap.Logger) WebsocketFanout {
+	hostname, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+
+	return &postgresFanout{
+		pool:      writePool,
+		replicaId: hostname + "-" + hex.EncodeToString(suffix),
+		logger:    logger,
+	}
+}
+
+func (f *postgresFanout) Remember(ctx context.Context, userId int32, key string, payload []byte) error {
+	_, err := f.pool.Exec(ctx, `
+		INSERT INTO comms_ws_recent (user_id, key, payload)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`, userId, key, string(payload))
+	return err
+}
+
+func (f *postgresFanout) Recent(ctx context.Context, userId int32, maxAge time.Duration) ([][]byte, error) {
+	rows, err := f.pool.Query(ctx, `
+		SELECT payload FROM comms_ws_recent
+		WHERE user_id = $1
+			AND created_at > now() - make_interval(secs => $2)
+		ORDER BY created_at, key`, userId, maxAge.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) ([]byte, error) {
+		var payload string
+		err := row.Scan(&payload)
+		return []byte(payload), err
+	})
+}
+
+func (f *postgresFanout) Publish(ctx context.Context, userId int32, payload []byte) error {
+	notification, err := json.Marshal(websocketFanoutNotification{
+		UserID:  userId,
+		Payload: payload,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = f.pool.Exec(ctx, `SELECT pg_notify($1, $2)`, websocketFanoutChannel, string(notification))
+	return err
+}
+
+func (f *postgresFanout) Subscribe(ctx context.Context, deliver func(userId int32, payload []byte)) error {
+	listener := &pgxlisten.Listener{
+		Connect: func(ctx context.Context) (*pgx.Conn, error) {
+			conn, err := f.pool.Acquire(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return conn.Conn(), nil
+		},
+		LogError: func(ctx context.Context, err error) {
+			f.logger.Error("Comms websocket fanout listener error", zap.Error(err))
+		},
+		ReconnectDelay: 10 * time.Second,
+	}
+
+	listener.Handle(websocketFanoutChannel, pgxlisten.HandlerFunc(func(ctx context.Context, notification *pgconn.Notification, conn *pgx.Conn) error {
+		var payload websocketFanoutNotification
+		if err := json.Unmarshal([]byte(notification.Payload), &payload); err != nil {
+			f.logger.Error("Failed to parse websocket fanout payload", zap.Error(err))
+			return err
+		}
+		deliver(payload.UserID, payload.Payload)
+		return nil
+	}))
+
+	err := listener.Listen(ctx)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func (f *postgresFanout) SetOnline(ctx context.Context, userId int32, online bool) error {
+	if !online {
+		_, err := f.pool.Exec(ctx, `
+			DELETE FROM comms_ws_presence
+			WHERE replica_id = $1 AND user_id = $2`, f.replicaId, userId)
+		return err
+	}
+
+	_, err := f.pool.Exec(ctx, `
+		INSERT INTO comms_ws_presence (replica_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (replica_id, user_id) DO UPDATE SET heartbeat_at = now(), away = false`, f.replicaId, userId)
+	return err
+}
+
+func (f *postgresFanout) SetAway(ctx context.Context, userId int32, away bool) error {
+	_, err := f.pool.Exec(ctx, `
+		UPDATE comms_ws_presence SET away = $3
+		WHERE replica_id = $1 AND user_id = $2`, f.replicaId, userId, away)
+	return err
+}
+
+// Also clears out what other replicas left behind
+func (f *postgresFanout) Heartbeat(ctx context.Context, userIds []int32) error {
+	tx, err := f.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	args := pgx.NamedArgs{
+		"replicaId":   f.replicaId,
+		"userIds":     userIds,
+		"presenceTTL": presenceTTL.Seconds(),
+		"recentTTL":   recentTTL.Seconds(),
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM comms_ws_presence
+		WHERE (replica_id = @replicaId AND NOT user_id = ANY(@userIds::int[]))
+			OR heartbeat_at < now() - make_interval(secs => @presenceTTL)`, args)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO comms_ws_presence (replica_id, user_id)
+		SELECT @replicaId, unnest(@userIds::int[])
+		ON CONFLICT (replica_id, user_id) DO UPDATE SET heartbeat_at = now()`, args)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM comms_ws_recent
+		WHERE created_at < now() - make_interval(secs => @recentTTL)`, args)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (f *postgresFanout) Presence(ctx context.Context, userIds []int32) (map[int32]PresenceStatus, error) {
+	type connectedUser struct {
+		UserID int32 `db:"user_id"`
+		Away   bool  `db:"away"`
+	}
+	rows, err := f.pool.Query(ctx, `
+		SELECT user_id, bool_and(away) AS away FROM comms_ws_presence
+		WHERE user_id = ANY($1::int[])
+			AND heartbeat_at > now() - make_interval(secs => $2)
+		GROUP BY user_id`, userIds, presenceTTL.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	connected, err := pgx.CollectRows(rows, pgx.RowToStructByName[connectedUser])
+	if err != nil {
+		return nil, err
+	}
+
+	presence := map[int32]PresenceStatus{}
+	for _, user := range connected {
+		presence[user.UserID] = PresenceOnline
+		if user.Away {
+			presence[user.UserID] = PresenceAway
+		}
+	}
+	return presence, nil
+}
@@ -0,0 +1,77 @@
+package comms
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bridgerton.audius.co/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// what every WebsocketFanout does, whether or not it's shared
+func testWebsocketFanout(t *testing.T, fanout WebsocketFanout, other WebsocketFanout) {
+	ctx := t.Context()
+
+	// replay
+	require.NoError(t, fanout.Remember(ctx, 1, "a", []byte(`{"n":1}`)))
+	require.NoError(t, other.Remember(ctx, 1, "a", []byte(`{"n":1}`)))
+	require.NoError(t, other.Remember(ctx, 1, "b", []byte(`{"n":2}`)))
+	require.NoError(t, fanout.Remember(ctx, 2, "c", []byte(`{"n":3}`)))
+
+	recent, err := fanout.Recent(ctx, 1, recentTTL)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte(`{"n":1}`), []byte(`{"n":2}`)}, recent)
+
+	// presence
+	require.NoError(t, fanout.SetOnline(ctx, 1, true))
+	require.NoError(t, other.SetOnline(ctx, 2, true))
+	require.NoError(t, other.SetAway(ctx, 2, true))
+	require.NoError(t, other.SetAway(ctx, 4, true))
+	presence, err := fanout.Presence(ctx, []int32{1, 2, 3, 4})
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]PresenceStatus{1: PresenceOnline, 2: PresenceAway}, presence)
+
+	require.NoError(t, fanout.SetOnline(ctx, 1, false))
+	require.NoError(t, other.Heartbeat(ctx, []int32{2, 3}))
+	presence, err = fanout.Presence(ctx, []int32{1, 2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]PresenceStatus{2: PresenceAway, 3: PresenceOnline}, presence)
+
+	// publish
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	delivered := make(chan string, 1)
+	go fanout.Subscribe(subCtx, func(userId int32, payload []byte) {
+		assert.Equal(t, int32(1), userId)
+		delivered <- string(payload)
+	})
+
+	// subscribing may take a moment, so keep publishing until it's heard
+	for {
+		require.NoError(t, other.Publish(ctx, 1, []byte(`{"typing":true}`)))
+		select {
+		case payload := <-delivered:
+			assert.Equal(t, `{"typing":true}`, payload)
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func TestMemoryFanout(t *testing.T) {
+	// a single replica is its own other replica
+	fanout := NewMemoryFanout()
+	testWebsocketFanout(t, fanout, fanout)
+}
+
+func TestPostgresFanout(t *testing.T) {
+	pool := database.CreateTestDatabase(t, "test_comms")
+	defer pool.Close()
+
+	fanout := NewPostgresFanout(pool, zap.NewNop())
+	other := NewPostgresFanout(pool, zap.NewNop())
+	testWebsocketFanout(t, fanout, other)
+}
@@ -0,0 +1,232 @@
+package comms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"bridgerton.audius.co/api/dbv1"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// How far back a websocket can resume from.
+// Clients that were away longer refetch their chats instead.
+const resumeMaxAge = 72 * time.Hour
+
+var (
+	ErrInvalidResumeCursor = errors.New("since must be a timestamp or the signature of an rpc")
+	ErrResumeCursorExpired = errors.New("since is too old to resume from, refetch chats instead")
+)
+
+// A push a websocket missed, to replay
+type websocketEvent struct {
+	senderUserId int32
+	rpcJson      json.RawMessage
+	timestamp    time.Time
+}
+
+// ResumeCursor reads the since param of a websocket handshake:
+// the timestamp of the last push the client saw (its metadata.timestamp),
+// or the signature of the last rpc it saw in rpc_log.
+func (proc *RPCProcessor) ResumeCursor(ctx context.Context, since string) (time.Time, error) {
+	cursor, err := time.Parse(time.RFC3339Nano, since)
+	if err != nil {
+		err = proc.writePool.QueryRow(ctx, `SELECT relayed_at FROM rpc_log WHERE sig = $1`, since).Scan(&cursor)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, ErrInvalidResumeCursor
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if time.Since(cursor) > resumeMaxAge {
+		return time.Time{}, ErrResumeCursorExpired
+	}
+	return cursor.UTC(), nil
+}
+
+// The chat messages, edits, reactions and blasts pushed to userId after since, oldest first.
+// Reads the write pool, so nothing committed before the websocket registered is missed.
+func (proc *RPCProcessor) missedWebsocketEvents(ctx context.Context, userId int32, since time.Time) ([]websocketEvent, error) {
+	args := pgx.NamedArgs{
+		"userId": userId,
+		"since":  since,
+	}
+	events := []websocketEvent{}
+
+	// Messages, as handleChatMessageInserted pushes them
+	type missedMessage struct {
+		MessageID   string                  `db:"message_id"`
+		ChatID      string                  `db:"chat_id"`
+		UserID      int32                   `db:"user_id"`
+		CreatedAt   time.Time               `db:"created_at"`
+		Ciphertext  pgtype.Text             `db:"ciphertext"`
+		IsPlaintext bool                    `db:"is_plaintext"`
+		Attachments []ChatMessageAttachment `db:"attachments"`
+	}
+	rows, err := proc.writePool.Query(ctx, `
+		SELECT
+			chat_message.message_id,
+			chat_message.chat_id,
+			chat_message.user_id,
+			chat_message.created_at,
+			COALESCE(chat_message.ciphertext, chat_blast.plaintext) AS ciphertext,
+			chat_blast.plaintext IS NOT NULL AS is_plaintext,
+			`+chatMessageAttachmentsSQL+` AS attachments
+		FROM chat_message
+		JOIN chat_member ON chat_message.chat_id = chat_member.chat_id
+			AND chat_member.user_id = @userId
+			AND chat_member.is_hidden = false
+		LEFT JOIN chat_blast USING (blast_id)
+		WHERE chat_message.created_at > @since
+			AND chat_message.deleted_at IS NULL
+			AND chat_message.user_id != @userId
+			AND (chat_member.cleared_history_at IS NULL OR chat_message.created_at > chat_member.cleared_history_at)`, args)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := pgx.CollectRows(rows, pgx.RowToStructByName[missedMessage])
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range messages {
+		rpcJson, err := chatMessageRPCJson(m.ChatID, m.MessageID, m.Ciphertext.String, m.IsPlaintext, m.Attachments)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, websocketEvent{m.UserID, rpcJson, m.CreatedAt})
+	}
+
+	// Edits and deletes, as handleChatMessageEdited pushes them.
+	// Only a message's latest change is replayed.
+	rows, err = proc.writePool.Query(ctx, `
+		SELECT
+			chat_message.message_id,
+			chat_message.chat_id,
+			chat_message.user_id,
+			chat_message.ciphertext,
+			chat_message.edited_at,
+			chat_message.deleted_at
+		FROM chat_message
+		JOIN chat_member ON chat_message.chat_id = chat_member.chat_id
+			AND chat_member.user_id = @userId
+			AND chat_member.is_hidden = false
+		WHERE (chat_message.edited_at > @since OR chat_message.deleted_at > @since)
+			AND chat_message.user_id != @userId
+			AND (chat_member.cleared_history_at IS NULL OR chat_message.created_at > chat_member.cleared_history_at)`, args)
+	if err != nil {
+		return nil, err
+	}
+	edits, err := pgx.CollectRows(rows, pgx.RowToStructByName[editedChatMessage])
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range edits {
+		rpcJson, timestamp, err := chatMessageEditRPCJson(m)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, websocketEvent{m.UserID, rpcJson, timestamp})
+	}
+
+	// Reactions, as handleChatMessageReactionChanged pushes them.
+	// Removed reactions are gone from chat_message_reactions,
+	// so those come from the chat.react rpcs that removed them.
+	type missedReaction struct {
+		MessageID string      `db:"message_id"`
+		ChatID    string      `db:"chat_id"`
+		UserID    int32       `db:"user_id"`
+		Reaction  pgtype.Text `db:"reaction"`
+		UpdatedAt time.Time   `db:"updated_at"`
+	}
+	rows, err = proc.writePool.Query(ctx, `
+		SELECT
+			chat_message_reactions.message_id,
+			chat_message.chat_id,
+			chat_message_reactions.user_id,
+			chat_message_reactions.reaction,
+			chat_message_reactions.updated_at
+		FROM chat_message_reactions
+		JOIN chat_message USING (message_id)
+		JOIN chat_member ON chat_message.chat_id = chat_member.chat_id
+			AND chat_member.user_id = @userId
+			AND chat_member.is_hidden = false
+		WHERE chat_message_reactions.updated_at > @since
+			AND chat_message_reactions.user_id != @userId
+
+		UNION ALL
+
+		SELECT DISTINCT ON (rpc_log.sig)
+			rpc_log.rpc->'params'->>'message_id',
+			chat_member.chat_id,
+			users.user_id,
+			NULL,
+			rpc_log.relayed_at
+		FROM rpc_log
+		JOIN chat_member ON chat_member.chat_id = rpc_log.rpc->'params'->>'chat_id'
+			AND chat_member.user_id = @userId
+			AND chat_member.is_hidden = false
+		JOIN users ON users.wallet = rpc_log.from_wallet
+			AND users.is_current = true
+			AND users.user_id != @userId
+		WHERE rpc_log.applied_at > @since
+			AND rpc_log.relayed_at > @since
+			AND rpc_log.rpc->>'method' = 'chat.react'
+			AND rpc_log.rpc->'params'->>'reaction' IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM chat_message_reactions
+				WHERE chat_message_reactions.message_id = rpc_log.rpc->'params'->>'message_id'
+					AND chat_message_reactions.user_id = users.user_id
+			)`, args)
+	if err != nil {
+		return nil, err
+	}
+	reactions, err := pgx.CollectRows(rows, pgx.RowToStructByName[missedReaction])
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reactions {
+		var reaction *string
+		if r.Reaction.Valid {
+			reaction = &r.Reaction.String
+		}
+		rpcJson, err := chatReactRPCJson(r.ChatID, r.MessageID, reaction)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, websocketEvent{r.UserID, rpcJson, r.UpdatedAt})
+	}
+
+	// Blasts to an audience the user is in
+	rows, err = proc.writePool.Query(ctx, `
+		SELECT blast_id, from_user_id, audience, audience_content_id, plaintext, created_at, audience_content_type
+		FROM chat_blast
+		WHERE created_at > @since
+			AND EXISTS (
+				SELECT 1 FROM chat_blast_audience(chat_blast.blast_id)
+				WHERE to_user_id = @userId
+			)`, args)
+	if err != nil {
+		return nil, err
+	}
+	blasts, err := pgx.CollectRows(rows, pgx.RowToStructByName[dbv1.ChatBlast])
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range blasts {
+		rpcJson, err := chatBlastRPCJson(b)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, websocketEvent{b.FromUserID, rpcJson, b.CreatedAt.Time})
+	}
+
+	slices.SortStableFunc(events, func(a, b websocketEvent) int {
+		return a.timestamp.Compare(b.timestamp)
+	})
+	return events, nil
+}
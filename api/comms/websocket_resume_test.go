@@ -0,0 +1,131 @@
+package comms
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"bridgerton.audius.co/database"
+	"bridgerton.audius.co/trashid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	"go.uber.org/zap"
+)
+
+func TestMissedWebsocketEvents(t *testing.T) {
+	pool := database.CreateTestDatabase(t, "test_comms")
+	defer pool.Close()
+	ctx := context.Background()
+
+	t0 := time.Now().Add(-time.Minute * 10).UTC().Round(time.Microsecond)
+	at := func(minutes int) time.Time {
+		return t0.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	database.Seed(pool, database.FixtureMap{
+		"users": {
+			{"user_id": 1, "wallet": "wallet1", "handle": "user1"},
+			{"user_id": 2, "wallet": "wallet2", "handle": "user2"},
+			{"user_id": 3, "wallet": "wallet3", "handle": "user3"},
+		},
+		"follows": {
+			{"follower_user_id": 1, "followee_user_id": 3, "created_at": t0},
+		},
+	})
+
+	chatId := trashid.ChatID(1, 2)
+	SetupChatWithMembers(t, pool, ctx, chatId, 1, 2, "invite1", "invite2")
+
+	heart := "heart"
+	fire := "fire"
+
+	// before the cursor
+	require.NoError(t, chatSendMessage(pool, ctx, 2, chatId, "m1", at(1), "first"))
+	require.NoError(t, chatReactMessage(pool, ctx, 2, chatId, "m1", &heart, at(1)))
+
+	// after it
+	require.NoError(t, chatSendMessage(pool, ctx, 2, chatId, "m2", at(3), "second"))
+	require.NoError(t, chatSendMessage(pool, ctx, 1, chatId, "m3", at(3), "my own"))
+	require.NoError(t, chatReactMessage(pool, ctx, 2, chatId, "m3", &fire, at(4)))
+
+	unreact, err := json.Marshal(ChatReactRPC{
+		Method: MethodChatReact,
+		Params: ChatReactRPCParams{ChatID: chatId, MessageID: "m1"},
+	})
+	require.NoError(t, err)
+	_, err = insertRpcLogRow(pool, ctx, &RpcLog{
+		RelayedBy:  "bridge",
+		RelayedAt:  at(5),
+		FromWallet: "wallet2",
+		Rpc:        unreact,
+		Sig:        "unreact-sig",
+	})
+	require.NoError(t, err)
+	require.NoError(t, chatReactMessage(pool, ctx, 2, chatId, "m1", nil, at(5)))
+
+	_, err = chatBlast(pool, ctx, 3, at(6), ChatBlastRPCParams{
+		BlastID:  "b1",
+		Audience: FollowerAudience,
+		Message:  "hello followers",
+	})
+	require.NoError(t, err)
+
+	proc := &RPCProcessor{writePool: pool, logger: zap.NewNop()}
+
+	events, err := proc.missedWebsocketEvents(ctx, 1, at(2))
+	require.NoError(t, err)
+
+	summaries := []string{}
+	for _, event := range events {
+		summaries = append(summaries, gjson.GetBytes(event.rpcJson, "method").String()+" "+
+			gjson.GetBytes(event.rpcJson, "params.message_id").String()+
+			gjson.GetBytes(event.rpcJson, "params.blast_id").String()+" "+
+			gjson.GetBytes(event.rpcJson, "params.reaction").String())
+	}
+	assert.Equal(t, []string{
+		"chat.message m2 ",
+		"chat.react m3 fire",
+		"chat.react m1 ",
+		"chat.blast b1 ",
+	}, summaries)
+	assert.Equal(t, int32(2), events[2].senderUserId)
+	assert.Equal(t, at(5), events[2].timestamp)
+
+	// the cursor can be the signature of the last rpc seen
+	cursor, err := proc.ResumeCursor(ctx, "unreact-sig")
+	require.NoError(t, err)
+	assert.Equal(t, at(5), cursor)
+	events, err = proc.missedWebsocketEvents(ctx, 1, cursor)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "chat.blast", gjson.GetBytes(events[0].rpcJson, "method").String())
+
+	cursor, err = proc.ResumeCursor(ctx, at(2).Format(time.RFC3339Nano))
+	require.NoError(t, err)
+	assert.Equal(t, at(2), cursor)
+
+	_, err = proc.ResumeCursor(ctx, "nope")
+	assert.ErrorIs(t, err, ErrInvalidResumeCursor)
+	_, err = proc.ResumeCursor(ctx, time.Now().Add(-resumeMaxAge*2).Format(time.RFC3339Nano))
+	assert.ErrorIs(t, err, ErrResumeCursorExpired)
+}
+
+func TestPushKey(t *testing.T) {
+	rpcJson, err := chatReactRPCJson("chat", "m1", nil)
+	require.NoError(t, err)
+
+	live, err := websocketPayload(2, 1, rpcJson, time.Now())
+	require.NoError(t, err)
+	replayed, err := websocketPayload(2, 1, rpcJson, time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, pushKey(live), pushKey(replayed))
+
+	fire := "fire"
+	rpcJson, err = chatReactRPCJson("chat", "m1", &fire)
+	require.NoError(t, err)
+	other, err := websocketPayload(2, 1, rpcJson, time.Now())
+	require.NoError(t, err)
+	assert.NotEqual(t, pushKey(live), pushKey(other))
+}
@@ -0,0 +1,40 @@
+package comms
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestPushLocalWhileClientsChange(t *testing.T) {
+	m := NewCommsWebsocketManager(nil, zap.NewNop(), NewMemoryFanout())
+	const pushes = 500
+
+	// a client that stays, and others that come and go as it's pushed to
+	stays := &Client{userId: 1, send: make(chan []byte, pushes), manager: m}
+	m.clients[1] = map[*Client]struct{}{stays: {}}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range pushes {
+			cl := &Client{userId: 1, send: make(chan []byte, pushes), manager: m}
+			m.mu.Lock()
+			m.clients[1][cl] = struct{}{}
+			m.mu.Unlock()
+			m.mu.Lock()
+			delete(m.clients[1], cl)
+			m.mu.Unlock()
+		}
+	}()
+
+	for range pushes {
+		assert.GreaterOrEqual(t, m.pushLocal(1, []byte(`{}`)), 1)
+	}
+	wg.Wait()
+
+	assert.Len(t, stays.send, pushes)
+}
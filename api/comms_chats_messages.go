@@ -1,10 +1,12 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"time"
 
+	"bridgerton.audius.co/api/comms"
 	"bridgerton.audius.co/api/dbv1"
 	"github.com/gofiber/fiber/v2"
 	"github.com/jackc/pgx/v5"
@@ -29,9 +31,17 @@ func (app *ApiServer) getChatMessages(c *fiber.Ctx) error {
 		chat_message.user_id,
 		chat_message.created_at,
 		COALESCE(chat_blast.audience, '') AS audience,
-		COALESCE(chat_message.ciphertext, chat_blast.plaintext) AS ciphertext,
+		COALESCE(chat_message.ciphertext, chat_blast.plaintext, '') AS ciphertext,
 		chat_blast.plaintext IS NOT NULL as is_plaintext,
-		to_json(array(SELECT row_to_json(r) FROM chat_message_reactions r WHERE chat_message.message_id = r.message_id)) AS reactions
+		chat_message.edited_at,
+		chat_message.deleted_at,
+		to_json(array(SELECT row_to_json(r) FROM chat_message_reactions r WHERE chat_message.message_id = r.message_id)) AS reactions,
+		to_json(array(
+			SELECT json_build_object('type', a.type, 'cid', a.cid, 'mime_type', a.mime_type, 'id', a.entity_id)
+			FROM chat_message_attachments a
+			WHERE chat_message.message_id = a.message_id
+			ORDER BY a.ordinal
+		)) AS attachments
 	FROM chat_message
 	JOIN chat_member ON chat_message.chat_id = chat_member.chat_id
 	LEFT JOIN chat_blast USING (blast_id)
@@ -75,7 +85,10 @@ func (app *ApiServer) getChatMessages(c *fiber.Ctx) error {
 		'' as audience,
 		b.plaintext as ciphertext,
 		true as is_plaintext,
-		'[]'::json AS reactions
+		NULL::timestamp AS edited_at,
+		NULL::timestamp AS deleted_at,
+		'[]'::json AS reactions,
+		'[]'::json AS attachments
 	FROM chat_blast b
 	WHERE b.from_user_id = @user_id
 		AND concat_ws(':', audience, audience_content_type, 
@@ -213,6 +226,11 @@ func (app *ApiServer) getChatMessages(c *fiber.Ctx) error {
 		return err
 	}
 
+	err = app.hydrateChatMessageAttachments(c.Context(), int32(userId), rows)
+	if err != nil {
+		return err
+	}
+
 	if len(rows) > 0 {
 		beforeCursorPos = rows[len(rows)-1].CreatedAt
 		afterCursorPos = rows[0].CreatedAt
@@ -239,3 +257,56 @@ func (app *ApiServer) getChatMessages(c *fiber.Ctx) error {
 		},
 	})
 }
+
+// Loads the tracks, playlists and users attached to messages in one go,
+// so clients can show them without looking each one up
+func (app *ApiServer) hydrateChatMessageAttachments(ctx context.Context, myId int32, messages []dbv1.ChatMessageAndReactionsRow) error {
+	params := dbv1.ParallelParams{
+		MyID: myId,
+	}
+	for _, m := range messages {
+		for _, a := range m.Attachments {
+			if a.ID == nil {
+				continue
+			}
+			switch comms.ChatMessageAttachmentType(a.Type) {
+			case comms.AttachmentTrack:
+				params.TrackIds = append(params.TrackIds, int32(*a.ID))
+			case comms.AttachmentPlaylist:
+				params.PlaylistIds = append(params.PlaylistIds, int32(*a.ID))
+			case comms.AttachmentUser:
+				params.UserIds = append(params.UserIds, int32(*a.ID))
+			}
+		}
+	}
+
+	loaded, err := app.queries.Parallel(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range messages {
+		for idx, a := range m.Attachments {
+			if a.ID == nil {
+				continue
+			}
+			id := int32(*a.ID)
+			switch comms.ChatMessageAttachmentType(a.Type) {
+			case comms.AttachmentTrack:
+				if t, ok := loaded.TrackMap[id]; ok {
+					a.Item = t
+				}
+			case comms.AttachmentPlaylist:
+				if p, ok := loaded.PlaylistMap[id]; ok {
+					a.Item = p
+				}
+			case comms.AttachmentUser:
+				if u, ok := loaded.UserMap[id]; ok {
+					a.Item = u
+				}
+			}
+			m.Attachments[idx] = a
+		}
+	}
+	return nil
+}
@@ -279,3 +279,120 @@ func TestGetChatMessagesWithClearedHistory(t *testing.T) {
 		})
 	})
 }
+
+func TestGetChatMessagesAttachments(t *testing.T) {
+	app := emptyTestApp(t)
+
+	now := time.Now()
+	fixtures := database.FixtureMap{
+		"users": {
+			{
+				"user_id":    1,
+				"handle":     "user1",
+				"wallet":     "0x7d273271690538cf855e5b3002a0dd8c154bb060",
+				"created_at": now.Add(-time.Hour),
+				"updated_at": now.Add(-time.Hour),
+				"is_current": true,
+			},
+			{
+				"user_id":    2,
+				"handle":     "user2",
+				"wallet":     "0xc3d1d41e6872ffbd15c473d14fc3a9250be5b5e0",
+				"created_at": now.Add(-time.Hour),
+				"updated_at": now.Add(-time.Hour),
+				"is_current": true,
+			},
+		},
+		"tracks": {
+			{
+				"track_id":   10,
+				"owner_id":   2,
+				"title":      "Shared Track",
+				"created_at": now.Add(-time.Hour),
+				"updated_at": now.Add(-time.Hour),
+			},
+		},
+		"chat": {
+			{
+				"chat_id":         "test-chat-attachments",
+				"created_at":      now.Add(-time.Hour),
+				"last_message_at": now.Add(-time.Minute * 5),
+			},
+		},
+		"chat_member": {
+			{
+				"chat_id":            "test-chat-attachments",
+				"user_id":            1,
+				"invited_by_user_id": 1,
+				"invite_code":        "",
+				"created_at":         now.Add(-time.Hour),
+			},
+			{
+				"chat_id":            "test-chat-attachments",
+				"user_id":            2,
+				"invited_by_user_id": 1,
+				"invite_code":        "",
+				"created_at":         now.Add(-time.Hour),
+			},
+		},
+		"chat_message": {
+			{
+				"message_id": "msg1",
+				"chat_id":    "test-chat-attachments",
+				"user_id":    2,
+				"created_at": now.Add(-time.Minute * 5),
+				"ciphertext": "check these out",
+			},
+		},
+		"chat_message_attachments": {
+			{
+				"message_id": "msg1",
+				"ordinal":    0,
+				"type":       "track",
+				"entity_id":  10,
+			},
+			{
+				"message_id": "msg1",
+				"ordinal":    1,
+				"type":       "media",
+				"cid":        "bafkreiexample",
+				"mime_type":  "image/png",
+			},
+			{
+				"message_id": "msg1",
+				"ordinal":    2,
+				"type":       "user",
+				"entity_id":  2,
+			},
+			{
+				"message_id": "msg1",
+				"ordinal":    3,
+				"type":       "playlist",
+				"entity_id":  404,
+			},
+		},
+	}
+
+	database.Seed(app.pool.Replicas[0], fixtures)
+
+	status, body := testGetWithWallet(t, app, "/comms/chats/test-chat-attachments/messages", "0x7d273271690538cf855e5b3002a0dd8c154bb060")
+	assert.Equal(t, 200, status)
+
+	jsonAssert(t, body, map[string]any{
+		"data.0.message_id":                 "msg1",
+		"data.0.attachments.0.type":         "track",
+		"data.0.attachments.0.id":           trashid.MustEncodeHashID(10),
+		"data.0.attachments.0.item.title":   "Shared Track",
+		"data.0.attachments.0.item.user.id": trashid.MustEncodeHashID(2),
+		"data.0.attachments.1.type":         "media",
+		"data.0.attachments.1.cid":          "bafkreiexample",
+		"data.0.attachments.1.mime_type":    "image/png",
+		"data.0.attachments.1.item":         nil,
+		"data.0.attachments.2.type":         "user",
+		"data.0.attachments.2.item.handle":  "user2",
+		"data.0.attachments.3.type":         "playlist",
+		"data.0.attachments.3.item":         nil, // gone, so nothing to show
+		"data.0.edited_at":                  nil,
+		"health.is_healthy":                 true,
+	})
+}
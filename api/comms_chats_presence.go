@@ -0,0 +1,74 @@
+package api
+
+import (
+	"bridgerton.audius.co/api/comms"
+	"bridgerton.audius.co/trashid"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+type ChatMemberPresence struct {
+	UserID trashid.HashId       `json:"user_id"`
+	Status comms.PresenceStatus `json:"status"`
+}
+
+func (app *ApiServer) getChatPresence(c *fiber.Ctx) error {
+	sql := `
+	SELECT user_id
+	FROM chat_member
+	WHERE chat_id = @chat_id
+		AND EXISTS (
+			SELECT 1 FROM chat_member
+			WHERE chat_id = @chat_id AND user_id = @user_id
+		)
+	ORDER BY user_id
+	;`
+
+	params := &GetChatRouteParams{}
+	err := c.ParamsParser(params)
+	if err != nil {
+		return err
+	}
+
+	wallet := app.getAuthedWallet(c)
+	userId, err := app.getUserIDFromWallet(c.Context(), wallet)
+	if err != nil {
+		return err
+	}
+
+	rawRows, err := app.pool.Query(c.Context(), sql, pgx.NamedArgs{
+		"user_id": userId,
+		"chat_id": params.ChatID,
+	})
+	if err != nil {
+		return err
+	}
+
+	memberIds, err := pgx.CollectRows(rawRows, pgx.RowTo[int32])
+	if err != nil {
+		return err
+	}
+	if len(memberIds) == 0 {
+		return fiber.NewError(fiber.StatusForbidden, "user is not a member of this chat")
+	}
+
+	presence, err := app.commsRpcProcessor.Presence(c.Context(), memberIds)
+	if err != nil {
+		return err
+	}
+
+	data := make([]ChatMemberPresence, 0, len(memberIds))
+	for _, memberId := range memberIds {
+		data = append(data, ChatMemberPresence{
+			UserID: trashid.HashId(memberId),
+			Status: presence[memberId],
+		})
+	}
+
+	return c.JSON(CommsResponse{
+		Data: data,
+		Health: CommsHealth{
+			IsHealthy: true,
+		},
+	})
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"bridgerton.audius.co/database"
+	"bridgerton.audius.co/trashid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetChatPresence(t *testing.T) {
+	app := emptyTestApp(t)
+
+	now := time.Now()
+	database.Seed(app.pool.Replicas[0], database.FixtureMap{
+		"users": {
+			{"user_id": 1, "handle": "user1", "wallet": "0x7d273271690538cf855e5b3002a0dd8c154bb060"},
+			{"user_id": 2, "handle": "user2", "wallet": "0xc3d1d41e6872ffbd15c473d14fc3a9250be5b5e0"},
+			{"user_id": 3, "handle": "user3", "wallet": "0x4954d18926ba0ed9378938444731be4e622537b2"},
+		},
+		"chat": {
+			{"chat_id": "test-chat-1", "created_at": now, "last_message_at": now},
+		},
+		"chat_member": {
+			{"chat_id": "test-chat-1", "user_id": 1, "invited_by_user_id": 1, "invite_code": "", "created_at": now},
+			{"chat_id": "test-chat-1", "user_id": 2, "invited_by_user_id": 1, "invite_code": "", "created_at": now},
+		},
+	})
+
+	// the test app doesn't push over websockets, so nobody is connected
+	status, body := testGetWithWallet(t, app, "/comms/chats/test-chat-1/presence", "0x7d273271690538cf855e5b3002a0dd8c154bb060")
+	assert.Equal(t, 200, status)
+	jsonAssert(t, body, map[string]any{
+		"data.0.user_id":    trashid.MustEncodeHashID(1),
+		"data.0.status":     "offline",
+		"data.1.user_id":    trashid.MustEncodeHashID(2),
+		"data.1.status":     "offline",
+		"health.is_healthy": true,
+	})
+
+	status, _ = testGetWithWallet(t, app, "/comms/chats/test-chat-1/presence", "0x4954d18926ba0ed9378938444731be4e622537b2")
+	assert.Equal(t, 403, status)
+}
@@ -1,11 +1,14 @@
 package api
 
 import (
+	"time"
+
 	"github.com/gofiber/contrib/websocket"
 )
 
 func (app *ApiServer) getChatWebsocket(conn *websocket.Conn) {
 	userId := int32(conn.Locals("websocketUserId").(int))
+	since, _ := conn.Locals("websocketSince").(*time.Time)
 
-	app.commsRpcProcessor.RegisterWebsocket(userId, conn)
+	app.commsRpcProcessor.RegisterWebsocket(userId, conn, since)
 }
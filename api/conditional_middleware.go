@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Answers 304 Not Modified when a GET response's validators
+// (see entityVersions) match the request's If-None-Match or If-Modified-Since
+func (app *ApiServer) conditionalRequestMiddleware(c *fiber.Ctx) error {
+	if err := c.Next(); err != nil {
+		return err
+	}
+
+	method := c.Method()
+	if method != fiber.MethodGet && method != fiber.MethodHead {
+		return nil
+	}
+	if c.Response().StatusCode() != fiber.StatusOK || !isNotModified(c) {
+		return nil
+	}
+
+	c.Response().ResetBody()
+	c.Response().Header.Del(fiber.HeaderContentType)
+	c.Status(fiber.StatusNotModified)
+	return nil
+}
+
+// If-Modified-Since only counts without an If-None-Match (RFC 9110 13.1.3)
+func isNotModified(c *fiber.Ctx) bool {
+	if ifNoneMatch := c.Get(fiber.HeaderIfNoneMatch); ifNoneMatch != "" {
+		etag := string(c.Response().Header.Peek(fiber.HeaderETag))
+		return etag != "" && etagMatches(ifNoneMatch, etag)
+	}
+
+	ifModifiedSince := c.Get(fiber.HeaderIfModifiedSince)
+	lastModified := string(c.Response().Header.Peek(fiber.HeaderLastModified))
+	if ifModifiedSince == "" || lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.After(since)
+}
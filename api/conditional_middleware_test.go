@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bridgerton.audius.co/database"
+	"bridgerton.audius.co/trashid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	app := emptyTestApp(t)
+	updatedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	database.Seed(app.pool.Replicas[0], database.FixtureMap{
+		"users": {
+			{"user_id": 1, "handle": "artist", "handle_lc": "artist"},
+			{"user_id": 2, "handle": "fan", "handle_lc": "fan"},
+		},
+		"tracks": {
+			{"track_id": 1, "owner_id": 1, "title": "polled", "updated_at": updatedAt},
+		},
+	})
+
+	get := func(path string, headers map[string]string) *http.Response {
+		req := httptest.NewRequest("GET", path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		res, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return res
+	}
+
+	// without the signed stream link, which is new on every request
+	path := "/v1/full/tracks/" + trashid.MustEncodeHashID(1) + "?fields=id,title,repost_count"
+
+	res := get(path, nil)
+	assert.Equal(t, 200, res.StatusCode)
+	etag := res.Header.Get("ETag")
+	assert.Regexp(t, `^W/"[0-9a-f]+"$`, etag)
+	assert.Equal(t, updatedAt.Format(http.TimeFormat), res.Header.Get("Last-Modified"))
+
+	// the same track looks the same
+	assert.Equal(t, etag, get(path, nil).Header.Get("ETag"))
+
+	res = get(path, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, 304, res.StatusCode)
+	assert.Equal(t, etag, res.Header.Get("ETag"))
+
+	res = get(path, map[string]string{"If-None-Match": `W/"0"`})
+	assert.Equal(t, 200, res.StatusCode)
+
+	res = get(path, map[string]string{"If-Modified-Since": updatedAt.Format(http.TimeFormat)})
+	assert.Equal(t, 304, res.StatusCode)
+
+	res = get(path, map[string]string{"If-Modified-Since": updatedAt.Add(-time.Second).Format(http.TimeFormat)})
+	assert.Equal(t, 200, res.StatusCode)
+
+	// If-None-Match wins over If-Modified-Since
+	res = get(path, map[string]string{
+		"If-None-Match":     `W/"0"`,
+		"If-Modified-Since": updatedAt.Format(http.TimeFormat),
+	})
+	assert.Equal(t, 200, res.StatusCode)
+
+	// another viewer gets another version, which is theirs alone
+	res = get(path+"&user_id="+trashid.MustEncodeHashID(2), map[string]string{"If-None-Match": etag})
+	assert.Equal(t, 200, res.StatusCode)
+	assert.NotEqual(t, etag, res.Header.Get("ETag"))
+	assert.Equal(t, "private, no-cache", res.Header.Get("Cache-Control"))
+
+	// a repost changes the count, but not updated_at
+	database.Seed(app.pool.Replicas[0], database.FixtureMap{
+		"reposts": {
+			{"user_id": 2, "repost_item_id": 1, "repost_type": "track"},
+		},
+	})
+	res = get(path, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, 200, res.StatusCode)
+
+	// signed links are signed anew every time, but the track is the same
+	signedPath := "/v1/full/tracks/" + trashid.MustEncodeHashID(1)
+	res = get(signedPath, nil)
+	assert.Equal(t, 200, res.StatusCode)
+	signedEtag := res.Header.Get("ETag")
+	assert.Equal(t, updatedAt.Format(http.TimeFormat), res.Header.Get("Last-Modified"))
+
+	time.Sleep(5 * time.Millisecond)
+	res = get(signedPath, map[string]string{"If-None-Match": signedEtag})
+	assert.Equal(t, 304, res.StatusCode)
+
+	res = get(signedPath, map[string]string{"If-Modified-Since": updatedAt.Format(http.TimeFormat)})
+	assert.Equal(t, 304, res.StatusCode)
+
+	res = get("/v1/tracks/"+trashid.MustEncodeHashID(1), nil)
+	assert.Equal(t, 200, res.StatusCode)
+	res = get("/v1/tracks/"+trashid.MustEncodeHashID(1), map[string]string{"If-None-Match": res.Header.Get("ETag")})
+	assert.Equal(t, 304, res.StatusCode)
+}
+
+func TestMediaLinkSignature(t *testing.T) {
+	a := `{"url":"https://node/tracks/cidstream/Qm?id3=true\u0026signature=%7B%22data%22%3A1%7D\u0026id3_title=x"}`
+	b := `{"url":"https://node/tracks/cidstream/Qm?id3=true\u0026signature=%7B%22data%22%3A2%7D\u0026id3_title=x"}`
+	assert.Equal(t,
+		string(mediaLinkSignature.ReplaceAll([]byte(a), []byte("signature="))),
+		string(mediaLinkSignature.ReplaceAll([]byte(b), []byte("signature="))))
+	assert.Contains(t, string(mediaLinkSignature.ReplaceAll([]byte(a), []byte("signature="))), `id3_title=x`)
+}
@@ -9,14 +9,25 @@ import (
 )
 
 type ChatMessageAndReactionsRow struct {
-	MessageID   string                   `db:"message_id" json:"message_id"`
-	ChatID      string                   `db:"chat_id" json:"-"`
-	UserID      trashid.HashId           `db:"user_id" json:"sender_user_id"`
-	CreatedAt   time.Time                `db:"created_at" json:"created_at"`
-	Audience    string                   `db:"audience" json:"audience"`
-	Ciphertext  string                   `db:"ciphertext" json:"message"`
-	IsPlaintext bool                     `db:"is_plaintext" json:"is_plaintext"`
-	Reactions   []ChatMessageReactionRow `json:"reactions"`
+	MessageID   string                     `db:"message_id" json:"message_id"`
+	ChatID      string                     `db:"chat_id" json:"-"`
+	UserID      trashid.HashId             `db:"user_id" json:"sender_user_id"`
+	CreatedAt   time.Time                  `db:"created_at" json:"created_at"`
+	Audience    string                     `db:"audience" json:"audience"`
+	Ciphertext  string                     `db:"ciphertext" json:"message"`
+	IsPlaintext bool                       `db:"is_plaintext" json:"is_plaintext"`
+	EditedAt    *time.Time                 `db:"edited_at" json:"edited_at"`
+	DeletedAt   *time.Time                 `db:"deleted_at" json:"deleted_at"` // unsent, with an empty message
+	Reactions   []ChatMessageReactionRow   `json:"reactions"`
+	Attachments []ChatMessageAttachmentRow `json:"attachments"`
+}
+
+type ChatMessageAttachmentRow struct {
+	Type     string          `json:"type"`
+	CID      *string         `json:"cid,omitempty"`
+	MimeType *string         `json:"mime_type,omitempty"`
+	ID       *trashid.HashId `json:"id,omitempty"`
+	Item     any             `json:"item,omitempty"` // the track, playlist or user attached, if it's still there
 }
 
 type ChatMessageReactionRow struct {
@@ -0,0 +1,63 @@
+package dbv1
+
+import "strings"
+
+// Fields is a sparse fieldset, as given by the fields= query param:
+// json paths like "id", "title" and "user.handle".
+// A path includes everything under it, and the path to it,
+// so "user.handle" includes "user", but only the handle of it.
+//
+// nil includes everything, and is what callers that don't prune get.
+// An empty, non-nil Fields includes nothing.
+type Fields []string
+
+// Parses a comma separated list of paths. Blank ones are skipped.
+func ParseFields(s string) Fields {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	fields := Fields{}
+	for _, path := range strings.Split(s, ",") {
+		path = strings.Trim(strings.TrimSpace(path), ".")
+		if path != "" {
+			fields = append(fields, path)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// Whether the field at name is in the response, in whole or in part
+func (f Fields) Includes(name string) bool {
+	if f == nil {
+		return true
+	}
+	for _, path := range f {
+		if path == name || strings.HasPrefix(path, name+".") || strings.HasPrefix(name, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// The fields under name, e.g. Sub("user") of "id,user.handle" is "handle".
+// Everything under name is included when name itself is.
+func (f Fields) Sub(name string) Fields {
+	if f == nil {
+		return nil
+	}
+
+	sub := Fields{}
+	for _, path := range f {
+		if path == name || strings.HasPrefix(name, path+".") {
+			return nil
+		}
+		if rest, ok := strings.CutPrefix(path, name+"."); ok {
+			sub = append(sub, rest)
+		}
+	}
+	return sub
+}
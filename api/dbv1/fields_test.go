@@ -0,0 +1,50 @@
+package dbv1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFields(t *testing.T) {
+	assert.Nil(t, ParseFields(""))
+	assert.Nil(t, ParseFields(" , "))
+	assert.Equal(t, Fields{"id", "user.handle"}, ParseFields("id, user.handle,,"))
+}
+
+func TestFields(t *testing.T) {
+	var all Fields
+	assert.True(t, all.Includes("access"))
+	assert.Nil(t, all.Sub("user"))
+
+	fields := ParseFields("id,user.handle,tracks")
+	assert.True(t, fields.Includes("id"))
+	assert.True(t, fields.Includes("user"))
+	assert.True(t, fields.Includes("user.handle"))
+	assert.False(t, fields.Includes("user.name"))
+	assert.False(t, fields.Includes("access"))
+	assert.False(t, fields.Includes("i"))
+
+	assert.Equal(t, Fields{"handle"}, fields.Sub("user"))
+	assert.Equal(t, Fields{}, fields.Sub("access"))
+	assert.False(t, fields.Sub("access").Includes("stream"))
+
+	// everything under a field that's asked for
+	assert.Nil(t, fields.Sub("tracks"))
+	assert.Nil(t, fields.Sub("tracks").Sub("user"))
+}
+
+func TestFullUsersFields(t *testing.T) {
+	all := FullUsersParams{GetUsersParams: GetUsersParams{MyID: 1}}.getUsersParams()
+	assert.False(t, all.SkipCurrentUserFields)
+	assert.False(t, all.SkipArtistCoinBadge)
+	assert.Equal(t, int32(1), all.MyID)
+
+	some := FullUsersParams{Fields: ParseFields("id,handle")}.getUsersParams()
+	assert.True(t, some.SkipCurrentUserFields)
+	assert.True(t, some.SkipArtistCoinBadge)
+
+	some = FullUsersParams{Fields: ParseFields("id,does_current_user_follow,artist_coin_badge.logo_uri")}.getUsersParams()
+	assert.False(t, some.SkipCurrentUserFields)
+	assert.False(t, some.SkipArtistCoinBadge)
+}
@@ -23,9 +23,11 @@ func (q *Queries) FullAccount(ctx context.Context, wallet string) (*FullAccount,
 		return nil, err
 	}
 
-	users, err := q.FullUsers(ctx, GetUsersParams{
-		Ids:  []int32{int32(userId)},
-		MyID: int32(userId),
+	users, err := q.FullUsers(ctx, FullUsersParams{
+		GetUsersParams: GetUsersParams{
+			Ids:  []int32{int32(userId)},
+			MyID: int32(userId),
+		},
 	})
 	if err != nil {
 		return nil, err
@@ -11,7 +11,8 @@ import (
 type FullPlaylistsParams struct {
 	GetPlaylistsParams
 	OmitTracks bool
-	TrackLimit int // 0 means use default (200), positive values set the limit
+	TrackLimit int    // 0 means use default (200), positive values set the limit
+	Fields     Fields // nil loads everything
 }
 
 type FullPlaylist struct {
@@ -44,13 +45,19 @@ func (q *Queries) FullPlaylistsKeyed(ctx context.Context, arg FullPlaylistsParam
 		return nil, err
 	}
 
+	// the min response counts the tracks it loads,
+	// so they're loaded for its track_count and total_play_count too
+	loadTracks := !arg.OmitTracks && (arg.Fields.Includes("tracks") ||
+		arg.Fields.Includes("track_count") ||
+		arg.Fields.Includes("total_play_count"))
+
 	// pluck user + track IDs
 	trackIds := []int32{}
 	userIds := make([]int32, len(rawPlaylists))
 	for idx, p := range rawPlaylists {
 		userIds[idx] = p.PlaylistOwnerID
 
-		if !arg.OmitTracks {
+		if loadTracks {
 			trackLimit := 200
 			if arg.TrackLimit != 0 {
 				trackLimit = arg.TrackLimit
@@ -69,9 +76,10 @@ func (q *Queries) FullPlaylistsKeyed(ctx context.Context, arg FullPlaylistsParam
 
 	// fetch users + tracks in parallel
 	loaded, err := q.Parallel(ctx, ParallelParams{
-		UserIds:  userIds,
-		TrackIds: trackIds,
-		MyID:     arg.MyID.(int32),
+		UserIds:     userIds,
+		TrackIds:    trackIds,
+		MyID:        arg.MyID.(int32),
+		TrackFields: arg.Fields.Sub("tracks"),
 	})
 	if err != nil {
 		return nil, err
@@ -108,12 +116,15 @@ func (q *Queries) FullPlaylistsKeyed(ctx context.Context, arg FullPlaylistsParam
 		}
 
 		// For playlists, download access is the same as stream access
-		streamAccess := q.GetPlaylistAccess(
-			ctx,
-			arg.MyID.(int32),
-			playlist.StreamConditions,
-			&playlist,
-			&user)
+		streamAccess := false
+		if arg.Fields.Includes("access") {
+			streamAccess = q.GetPlaylistAccess(
+				ctx,
+				arg.MyID.(int32),
+				playlist.StreamConditions,
+				&playlist,
+				&user)
+		}
 		downloadAccess := streamAccess
 
 		var playlistType string
@@ -143,6 +154,7 @@ func (q *Queries) FullPlaylistsKeyed(ctx context.Context, arg FullPlaylistsParam
 		}
 	}
 
+	recordLoaded(ctx, "playlists", playlistMap)
 	return playlistMap, nil
 }
 
@@ -255,9 +255,11 @@ func (q *Queries) FullTips(ctx context.Context, arg GetTipsParams) ([]FullTip, e
 	for id := range userIdSet {
 		userIds = append(userIds, int32(id))
 	}
-	userMap, err := q.FullUsersKeyed(ctx, GetUsersParams{
-		MyID: arg.MyId,
-		Ids:  userIds,
+	userMap, err := q.FullUsersKeyed(ctx, FullUsersParams{
+		GetUsersParams: GetUsersParams{
+			MyID: arg.MyId,
+			Ids:  userIds,
+		},
 	})
 	if err != nil {
 		return nil, err
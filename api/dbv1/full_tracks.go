@@ -11,6 +11,7 @@ import (
 
 type FullTracksParams struct {
 	GetTracksParams
+	Fields Fields // nil loads everything
 }
 
 type FullTrack struct {
@@ -62,9 +63,11 @@ func (q *Queries) FullTracksKeyed(ctx context.Context, arg FullTracksParams) (ma
 		collectSplitUserIds(track.DownloadConditions)
 	}
 
-	userMap, err := q.FullUsersKeyed(ctx, GetUsersParams{
-		MyID: arg.MyID.(int32),
-		Ids:  userIds,
+	userMap, err := q.FullUsersKeyed(ctx, FullUsersParams{
+		GetUsersParams: GetUsersParams{
+			MyID: arg.MyID.(int32),
+			Ids:  userIds,
+		},
 	})
 	if err != nil {
 		return nil, err
@@ -83,10 +86,13 @@ func (q *Queries) FullTracksKeyed(ctx context.Context, arg FullTracksParams) (ma
 		userPtrMap[id] = &userCopy
 	}
 
-	// Get bulk access for all tracks
-	accessMap, err := q.GetBulkTrackAccess(ctx, arg.MyID.(int32), trackPtrs, userPtrMap)
-	if err != nil {
-		return nil, err
+	// Get bulk access for all tracks, unless nothing that needs it was asked for
+	accessMap := map[int32]Access{}
+	if arg.Fields.Includes("access") || arg.Fields.Includes("stream") || arg.Fields.Includes("download") {
+		accessMap, err = q.GetBulkTrackAccess(ctx, arg.MyID.(int32), trackPtrs, userPtrMap)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	trackMap := map[int32]FullTrack{}
@@ -180,6 +186,7 @@ func (q *Queries) FullTracksKeyed(ctx context.Context, arg FullTracksParams) (ma
 		trackMap[track.TrackID] = fullTrack
 	}
 
+	recordLoaded(ctx, "tracks", trackMap)
 	return trackMap, nil
 }
 
@@ -3,6 +3,7 @@ package dbv1
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 
 	"bridgerton.audius.co/rendezvous"
@@ -10,6 +11,22 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type FullUsersParams struct {
+	GetUsersParams
+	Fields Fields // nil loads everything
+}
+
+// fields that take a sub-query per user
+var (
+	currentUserFields = []string{
+		"current_user_followee_follow_count",
+		"does_current_user_follow",
+		"does_current_user_subscribe",
+		"does_follow_current_user",
+	}
+	artistCoinBadgeFields = []string{"artist_coin_badge"}
+)
+
 type FullUser struct {
 	GetUsersRow
 
@@ -18,8 +35,20 @@ type FullUser struct {
 	CoverPhoto        *RectangleImage `json:"cover_photo"`
 }
 
-func (q *Queries) FullUsersKeyed(ctx context.Context, arg GetUsersParams) (map[int32]FullUser, error) {
-	rawUsers, err := q.GetUsers(ctx, arg)
+// The GetUsers params, skipping the sub-queries of fields that weren't asked for
+func (arg FullUsersParams) getUsersParams() GetUsersParams {
+	params := arg.GetUsersParams
+	if !slices.ContainsFunc(currentUserFields, arg.Fields.Includes) {
+		params.SkipCurrentUserFields = true
+	}
+	if !slices.ContainsFunc(artistCoinBadgeFields, arg.Fields.Includes) {
+		params.SkipArtistCoinBadge = true
+	}
+	return params
+}
+
+func (q *Queries) FullUsersKeyed(ctx context.Context, arg FullUsersParams) (map[int32]FullUser, error) {
+	rawUsers, err := q.GetUsers(ctx, arg.getUsersParams())
 	if err != nil {
 		return nil, err
 	}
@@ -63,10 +92,11 @@ func (q *Queries) FullUsersKeyed(ctx context.Context, arg GetUsersParams) (map[i
 		}
 	}
 
+	recordLoaded(ctx, "users", userMap)
 	return userMap, nil
 }
 
-func (q *Queries) FullUsers(ctx context.Context, arg GetUsersParams) ([]FullUser, error) {
+func (q *Queries) FullUsers(ctx context.Context, arg FullUsersParams) ([]FullUser, error) {
 	userMap, err := q.FullUsersKeyed(ctx, arg)
 	if err != nil {
 		return nil, err
@@ -84,6 +114,12 @@ func (q *Queries) FullUsers(ctx context.Context, arg GetUsersParams) ([]FullUser
 	return fullUsers, nil
 }
 
+// SquareImageFromCid returns the image urls for a square image cid,
+// or nil when there is no usable cid.
+func SquareImageFromCid(cid string) *SquareImage {
+	return squareImageStruct(pgtype.Text{String: cid, Valid: cid != ""})
+}
+
 func squareImageStruct(maybeCids ...pgtype.Text) *SquareImage {
 	cid := ""
 	for _, m := range maybeCids {
@@ -108,7 +108,8 @@ SELECT
       WHERE mf.follower_user_id = $1
         AND mf.is_delete = false
     ) mf ON f.follower_user_id = mf.followee_user_id
-    WHERE $1 > 0
+    WHERE NOT $2::bool -- skipped when not requested
+    AND $1 > 0
     AND $1 != u.user_id -- don't compute when viewing own profile
     AND f.followee_user_id = u.user_id
     AND f.is_delete = false
@@ -117,7 +118,8 @@ SELECT
   (
     SELECT count(*) > 0
     FROM follows
-    WHERE $1 > 0
+    WHERE NOT $2::bool
+      AND $1 > 0
       AND follower_user_id = $1
       AND followee_user_id = u.user_id
       AND is_delete = false
@@ -126,7 +128,8 @@ SELECT
   (
     SELECT count(*) > 0
     FROM subscriptions
-    WHERE $1 > 0
+    WHERE NOT $2::bool
+      AND $1 > 0
       AND subscriber_id = $1
       AND user_id = u.user_id
       AND is_delete = false
@@ -135,7 +138,8 @@ SELECT
   (
     SELECT count(*) > 0
     FROM follows
-    WHERE $1 > 0
+    WHERE NOT $2::bool
+      AND $1 > 0
       AND followee_user_id = $1
       AND follower_user_id = u.user_id
       AND is_delete = false
@@ -160,7 +164,8 @@ SELECT
       'logo_uri', logo_uri
     )::jsonb
     FROM artist_coins
-    WHERE artist_coins.mint = COALESCE(
+    WHERE NOT $3::bool -- skipped when not requested
+    AND artist_coins.mint = COALESCE(
       -- Owned first
       (
         SELECT artist_coins.mint
@@ -187,13 +192,16 @@ JOIN aggregate_user using (user_id)
 LEFT JOIN user_balances using (user_id)
 LEFT JOIN user_bank_accounts on u.wallet = user_bank_accounts.ethereum_address
 LEFT JOIN usdc_user_bank_accounts on u.wallet = usdc_user_bank_accounts.ethereum_address
-WHERE u.user_id = ANY($2::int[])
+WHERE u.user_id = ANY($4::int[])
 ORDER BY u.user_id
+
 `
 
 type GetUsersParams struct {
-	MyID int32   `json:"my_id"`
-	Ids  []int32 `json:"ids"`
+	MyID                  int32   `json:"my_id"`
+	SkipCurrentUserFields bool    `json:"skip_current_user_fields"`
+	SkipArtistCoinBadge   bool    `json:"skip_artist_coin_badge"`
+	Ids                   []int32 `json:"ids"`
 }
 
 type GetUsersRow struct {
@@ -260,7 +268,12 @@ type GetUsersRow struct {
 }
 
 func (q *Queries) GetUsers(ctx context.Context, arg GetUsersParams) ([]GetUsersRow, error) {
-	rows, err := q.db.Query(ctx, getUsers, arg.MyID, arg.Ids)
+	rows, err := q.db.Query(ctx, getUsers,
+		arg.MyID,
+		arg.SkipCurrentUserFields,
+		arg.SkipArtistCoinBadge,
+		arg.Ids,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,47 @@
+package dbv1
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// LoadedEntitiesKey is the context key of a *LoadedEntities.
+type LoadedEntitiesKey struct{}
+
+// LoadedEntities collects the ids of the users, tracks and playlists
+// the Full* queries load while it is set on the context,
+// so a response can be tagged with the entities it was built from.
+type LoadedEntities struct {
+	mu   sync.Mutex
+	tags map[string]bool
+}
+
+func NewLoadedEntities() *LoadedEntities {
+	return &LoadedEntities{tags: map[string]bool{}}
+}
+
+// Tags are "<table>:<id>", e.g. "tracks:1"
+func (l *LoadedEntities) Tags() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tags := make([]string, 0, len(l.tags))
+	for tag := range l.tags {
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+func recordLoaded[T any](ctx context.Context, table string, entities map[int32]T) {
+	l, ok := ctx.Value(LoadedEntitiesKey{}).(*LoadedEntities)
+	if !ok {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for id := range entities {
+		l.tags[fmt.Sprintf("%s:%d", table, id)] = true
+	}
+}
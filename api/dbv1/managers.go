@@ -36,9 +36,11 @@ func (q *Queries) FullManagers(ctx context.Context, params GetGrantsForUserIdPar
 		user_ids[i] = int32(grant.GranteeUserID)
 	}
 
-	users, err := q.FullUsersKeyed(ctx, GetUsersParams{
-		Ids:  user_ids,
-		MyID: params.UserID,
+	users, err := q.FullUsersKeyed(ctx, FullUsersParams{
+		GetUsersParams: GetUsersParams{
+			Ids:  user_ids,
+			MyID: params.UserID,
+		},
 	})
 
 	if err != nil {
@@ -71,9 +73,11 @@ func (q *Queries) FullManagedUsers(ctx context.Context, params GetGrantsForGrant
 		user_ids[i] = int32(grant.UserID)
 	}
 
-	users, err := q.FullUsersKeyed(ctx, GetUsersParams{
-		Ids:  user_ids,
-		MyID: params.UserID,
+	users, err := q.FullUsersKeyed(ctx, FullUsersParams{
+		GetUsersParams: GetUsersParams{
+			Ids:  user_ids,
+			MyID: params.UserID,
+		},
 	})
 
 	if err != nil {
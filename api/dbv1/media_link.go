@@ -14,6 +14,9 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
+// the path of signed media links, whose signature is only good for a while
+const MediaLinkPath = "tracks/cidstream/"
+
 type MediaLink struct {
 	Url     string   `json:"url"`
 	Mirrors []string `json:"mirrors"`
@@ -57,7 +60,7 @@ func mediaLink(cid string, trackId int32, userId int32, id3Tags *Id3Tags) (*Medi
 		queryParams.Set("id3_title", id3Tags.Title)
 	}
 
-	basePath := fmt.Sprintf("tracks/cidstream/%s", cid)
+	basePath := MediaLinkPath + cid
 	path := fmt.Sprintf("%s?%s", basePath, queryParams.Encode())
 
 	return &MediaLink{
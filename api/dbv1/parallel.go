@@ -11,6 +11,7 @@ type ParallelParams struct {
 	TrackIds    []int32
 	PlaylistIds []int32
 	MyID        int32
+	TrackFields Fields // nil loads everything
 }
 
 type ParallelResult struct {
@@ -29,9 +30,11 @@ func (q *Queries) Parallel(ctx context.Context, arg ParallelParams) (*ParallelRe
 	if len(arg.UserIds) > 0 {
 		g.Go(func() error {
 			var err error
-			userMap, err = q.FullUsersKeyed(ctx, GetUsersParams{
-				Ids:  arg.UserIds,
-				MyID: arg.MyID,
+			userMap, err = q.FullUsersKeyed(ctx, FullUsersParams{
+				GetUsersParams: GetUsersParams{
+					Ids:  arg.UserIds,
+					MyID: arg.MyID,
+				},
 			})
 			return err
 		})
@@ -45,6 +48,7 @@ func (q *Queries) Parallel(ctx context.Context, arg ParallelParams) (*ParallelRe
 					Ids:  arg.TrackIds,
 					MyID: arg.MyID,
 				},
+				Fields: arg.TrackFields,
 			})
 			return err
 		})
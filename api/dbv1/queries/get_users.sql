@@ -92,7 +92,8 @@ SELECT
       WHERE mf.follower_user_id = @my_id
         AND mf.is_delete = false
     ) mf ON f.follower_user_id = mf.followee_user_id
-    WHERE @my_id > 0
+    WHERE NOT @skip_current_user_fields::bool -- skipped when not requested
+    AND @my_id > 0
     AND @my_id != u.user_id -- don't compute when viewing own profile
     AND f.followee_user_id = u.user_id
     AND f.is_delete = false
@@ -101,7 +102,8 @@ SELECT
   (
     SELECT count(*) > 0
     FROM follows
-    WHERE @my_id > 0
+    WHERE NOT @skip_current_user_fields::bool
+      AND @my_id > 0
       AND follower_user_id = @my_id
       AND followee_user_id = u.user_id
       AND is_delete = false
@@ -110,7 +112,8 @@ SELECT
   (
     SELECT count(*) > 0
     FROM subscriptions
-    WHERE @my_id > 0
+    WHERE NOT @skip_current_user_fields::bool
+      AND @my_id > 0
       AND subscriber_id = @my_id
       AND user_id = u.user_id
       AND is_delete = false
@@ -119,7 +122,8 @@ SELECT
   (
     SELECT count(*) > 0
     FROM follows
-    WHERE @my_id > 0
+    WHERE NOT @skip_current_user_fields::bool
+      AND @my_id > 0
       AND followee_user_id = @my_id
       AND follower_user_id = u.user_id
       AND is_delete = false
@@ -144,7 +148,8 @@ SELECT
       'logo_uri', logo_uri
     )::jsonb
     FROM artist_coins
-    WHERE artist_coins.mint = COALESCE(
+    WHERE NOT @skip_artist_coin_badge::bool -- skipped when not requested
+    AND artist_coins.mint = COALESCE(
       -- Owned first
       (
         SELECT artist_coins.mint
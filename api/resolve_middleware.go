@@ -1,8 +1,11 @@
 package api
 
 import (
+	"errors"
 	"strings"
 
+	"bridgerton.audius.co/api/comms"
+	"bridgerton.audius.co/api/dbv1"
 	"bridgerton.audius.co/trashid"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
@@ -20,6 +23,11 @@ func (app *ApiServer) getIsFull(c *fiber.Ctx) bool {
 	return c.Locals("isFull").(bool)
 }
 
+// The fields= sparse fieldset, so Full* queries can skip what isn't asked for
+func (app *ApiServer) getFields(c *fiber.Ctx) dbv1.Fields {
+	return dbv1.ParseFields(c.Query("fields"))
+}
+
 // will set myId if valid, defaults to 0
 func (app *ApiServer) resolveMyIdMiddleware(c *fiber.Ctx) error {
 	myId, _ := trashid.DecodeHashId(c.Query("user_id"))
@@ -102,5 +110,17 @@ func (app *ApiServer) validateWebsocketMiddleware(c *fiber.Ctx) error {
 		return err
 	}
 	c.Locals("websocketUserId", userId)
+
+	// resume from the last push the client saw
+	if since := c.Query("since"); since != "" {
+		cursor, err := app.commsRpcProcessor.ResumeCursor(c.Context(), since)
+		if errors.Is(err, comms.ErrInvalidResumeCursor) || errors.Is(err, comms.ErrResumeCursorExpired) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		if err != nil {
+			return err
+		}
+		c.Locals("websocketSince", &cursor)
+	}
 	return c.Next()
 }
@@ -0,0 +1,312 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bridgerton.audius.co/api/dbv1"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgxlisten"
+	"github.com/maypok86/otter"
+	"github.com/tidwall/gjson"
+	"go.uber.org/zap"
+)
+
+// ResponseCache caches the responses of hot endpoints for anonymous requests
+// (no user_id and no signature), which are the same for everyone.
+//
+// Responses are held in memory, and also in a shared Redis-compatible tier
+// when responseCacheRedisUrl is set (see response_cache_shared.go).
+// Each response is tagged with the users, tracks and playlists it was built from
+// (see dbv1.LoadedEntities), and is dropped when the database NOTIFYs a change
+// to one of them, on the same channels the esindexer listens on.
+
+const (
+	// most bytes of response bodies held in memory
+	responseCacheCapacity = 256 << 20
+	// longest a response is cached for
+	responseCacheMaxTTL = 10 * time.Minute
+	// how often changes are applied to the cached responses
+	responseCacheFlushInterval = time.Second
+)
+
+// query params that don't change the response
+var responseCacheIgnoredParams = []string{"api_key", "app_name"}
+
+// NOTIFY channel => the table it tags and the payload's id field
+var responseCacheChannels = map[string][2]string{
+	"users":           {"users", "user_id"},
+	"aggregate_user":  {"users", "user_id"},
+	"tracks":          {"tracks", "track_id"},
+	"aggregate_track": {"tracks", "track_id"},
+	"playlists":       {"playlists", "playlist_id"},
+	"playlist_track":  {"playlists", "playlist_id"},
+}
+
+type cachedResponse struct {
+	etag        string
+	contentType string
+	tags        []string
+	body        []byte
+}
+
+type ResponseCache struct {
+	logger *zap.Logger
+	local  otter.CacheWithVariableTTL[string, *cachedResponse]
+	shared *sharedResponseCache
+
+	// when each tag last changed,
+	// so a response loaded before a change isn't cached after it
+	changedAt otter.Cache[string, time.Time]
+
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+// redisUrl is optional
+func NewResponseCache(logger *zap.Logger, redisUrl string) (*ResponseCache, error) {
+	local, err := otter.MustBuilder[string, *cachedResponse](responseCacheCapacity).
+		Cost(func(key string, value *cachedResponse) uint32 {
+			return uint32(len(key) + len(value.body))
+		}).
+		WithVariableTTL().
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	changedAt, err := otter.MustBuilder[string, time.Time](100_000).
+		WithTTL(time.Minute).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	rc := &ResponseCache{
+		logger:    logger.With(zap.String("component", "ResponseCache")),
+		local:     local,
+		changedAt: changedAt,
+		pending:   map[string]bool{},
+	}
+
+	if redisUrl != "" {
+		if rc.shared, err = newSharedResponseCache(rc.logger, redisUrl); err != nil {
+			return nil, err
+		}
+	}
+
+	return rc, nil
+}
+
+// Caches the successful responses of an anonymous GET route for ttl.
+// Passes through when the app has no response cache.
+func (app *ApiServer) responseCacheMiddleware(ttl time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		rc := app.responseCache
+		if rc == nil || c.Method() != fiber.MethodGet || app.getMyId(c) != 0 || app.getAuthedWallet(c) != "" {
+			return c.Next()
+		}
+
+		key := responseCacheKey(c)
+		if cached, ok := rc.get(key); ok {
+			c.Set("X-Cache", "HIT")
+			return cached.send(c)
+		}
+
+		loaded := dbv1.NewLoadedEntities()
+		c.Context().SetUserValue(dbv1.LoadedEntitiesKey{}, loaded)
+		startedAt := time.Now()
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+		if c.Response().StatusCode() != fiber.StatusOK {
+			return nil
+		}
+
+		body := bytes.Clone(c.Response().Body())
+		// keep the handler's ETag (see entityVersions), so it's the same hit or miss
+		etag := string(c.Response().Header.Peek(fiber.HeaderETag))
+		if etag == "" {
+			etag = bodyETag(body)
+		}
+		cached := &cachedResponse{
+			etag:        etag,
+			contentType: string(c.Response().Header.ContentType()),
+			tags:        loaded.Tags(),
+			body:        body,
+		}
+		rc.set(key, cached, ttl, startedAt)
+
+		c.Set("X-Cache", "MISS")
+		return cached.send(c)
+	}
+}
+
+// the path and the query params, in order, without ones that don't matter
+func responseCacheKey(c *fiber.Ctx) string {
+	params := []string{}
+	c.Request().URI().QueryArgs().VisitAll(func(key, value []byte) {
+		if !slices.Contains(responseCacheIgnoredParams, string(key)) {
+			params = append(params, string(key)+"="+string(value))
+		}
+	})
+	slices.Sort(params)
+	return c.Path() + "?" + strings.Join(params, "&")
+}
+
+func bodyETag(body []byte) string {
+	h := fnv.New64a()
+	h.Write(body)
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+// If-None-Match uses the weak comparison, so W/ prefixes are ignored
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Clients may keep the response, but must revalidate it with the ETag,
+// since a change can drop it from the cache at any time
+func (cached *cachedResponse) send(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderETag, cached.etag)
+	c.Set(fiber.HeaderCacheControl, "public, no-cache")
+
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), cached.etag) {
+		c.Response().ResetBody()
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	c.Set(fiber.HeaderContentType, cached.contentType)
+	return c.Status(fiber.StatusOK).Send(cached.body)
+}
+
+func (rc *ResponseCache) get(key string) (*cachedResponse, bool) {
+	if cached, ok := rc.local.Get(key); ok {
+		return cached, true
+	}
+	if rc.shared == nil {
+		return nil, false
+	}
+
+	cached, ttl, ok := rc.shared.get(key)
+	if !ok {
+		return nil, false
+	}
+	rc.local.Set(key, cached, ttl)
+	return cached, true
+}
+
+// Skips responses that may have loaded an entity before it changed
+func (rc *ResponseCache) set(key string, cached *cachedResponse, ttl time.Duration, startedAt time.Time) {
+	for _, tag := range cached.tags {
+		if changedAt, ok := rc.changedAt.Get(tag); ok && !changedAt.Before(startedAt) {
+			return
+		}
+	}
+
+	ttl = min(ttl, responseCacheMaxTTL)
+	rc.local.Set(key, cached, ttl)
+	if rc.shared != nil {
+		rc.shared.set(key, cached, ttl, startedAt)
+	}
+}
+
+// Marks tags as changed. Responses with them are dropped on the next flush.
+func (rc *ResponseCache) Invalidate(tags ...string) {
+	now := time.Now()
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for _, tag := range tags {
+		rc.changedAt.Set(tag, now)
+		rc.pending[tag] = true
+	}
+}
+
+// Drops the responses tagged with anything that changed since the last flush
+func (rc *ResponseCache) flush() {
+	rc.mu.Lock()
+	changed := rc.pending
+	rc.pending = map[string]bool{}
+	rc.mu.Unlock()
+
+	if len(changed) == 0 {
+		return
+	}
+
+	rc.local.DeleteByFunc(func(key string, cached *cachedResponse) bool {
+		return slices.ContainsFunc(cached.tags, func(tag string) bool {
+			return changed[tag]
+		})
+	})
+
+	if rc.shared != nil {
+		changedAt := make(map[string]time.Time, len(changed))
+		for tag := range changed {
+			at, ok := rc.changedAt.Get(tag)
+			if !ok {
+				at = time.Now()
+			}
+			changedAt[tag] = at
+		}
+		rc.shared.invalidate(changedAt)
+	}
+}
+
+// Follows entity changes until ctx is done.
+// LISTEN needs the write leader, like the esindexer.
+func (rc *ResponseCache) Listen(ctx context.Context, writeDbUrl string) error {
+	listener := &pgxlisten.Listener{
+		Connect: func(ctx context.Context) (*pgx.Conn, error) {
+			return pgx.Connect(ctx, writeDbUrl)
+		},
+		LogError: func(ctx context.Context, err error) {
+			rc.logger.Warn("listener error", zap.Error(err))
+		},
+		ReconnectDelay: 10 * time.Second,
+	}
+
+	for channel, target := range responseCacheChannels {
+		table, idField := target[0], target[1]
+		listener.Handle(channel, pgxlisten.HandlerFunc(func(ctx context.Context, notification *pgconn.Notification, conn *pgx.Conn) error {
+			id := gjson.Get(notification.Payload, idField).Int()
+			rc.Invalidate(table + ":" + strconv.FormatInt(id, 10))
+			return nil
+		}))
+	}
+
+	go func() {
+		ticker := time.NewTicker(responseCacheFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				rc.flush()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return listener.Listen(ctx)
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// The shared tier of ResponseCache lives in a Redis-compatible server
+// (Redis, Valkey, KeyDB, Dragonfly...), so replicas reuse each other's responses.
+//
+// Responses are stored with when they started loading.
+// A change to a tag is stored as a marker with when it changed,
+// and a response is only used if none of its tags changed after it started loading.
+// Every key expires within responseCacheMaxTTL, so nothing in the tier grows without bound.
+
+const (
+	// prefix of the shared tier's keys
+	responseCachePrefix = "respcache:"
+	// longest a shared get or write may take
+	responseCacheSharedTimeout = 100 * time.Millisecond
+	// writes wait in a queue of this size for the writers,
+	// and are dropped when it is full
+	responseCacheSharedQueueSize = 1024
+	responseCacheSharedWriters   = 4
+	// larger responses are only cached in memory
+	responseCacheSharedMaxBody = 1 << 20
+)
+
+type sharedResponseCache struct {
+	logger *zap.Logger
+	client *redis.Client
+	writes chan sharedWrite
+}
+
+type sharedWrite struct {
+	key   string
+	value []byte
+	ttl   time.Duration
+}
+
+// redisUrl is redis://[:password@]host:port[/db]
+func newSharedResponseCache(logger *zap.Logger, redisUrl string) (*sharedResponseCache, error) {
+	opts, err := redis.ParseURL(redisUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := &sharedResponseCache{
+		logger: logger,
+		client: redis.NewClient(opts),
+		writes: make(chan sharedWrite, responseCacheSharedQueueSize),
+	}
+	for range responseCacheSharedWriters {
+		go shared.writeLoop()
+	}
+	return shared, nil
+}
+
+// Returns the response and how long it has left to live
+func (s *sharedResponseCache) get(key string) (*cachedResponse, time.Duration, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), responseCacheSharedTimeout)
+	defer cancel()
+
+	pipe := s.client.Pipeline()
+	value := pipe.Get(ctx, responseCachePrefix+key)
+	ttl := pipe.PTTL(ctx, responseCachePrefix+key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		if !errors.Is(err, redis.Nil) {
+			s.logger.Warn("shared get failed", zap.Error(err))
+		}
+		return nil, 0, false
+	}
+
+	valueBytes, err := value.Bytes()
+	if err != nil || ttl.Val() <= 0 {
+		return nil, 0, false
+	}
+	cached, startedAt, err := decodeCachedResponse(valueBytes)
+	if err != nil {
+		return nil, 0, false
+	}
+
+	if len(cached.tags) > 0 {
+		markerKeys := make([]string, len(cached.tags))
+		for i, tag := range cached.tags {
+			markerKeys[i] = responseCacheMarkerKey(tag)
+		}
+		markers, err := s.client.MGet(ctx, markerKeys...).Result()
+		if err != nil {
+			s.logger.Warn("shared get failed", zap.Error(err))
+			return nil, 0, false
+		}
+		for _, marker := range markers {
+			changedAt, ok := marker.(string)
+			if !ok {
+				continue
+			}
+			if nanos, err := strconv.ParseInt(changedAt, 10, 64); err != nil || nanos >= startedAt.UnixNano() {
+				return nil, 0, false
+			}
+		}
+	}
+
+	return cached, ttl.Val(), true
+}
+
+// Queues the response to be written, unless the queue is full
+// (the server is slow or down), or it's too large to share
+func (s *sharedResponseCache) set(key string, cached *cachedResponse, ttl time.Duration, startedAt time.Time) {
+	if len(cached.body) > responseCacheSharedMaxBody {
+		return
+	}
+
+	select {
+	case s.writes <- sharedWrite{key: key, value: cached.encode(startedAt), ttl: ttl}:
+	default:
+	}
+}
+
+func (s *sharedResponseCache) writeLoop() {
+	for write := range s.writes {
+		ctx, cancel := context.WithTimeout(context.Background(), responseCacheSharedTimeout)
+		err := s.client.Set(ctx, responseCachePrefix+write.key, write.value, write.ttl).Err()
+		cancel()
+		if err != nil {
+			s.logger.Warn("shared set failed", zap.Error(err))
+		}
+	}
+}
+
+// Records when tags changed, so every replica skips responses loaded before
+func (s *sharedResponseCache) invalidate(changedAt map[string]time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), responseCacheSharedTimeout)
+	defer cancel()
+
+	pipe := s.client.Pipeline()
+	for tag, at := range changedAt {
+		pipe.Set(ctx, responseCacheMarkerKey(tag), at.UnixNano(), responseCacheMaxTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.logger.Warn("shared invalidate failed", zap.Error(err))
+	}
+}
+
+func responseCacheMarkerKey(tag string) string {
+	return responseCachePrefix + "changed:" + tag
+}
+
+// when it started loading, etag, content type and tags on a line each, then the body
+func (cached *cachedResponse) encode(startedAt time.Time) []byte {
+	header := strconv.FormatInt(startedAt.UnixNano(), 10) + "\n" +
+		cached.etag + "\n" +
+		cached.contentType + "\n" +
+		strings.Join(cached.tags, ",") + "\n"
+	return append([]byte(header), cached.body...)
+}
+
+func decodeCachedResponse(value []byte) (*cachedResponse, time.Time, error) {
+	parts := bytes.SplitN(value, []byte("\n"), 5)
+	if len(parts) != 5 {
+		return nil, time.Time{}, errors.New("invalid cached response")
+	}
+	startedAt, err := strconv.ParseInt(string(parts[0]), 10, 64)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	cached := &cachedResponse{
+		etag:        string(parts[1]),
+		contentType: string(parts[2]),
+		body:        parts[4],
+	}
+	if len(parts[3]) > 0 {
+		cached.tags = strings.Split(string(parts[3]), ",")
+	}
+	return cached, time.Unix(0, startedAt), nil
+}
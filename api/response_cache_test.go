@@ -0,0 +1,135 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bridgerton.audius.co/database"
+	"bridgerton.audius.co/trashid"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestResponseCache(t *testing.T) {
+	app := emptyTestApp(t)
+	database.Seed(app.pool.Replicas[0], database.FixtureMap{
+		"users": {
+			{"user_id": 1, "handle": "artist", "handle_lc": "artist"},
+		},
+		"tracks": {
+			{"track_id": 1, "owner_id": 1, "title": "cached"},
+		},
+	})
+
+	rc, err := NewResponseCache(zap.NewNop(), "")
+	require.NoError(t, err)
+	app.responseCache = rc
+
+	get := func(path string, headers map[string]string) (int, string, string) {
+		req := httptest.NewRequest("GET", path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		res, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return res.StatusCode, res.Header.Get("X-Cache"), res.Header.Get("ETag")
+	}
+
+	path := "/v1/tracks/" + trashid.MustEncodeHashID(1)
+
+	status, cache, etag := get(path, nil)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "MISS", cache)
+	assert.NotEmpty(t, etag)
+
+	// query params are normalized, and analytics params ignored
+	status, cache, _ = get(path+"?app_name=test", nil)
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "HIT", cache)
+
+	status, _, _ = get(path, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, 304, status)
+
+	// requests made as a user aren't cached
+	_, cache, _ = get(path+"?user_id="+trashid.MustEncodeHashID(1), nil)
+	assert.Equal(t, "", cache)
+
+	// the response is tagged with the track and its owner
+	cached, ok := rc.local.Get(path + "?")
+	require.True(t, ok)
+	assert.ElementsMatch(t, []string{"tracks:1", "users:1"}, cached.tags)
+
+	// a change to the owner drops it
+	rc.Invalidate("users:1")
+	rc.flush()
+	_, cache, _ = get(path, nil)
+	assert.Equal(t, "MISS", cache)
+	_, cache, _ = get(path, nil)
+	assert.Equal(t, "HIT", cache)
+
+	// unrelated changes don't
+	rc.Invalidate("tracks:2")
+	rc.flush()
+	_, cache, _ = get(path, nil)
+	assert.Equal(t, "HIT", cache)
+}
+
+func TestSharedResponseCache(t *testing.T) {
+	server := miniredis.RunT(t)
+	newCache := func() *ResponseCache {
+		rc, err := NewResponseCache(zap.NewNop(), "redis://"+server.Addr())
+		require.NoError(t, err)
+		return rc
+	}
+
+	rc1 := newCache()
+	startedAt := time.Now()
+	rc1.set("/v1/tracks/1?", &cachedResponse{
+		etag:        `"abc"`,
+		contentType: "application/json",
+		tags:        []string{"tracks:1", "users:1"},
+		body:        []byte(`{"data":{}}`),
+	}, time.Minute, startedAt)
+	assert.Eventually(t, func() bool {
+		return server.Exists(responseCachePrefix + "/v1/tracks/1?")
+	}, time.Second, 10*time.Millisecond)
+
+	// another replica gets it from the shared tier
+	rc2 := newCache()
+	cached, ok := rc2.get("/v1/tracks/1?")
+	require.True(t, ok)
+	assert.Equal(t, `"abc"`, cached.etag)
+	assert.Equal(t, "application/json", cached.contentType)
+	assert.ElementsMatch(t, []string{"tracks:1", "users:1"}, cached.tags)
+	assert.Equal(t, `{"data":{}}`, string(cached.body))
+	_, ok = rc2.local.Get("/v1/tracks/1?")
+	assert.True(t, ok)
+
+	// a change to one of its tags drops it for every replica
+	rc1.Invalidate("users:1")
+	rc1.flush()
+	_, ok = newCache().get("/v1/tracks/1?")
+	assert.False(t, ok)
+
+	// large responses stay in memory
+	rc1.set("/v1/tracks/2?", &cachedResponse{
+		body: []byte(strings.Repeat("a", responseCacheSharedMaxBody+1)),
+	}, time.Minute, time.Now())
+	_, ok = rc1.local.Get("/v1/tracks/2?")
+	assert.True(t, ok)
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, server.Exists(responseCachePrefix+"/v1/tracks/2?"))
+}
+
+func TestETagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`"abc"`, `"abc"`))
+	assert.True(t, etagMatches(`W/"abc"`, `"abc"`))
+	assert.True(t, etagMatches(`"xyz", "abc"`, `"abc"`))
+	assert.True(t, etagMatches(`*`, `"abc"`))
+	assert.False(t, etagMatches(``, `"abc"`))
+	assert.False(t, etagMatches(`"xyz"`, `"abc"`))
+}
@@ -1,85 +1,205 @@
 package api
 
 import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"regexp"
+	"time"
+
 	"bridgerton.audius.co/api/dbv1"
 	"github.com/gofiber/fiber/v2"
+	"github.com/segmentio/encoding/json"
 )
 
-func v1UserResponse(c *fiber.Ctx, user dbv1.FullUser) error {
-	if c.Locals("isFull").(bool) {
+// entityVersions builds the validators of an entity response:
+// a weak ETag from the viewer and the serialized body,
+// and a Last-Modified from the latest updated_at.
+// conditionalRequestMiddleware answers 304 when they match the request.
+//
+// Signed media links (stream, download, preview) are signed anew on every request,
+// so their signatures are left out of the ETag.
+type entityVersions struct {
+	myId         int
+	lastModified time.Time
+}
+
+func newEntityVersions(c *fiber.Ctx) *entityVersions {
+	v := &entityVersions{}
+	v.myId, _ = c.Locals("myId").(int)
+	return v
+}
+
+func (v *entityVersions) modified(updatedAt time.Time) {
+	if updatedAt.After(v.lastModified) {
+		v.lastModified = updatedAt
+	}
+}
+
+func (v *entityVersions) user(user dbv1.FullUser) {
+	v.modified(user.UpdatedAt)
+}
+
+func (v *entityVersions) track(track dbv1.FullTrack) {
+	v.modified(track.UpdatedAt)
+	v.user(track.User)
+}
+
+func (v *entityVersions) playlist(playlist dbv1.FullPlaylist) {
+	v.modified(playlist.UpdatedAt)
+	v.user(playlist.User)
+	for _, track := range playlist.Tracks {
+		v.track(track)
+	}
+}
+
+// the signature param of a signed media link, up to the next param or the end of the url
+// (& is escaped as \u0026 in JSON)
+var mediaLinkSignature = regexp.MustCompile(`signature=[^&"\\]*`)
+
+// Sends the data response with its validators
+func (v *entityVersions) send(c *fiber.Ctx, data any) error {
+	if err := v1DataResponse(c, data); err != nil {
+		return err
+	}
+
+	body := c.Response().Body()
+	if bytes.Contains(body, []byte(dbv1.MediaLinkPath)) {
+		body = mediaLinkSignature.ReplaceAll(body, []byte("signature="))
+	}
+	h := fnv.New64a()
+	fmt.Fprintln(h, "viewer", v.myId)
+	h.Write(body)
+	c.Set(fiber.HeaderETag, `W/"`+hex.EncodeToString(h.Sum(nil))+`"`)
+
+	if !v.lastModified.IsZero() {
+		c.Set(fiber.HeaderLastModified, v.lastModified.UTC().Format(http.TimeFormat))
+	}
+	// what the current user sees is theirs alone
+	if v.myId != 0 {
+		c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	}
+	return nil
+}
+
+// Sends {"data": data}, pruned to the fields= sparse fieldset if there is one.
+// Paths apply to each item of an array, e.g. fields=id,user.handle
+// keeps the id and user handle of every track in a list.
+func v1DataResponse(c *fiber.Ctx, data any) error {
+	fields := dbv1.ParseFields(c.Query("fields"))
+	if fields == nil {
 		return c.JSON(fiber.Map{
-			"data": user,
+			"data": data,
 		})
 	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var decoded any
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return err
+	}
+
 	return c.JSON(fiber.Map{
-		"data": dbv1.ToMinUser(user),
+		"data": pruneFields(decoded, fields),
 	})
 }
 
+func pruneFields(value any, fields dbv1.Fields) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, child := range value {
+			if !fields.Includes(key) {
+				delete(value, key)
+			} else if sub := fields.Sub(key); sub != nil {
+				value[key] = pruneFields(child, sub)
+			}
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = pruneFields(item, fields)
+		}
+	}
+	return value
+}
+
+func v1UserResponse(c *fiber.Ctx, user dbv1.FullUser) error {
+	versions := newEntityVersions(c)
+	versions.user(user)
+
+	if c.Locals("isFull").(bool) {
+		return versions.send(c, user)
+	}
+	return versions.send(c, dbv1.ToMinUser(user))
+}
+
 func v1UsersResponse(c *fiber.Ctx, users []dbv1.FullUser) error {
+	versions := newEntityVersions(c)
+	for _, user := range users {
+		versions.user(user)
+	}
+
 	if c.Locals("isFull").(bool) {
-		return c.JSON(fiber.Map{
-			"data": users,
-		})
+		return versions.send(c, users)
 	}
-	return c.JSON(fiber.Map{
-		"data": dbv1.ToMinUsers(users),
-	})
+	return versions.send(c, dbv1.ToMinUsers(users))
 }
 
 // Note: playlist response returned an array even though it's a single playlist
 // Done for backwards compatibility. Would be nice to get rid of this.
 func v1PlaylistResponse(c *fiber.Ctx, playlist dbv1.FullPlaylist) error {
+	versions := newEntityVersions(c)
+	versions.playlist(playlist)
+
 	if c.Locals("isFull").(bool) {
-		return c.JSON(fiber.Map{
-			"data": []dbv1.FullPlaylist{playlist},
-		})
+		return versions.send(c, []dbv1.FullPlaylist{playlist})
 	}
-	return c.JSON(fiber.Map{
-		"data": []dbv1.MinPlaylist{dbv1.ToMinPlaylist(playlist)},
-	})
+	return versions.send(c, []dbv1.MinPlaylist{dbv1.ToMinPlaylist(playlist)})
 }
 
 func v1PlaylistsResponse(c *fiber.Ctx, playlists []dbv1.FullPlaylist) error {
+	versions := newEntityVersions(c)
+	for _, playlist := range playlists {
+		versions.playlist(playlist)
+	}
+
 	if c.Locals("isFull").(bool) {
-		return c.JSON(fiber.Map{
-			"data": playlists,
-		})
+		return versions.send(c, playlists)
 	}
-	return c.JSON(fiber.Map{
-		"data": dbv1.ToMinPlaylists(playlists),
-	})
+	return versions.send(c, dbv1.ToMinPlaylists(playlists))
 }
 
 func v1TrackResponse(c *fiber.Ctx, track dbv1.FullTrack) error {
+	versions := newEntityVersions(c)
+	versions.track(track)
+
 	if c.Locals("isFull").(bool) {
-		return c.JSON(fiber.Map{
-			"data": track,
-		})
+		return versions.send(c, track)
 	}
-	return c.JSON(fiber.Map{
-		"data": dbv1.ToMinTrack(track),
-	})
+	return versions.send(c, dbv1.ToMinTrack(track))
 }
 
 func v1TracksResponse(c *fiber.Ctx, tracks []dbv1.FullTrack) error {
+	versions := newEntityVersions(c)
+	for _, track := range tracks {
+		versions.track(track)
+	}
+
 	if c.Locals("isFull").(bool) {
-		return c.JSON(fiber.Map{
-			"data": tracks,
-		})
+		return versions.send(c, tracks)
 	}
-	return c.JSON(fiber.Map{
-		"data": dbv1.ToMinTracks(tracks),
-	})
+	return versions.send(c, dbv1.ToMinTracks(tracks))
 }
 
 func v1TipsResponse(c *fiber.Ctx, tips []dbv1.FullTip) error {
 	if c.Locals("isFull").(bool) {
-		return c.JSON(fiber.Map{
-			"data": tips,
-		})
+		return v1DataResponse(c, tips)
 	}
-	return c.JSON(fiber.Map{
-		"data": dbv1.ToMinTips(tips),
-	})
+	return v1DataResponse(c, dbv1.ToMinTips(tips))
 }
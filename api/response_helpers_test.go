@@ -0,0 +1,94 @@
+package api
+
+import (
+	"testing"
+
+	"bridgerton.audius.co/database"
+	"bridgerton.audius.co/trashid"
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestSparseFields(t *testing.T) {
+	app := testAppWithFixtures(t)
+
+	keys := func(body []byte, path string) []string {
+		keys := []string{}
+		gjson.GetBytes(body, path).ForEach(func(key, value gjson.Result) bool {
+			keys = append(keys, key.String())
+			return true
+		})
+		return keys
+	}
+
+	status, body := testGet(t, app, "/v1/full/tracks/eYJyn?fields=id,title,user.handle")
+	assert.Equal(t, 200, status)
+	assert.ElementsMatch(t, []string{"id", "title", "user"}, keys(body, "data"))
+	assert.Equal(t, []string{"handle"}, keys(body, "data.user"))
+	jsonAssert(t, body, map[string]any{
+		"data.id":    "eYJyn",
+		"data.title": "Culca Canyon",
+	})
+
+	// a path applies to every item of a list, and to the min responses too
+	status, body = testGet(t, app, "/v1/tracks?id=eYJyn&fields=id,user")
+	assert.Equal(t, 200, status)
+	assert.ElementsMatch(t, []string{"id", "user"}, keys(body, "data.0"))
+	assert.Contains(t, keys(body, "data.0.user"), "handle")
+
+	// tracks aren't loaded when they're not asked for
+	status, body = testGet(t, app, "/v1/full/playlists/7eP5n?fields=id,tracks.title")
+	assert.Equal(t, 200, status)
+	assert.ElementsMatch(t, []string{"id", "tracks"}, keys(body, "data.0"))
+
+	status, body = testGet(t, app, "/v1/full/playlists/7eP5n?fields=id,playlist_name")
+	assert.Equal(t, 200, status)
+	assert.ElementsMatch(t, []string{"id", "playlist_name"}, keys(body, "data.0"))
+
+	// but counts that come from the tracks still count them
+	status, full := testGet(t, app, "/v1/playlists/7eP5n")
+	assert.Equal(t, 200, status)
+	status, body = testGet(t, app, "/v1/playlists/7eP5n?fields=id,track_count")
+	assert.Equal(t, 200, status)
+	assert.ElementsMatch(t, []string{"id", "track_count"}, keys(body, "data.0"))
+	assert.NotZero(t, gjson.GetBytes(full, "data.0.track_count").Int())
+	assert.Equal(t, gjson.GetBytes(full, "data.0.track_count").Int(), gjson.GetBytes(body, "data.0.track_count").Int())
+
+	status, body = testGet(t, app, "/v1/playlists/7eP5n?fields=id,total_play_count")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, gjson.GetBytes(full, "data.0.total_play_count").Int(), gjson.GetBytes(body, "data.0.total_play_count").Int())
+
+	// without fields, everything is there
+	status, body = testGet(t, app, "/v1/full/tracks/eYJyn")
+	assert.Equal(t, 200, status)
+	assert.Contains(t, keys(body, "data"), "access")
+	assert.Contains(t, keys(body, "data.user"), "name")
+}
+
+func TestSparseUserFields(t *testing.T) {
+	app := emptyTestApp(t)
+	database.Seed(app.pool.Replicas[0], database.FixtureMap{
+		"users": {
+			{"user_id": 1, "handle": "artist", "handle_lc": "artist"},
+			{"user_id": 2, "handle": "fan", "handle_lc": "fan"},
+		},
+		"follows": {
+			{"follower_user_id": 2, "followee_user_id": 1},
+		},
+	})
+
+	path := "/v1/full/users/" + trashid.MustEncodeHashID(1) + "?user_id=" + trashid.MustEncodeHashID(2)
+
+	status, body := testGet(t, app, path+"&fields=id,handle")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, map[string]any{"id": trashid.MustEncodeHashID(1), "handle": "artist"}, gjson.GetBytes(body, "data.0").Value())
+
+	// the follow state is still loaded when it's asked for
+	status, body = testGet(t, app, path+"&fields=id,does_current_user_follow")
+	assert.Equal(t, 200, status)
+	assert.True(t, gjson.GetBytes(body, "data.0.does_current_user_follow").Bool())
+
+	status, body = testGet(t, app, path)
+	assert.Equal(t, 200, status)
+	assert.True(t, gjson.GetBytes(body, "data.0.does_current_user_follow").Bool())
+}
@@ -0,0 +1,88 @@
+package searchv1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/tidwall/gjson"
+)
+
+// AutocompleteQuery is a typo tolerant prefix lookup against the
+// `autocomplete` completion field, ranked by the popularity weight
+// each document was indexed with.
+type AutocompleteQuery struct {
+	Query string
+	Limit int
+
+	// completion contexts to filter on, e.g. {"kind": ["album"]}
+	Contexts map[string][]string
+
+	// document fields to return with each hit
+	Source []string
+}
+
+func (q *AutocompleteQuery) Map() map[string]any {
+	completion := map[string]any{
+		"field": "autocomplete",
+		"size":  q.Limit,
+		"fuzzy": map[string]any{
+			"fuzziness": "AUTO",
+		},
+	}
+	if len(q.Contexts) > 0 {
+		completion["contexts"] = q.Contexts
+	}
+
+	return map[string]any{
+		"_source": q.Source,
+		"suggest": map[string]any{
+			"autocomplete": map[string]any{
+				"prefix":     q.Query,
+				"completion": completion,
+			},
+		},
+	}
+}
+
+func (q *AutocompleteQuery) DSL() string {
+	dsl, err := json.Marshal(q.Map())
+	if err != nil {
+		panic(err)
+	}
+	return string(dsl)
+}
+
+// Autocomplete returns the matching suggestions,
+// each with its `_id` and the requested `_source` fields.
+func Autocomplete(esClient *elasticsearch.Client, index string, q AutocompleteQuery) ([]gjson.Result, error) {
+	if strings.TrimSpace(q.Query) == "" {
+		return []gjson.Result{}, nil
+	}
+
+	req := esapi.SearchRequest{
+		Index: []string{index},
+		Body:  strings.NewReader(q.DSL()),
+	}
+
+	res, err := req.Do(context.Background(), esClient)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("Autocomplete %s failed: %s", index, res.String())
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return gjson.GetBytes(body, "suggest.autocomplete.0.options").Array(), nil
+}
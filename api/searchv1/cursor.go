@@ -0,0 +1,65 @@
+package searchv1
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page selects a page of search results:
+// the Limit hits after Cursor when it is set, otherwise Limit hits from Offset.
+type Page struct {
+	Limit  int
+	Offset int
+	Cursor string
+
+	// which result set a cursor belongs to (e.g. "tracks" or "albums").
+	// A cursor issued for another scope is ignored,
+	// so paging one section of full search leaves the others on their first page.
+	Scope string
+}
+
+// cursors are opaque to clients, but are the search_after sort values
+// of the last hit, along with the scope they were issued for
+type cursor struct {
+	Scope       string `json:"s"`
+	SearchAfter []any  `json:"a"`
+}
+
+func encodeCursor(scope string, searchAfter []any) string {
+	j, err := json.Marshal(cursor{scope, searchAfter})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(j)
+}
+
+func decodeCursor(encoded string) (cursor, error) {
+	var c cursor
+	j, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err := decodeNumbers(j, &c); err != nil || len(c.SearchAfter) == 0 {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func parseSortValues(raw string) ([]any, error) {
+	var values []any
+	err := decodeNumbers([]byte(raw), &values)
+	return values, err
+}
+
+// sort values include long ids and timestamps,
+// which would lose precision as float64s
+func decodeNumbers(j []byte, dest any) error {
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	decoder.UseNumber()
+	return decoder.Decode(dest)
+}
@@ -0,0 +1,73 @@
+package searchv1
+
+import (
+	"testing"
+
+	"github.com/test-go/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	searchAfter, err := parseSortValues(`[12.5, 9007199254740993, "abc"]`)
+	require.NoError(t, err)
+
+	encoded := encodeCursor("tracks", searchAfter)
+	decoded, err := decodeCursor(encoded)
+	require.NoError(t, err)
+	require.Equal(t, "tracks", decoded.Scope)
+	require.Len(t, decoded.SearchAfter, 3)
+
+	// large ids keep their precision
+	require.Equal(t, "9007199254740993", decoded.SearchAfter[1].(interface{ String() string }).String())
+
+	_, err = decodeCursor("not a cursor")
+	require.Equal(t, ErrInvalidCursor, err)
+
+	_, err = decodeCursor(encodeCursor("tracks", nil))
+	require.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestWithPaging(t *testing.T) {
+	dsl := `{"query": {"match_all": {}}}`
+
+	{
+		paged, err := withPaging(dsl, Page{Limit: 10, Offset: 20, Scope: "users"})
+		require.NoError(t, err)
+		require.Equal(t, int64(20), gjson.Get(paged, "from").Int())
+		require.Equal(t, "desc", gjson.Get(paged, "sort.0._score").String())
+		require.Equal(t, "desc", gjson.Get(paged, "sort.1.id.order").String())
+		require.False(t, gjson.Get(paged, "search_after").Exists())
+	}
+
+	// the dsl's own sort comes before the id tiebreaker
+	{
+		sorted := `{"query": {"match_all": {}}, "sort": [{"created_at": {"order": "desc"}}]}`
+		paged, err := withPaging(sorted, Page{Limit: 10, Scope: "users"})
+		require.NoError(t, err)
+		require.Equal(t, 2, len(gjson.Get(paged, "sort").Array()))
+		require.Equal(t, "desc", gjson.Get(paged, "sort.0.created_at.order").String())
+		require.True(t, gjson.Get(paged, "sort.1.id").Exists())
+	}
+
+	cursor := encodeCursor("users", []any{1.5, 101})
+
+	// a cursor replaces the offset
+	{
+		paged, err := withPaging(dsl, Page{Limit: 10, Offset: 20, Cursor: cursor, Scope: "users"})
+		require.NoError(t, err)
+		require.Equal(t, int64(0), gjson.Get(paged, "from").Int())
+		require.Equal(t, `[1.5,101]`, gjson.Get(paged, "search_after").Raw)
+	}
+
+	// but only for the scope it was issued for
+	{
+		paged, err := withPaging(dsl, Page{Limit: 10, Cursor: cursor, Scope: "tracks"})
+		require.NoError(t, err)
+		require.False(t, gjson.Get(paged, "search_after").Exists())
+	}
+
+	{
+		_, err := withPaging(dsl, Page{Limit: 10, Cursor: "bogus!", Scope: "users"})
+		require.Equal(t, ErrInvalidCursor, err)
+	}
+}
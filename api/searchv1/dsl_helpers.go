@@ -5,12 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 
+	"github.com/aquasecurity/esquery"
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/tidwall/gjson"
-	"github.com/tidwall/pretty"
 )
 
 func toAnySlice[T any](slice []T) []any {
@@ -21,6 +22,51 @@ func toAnySlice[T any](slice []T) []any {
 	return result
 }
 
+// fields indexed with the multilingual sub-fields
+// (see multilingualText in esindexer/index_settings.go)
+var multilingualFields = []string{"name", "handle", "title", "user.name", "user.handle"}
+
+// withMultilingual adds the folded, cjk and translit sub-fields
+// of each multilingual field to a field list, with the same boost.
+func withMultilingual(fields ...string) []string {
+	result := []string{}
+	for _, field := range fields {
+		result = append(result, field)
+
+		name, boost, hasBoost := strings.Cut(field, "^")
+		if !slices.Contains(multilingualFields, name) {
+			continue
+		}
+		for _, sub := range []string{"folded", "cjk", "translit"} {
+			subField := name + "." + sub
+			if hasBoost {
+				subField += "^" + boost
+			}
+			result = append(result, subField)
+		}
+	}
+	return result
+}
+
+// libraryFilter matches documents whose id is in any of the given
+// lists (e.g. saved_track_ids) of myId's socials document,
+// which limits a search to the user's own library.
+func libraryFilter(myId int32, paths ...string) esquery.Mappable {
+	lookups := []esquery.Mappable{}
+	for _, path := range paths {
+		lookups = append(lookups, esquery.CustomQuery(map[string]any{
+			"terms": map[string]any{
+				"_id": map[string]any{
+					"index": "socials",
+					"id":    fmt.Sprintf("%d", myId),
+					"path":  path,
+				},
+			},
+		}))
+	}
+	return esquery.Bool().Should(lookups...).MinimumShouldMatch(1)
+}
+
 func sortWithField(innerQuery map[string]any, sortField, direction string) string {
 	innerJson, err := json.Marshal(innerQuery)
 	if err != nil {
@@ -67,19 +113,60 @@ func BuildFunctionScoreDSL(scoreField string, weight float64, innerQuery map[str
 	return dsl
 }
 
-func SearchAndPluck(esClient *elasticsearch.Client, index, dsl string, limit, offset int) ([]int32, error) {
+type SearchResult struct {
+	Ids   []int32
+	Total int64
+
+	// empty when there are no more results
+	NextCursor string
+
+	// only set when the dsl asked for facets
+	Facets map[string][]FacetBucket
+}
+
+// Search runs dsl against index and returns the ids of the page of hits,
+// the total number of matches, and a cursor for the next page.
+func Search(esClient *elasticsearch.Client, index, dsl string, page Page) (SearchResult, error) {
+	body, err := withPaging(dsl, page)
+	if err != nil {
+		return SearchResult{}, err
+	}
 
-	// set to true to debug scoring (locally)
-	// don't leave in in prod tho
-	explain := false
+	resBody, err := doSearch(esClient, index, body, page.Limit, false)
+	if err != nil {
+		return SearchResult{}, err
+	}
 
+	result := SearchResult{
+		Ids:    []int32{},
+		Total:  gjson.GetBytes(resBody, "hits.total.value").Int(),
+		Facets: parseFacets(resBody),
+	}
+	hits := gjson.GetBytes(resBody, "hits.hits").Array()
+	for _, hit := range hits {
+		id := hit.Get("_id").Int()
+		result.Ids = append(result.Ids, int32(id))
+	}
+
+	// a full page may be followed by more
+	if len(hits) > 0 && len(hits) == page.Limit {
+		if searchAfter, err := parseSortValues(hits[len(hits)-1].Get("sort").Raw); err == nil {
+			result.NextCursor = encodeCursor(page.Scope, searchAfter)
+		}
+	}
+
+	return result, nil
+}
+
+// explain adds each hit's score breakdown (see /v1/search/explain)
+func doSearch(esClient *elasticsearch.Client, index, body string, size int, explain bool) ([]byte, error) {
 	req := esapi.SearchRequest{
-		Index:   []string{index},
-		Body:    strings.NewReader(dsl),
-		Source:  []string{"false"},
-		Size:    &limit,
-		From:    &offset,
-		Explain: &explain,
+		Index:          []string{index},
+		Body:           strings.NewReader(body),
+		Source:         []string{"false"},
+		Size:           &size,
+		Explain:        &explain,
+		TrackTotalHits: true,
 	}
 
 	res, err := req.Do(context.Background(), esClient)
@@ -92,24 +179,43 @@ func SearchAndPluck(esClient *elasticsearch.Client, index, dsl string, limit, of
 		return nil, fmt.Errorf("Search %s failed: %s", index, res.String())
 	}
 
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, err
-	}
+	return io.ReadAll(res.Body)
+}
 
-	if explain {
-		pprintJson(string(body))
+// adds a stable sort and the page position to dsl.
+// Hits are sorted by score (or the dsl's own sort), then by id,
+// so search_after always continues from the same place.
+func withPaging(dsl string, page Page) (string, error) {
+	var query map[string]any
+	if err := json.Unmarshal([]byte(dsl), &query); err != nil {
+		return "", err
 	}
 
-	result := []int32{}
-	for _, hit := range gjson.GetBytes(body, "hits.hits").Array() {
-		id := hit.Get("_id").Int()
-		result = append(result, int32(id))
+	sort, _ := query["sort"].([]any)
+	if len(sort) == 0 {
+		sort = []any{map[string]any{"_score": "desc"}}
 	}
+	sort = append(sort, map[string]any{
+		"id": map[string]any{"order": "desc", "unmapped_type": "long"},
+	})
+	query["sort"] = sort
+
+	// sorting by a field skips scoring unless asked for
+	query["track_scores"] = true
+
+	from := page.Offset
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return "", err
+		}
+		if c.Scope == page.Scope {
+			query["search_after"] = c.SearchAfter
+			from = 0
+		}
+	}
+	query["from"] = from
 
-	return result, nil
-}
-
-func pprintJson(j string) {
-	fmt.Println(string(pretty.Pretty([]byte(j))))
+	j, err := json.Marshal(query)
+	return string(j), err
 }
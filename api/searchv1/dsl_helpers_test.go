@@ -0,0 +1,18 @@
+package searchv1
+
+import (
+	"testing"
+
+	"github.com/test-go/testify/require"
+)
+
+func TestWithMultilingual(t *testing.T) {
+	require.Equal(t,
+		[]string{
+			"title^10", "title.folded^10", "title.cjk^10", "title.translit^10",
+			"suggest",
+			"user.name", "user.name.folded", "user.name.cjk", "user.name.translit",
+		},
+		withMultilingual("title^10", "suggest", "user.name"),
+	)
+}
@@ -0,0 +1,74 @@
+package searchv1
+
+import (
+	"encoding/json"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/tidwall/gjson"
+)
+
+// most hits a search can be explained for
+const MaxExplainHits = 25
+
+type ExplainHit struct {
+	ID    int32   `json:"id"`
+	Score float64 `json:"score"`
+
+	// the values the hit was ordered by (see withPaging)
+	Sort []any `json:"sort"`
+
+	// the _explain breakdown of the score
+	Explanation json.RawMessage `json:"explanation"`
+}
+
+type Explanation struct {
+	Index string `json:"index"`
+
+	// the DSL as sent to ES, with paging
+	DSL   json.RawMessage `json:"dsl"`
+	Total int64           `json:"total"`
+	Hits  []ExplainHit    `json:"hits"`
+}
+
+// Explain runs the same search as Search,
+// and returns the score breakdown of each hit on the page.
+func Explain(esClient *elasticsearch.Client, index, dsl string, page Page) (Explanation, error) {
+	page.Limit = min(page.Limit, MaxExplainHits)
+
+	body, err := withPaging(dsl, page)
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	resBody, err := doSearch(esClient, index, body, page.Limit, true)
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	return parseExplanation(index, body, resBody), nil
+}
+
+func parseExplanation(index, body string, resBody []byte) Explanation {
+	explanation := Explanation{
+		Index: index,
+		DSL:   json.RawMessage(body),
+		Total: gjson.GetBytes(resBody, "hits.total.value").Int(),
+		Hits:  []ExplainHit{},
+	}
+
+	for _, hit := range gjson.GetBytes(resBody, "hits.hits").Array() {
+		sort, _ := parseSortValues(hit.Get("sort").Raw)
+		breakdown := hit.Get("_explanation").Raw
+		if breakdown == "" {
+			breakdown = "null"
+		}
+		explanation.Hits = append(explanation.Hits, ExplainHit{
+			ID:          int32(hit.Get("_id").Int()),
+			Score:       hit.Get("_score").Float(),
+			Sort:        sort,
+			Explanation: json.RawMessage(breakdown),
+		})
+	}
+
+	return explanation
+}
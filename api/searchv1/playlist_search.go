@@ -16,6 +16,7 @@ type PlaylistSearchQuery struct {
 	OnlyVerified bool
 	MyID         int32
 	SortMethod   string
+	OnlyLibrary  bool
 }
 
 func (q *PlaylistSearchQuery) Map() map[string]any {
@@ -26,7 +27,7 @@ func (q *PlaylistSearchQuery) Map() map[string]any {
 	} else if q.Query != "" {
 		builder.Must(esquery.MultiMatch().
 			Query(q.Query).
-			Fields("title^10", "suggest", "tracks.tags").
+			Fields(withMultilingual("title^10", "suggest", "tracks.tags")...).
 			MinimumShouldMatch("100%").
 			Fuzziness("AUTO").
 			Type(esquery.MatchTypeBoolPrefix))
@@ -34,7 +35,7 @@ func (q *PlaylistSearchQuery) Map() map[string]any {
 		// for exact title / handle / artist name match
 		builder.Should(
 			esquery.MultiMatch().Query(q.Query).
-				Fields("title^10", "user.name", "user.handle").
+				Fields(withMultilingual("title^10", "user.name", "user.handle")...).
 				Boost(10).
 				Operator(esquery.OperatorAnd),
 		)
@@ -42,7 +43,7 @@ func (q *PlaylistSearchQuery) Map() map[string]any {
 		// exact match, but remove spaces from query
 		builder.Should(
 			esquery.MultiMatch().Query(strings.ReplaceAll(q.Query, " ", "")).
-				Fields("title^10", "user.name", "user.handle").
+				Fields(withMultilingual("title^10", "user.name", "user.handle")...).
 				Boost(10).
 				Operator(esquery.OperatorAnd),
 		)
@@ -99,6 +100,11 @@ func (q *PlaylistSearchQuery) Map() map[string]any {
 		}))
 	}
 
+	// only search the user's saves and reposts
+	if q.OnlyLibrary {
+		builder.Filter(libraryFilter(q.MyID, "saved_playlist_ids", "reposted_playlist_ids"))
+	}
+
 	return builder.Map()
 }
 
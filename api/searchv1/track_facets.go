@@ -0,0 +1,180 @@
+package searchv1
+
+import (
+	"encoding/json"
+
+	"github.com/aquasecurity/esquery"
+	"github.com/tidwall/gjson"
+)
+
+// Facets count the tracks matching a search for each value of a filter,
+// so a filters UI can show how many results picking that value would give.
+//
+// A facet is counted with every filter applied except its own
+// (the filters go in the post_filter, and each aggregation applies the others),
+// so choosing a genre still shows counts for the other genres.
+
+type FacetBucket struct {
+	Value any   `json:"value"`
+	Count int64 `json:"count"`
+}
+
+// width of the bpm facet buckets
+const bpmFacetInterval = 10
+
+// most values returned for a terms facet
+const termsFacetSize = 50
+
+type facetFilter struct {
+	facet  string
+	filter esquery.Mappable
+}
+
+// the query's filters that are also facets, in a stable order
+func (q *TrackSearchQuery) facetFilters() []facetFilter {
+	filters := []facetFilter{}
+
+	if q.MinBPM > 0 || q.MaxBPM > 0 {
+		bpmRange := esquery.Range("bpm")
+		if q.MinBPM > 0 {
+			bpmRange.Gte(q.MinBPM)
+		}
+		if q.MaxBPM > 0 {
+			bpmRange.Lte(q.MaxBPM)
+		}
+		filters = append(filters, facetFilter{"bpm", bpmRange})
+	}
+
+	if len(q.Genres) > 0 {
+		filters = append(filters, facetFilter{"genre", esquery.Terms("genre.keyword", toAnySlice(q.Genres)...)})
+	}
+
+	if len(q.Moods) > 0 {
+		filters = append(filters, facetFilter{"mood", esquery.Terms("mood.keyword", toAnySlice(q.Moods)...)})
+	}
+
+	if len(q.MusicalKeys) > 0 {
+		filters = append(filters, facetFilter{"musical_key", esquery.Terms("musical_key.keyword", toAnySlice(q.MusicalKeys)...)})
+	}
+
+	if q.IsDownloadable {
+		filters = append(filters, facetFilter{"is_downloadable", esquery.Term("is_downloadable", true)})
+	}
+
+	if q.IsPurchaseable {
+		filters = append(filters, facetFilter{"is_purchaseable", isPurchaseableFilter()})
+	}
+
+	return filters
+}
+
+// stream or download
+func isPurchaseableFilter() esquery.Mappable {
+	return esquery.Bool().
+		Should(esquery.Exists("stream_conditions.usdc_purchase")).
+		Should(esquery.Exists("download_conditions.usdc_purchase"))
+}
+
+func trackFacetAggs() map[string]any {
+	terms := func(field string) map[string]any {
+		return map[string]any{
+			"terms": map[string]any{"field": field, "size": termsFacetSize},
+		}
+	}
+
+	return map[string]any{
+		"genre":       terms("genre.keyword"),
+		"mood":        terms("mood.keyword"),
+		"musical_key": terms("musical_key.keyword"),
+		"bpm": map[string]any{
+			"histogram": map[string]any{
+				"field":         "bpm",
+				"interval":      bpmFacetInterval,
+				"min_doc_count": 1,
+			},
+		},
+		"is_downloadable": terms("is_downloadable"),
+		"is_purchaseable": map[string]any{
+			"filters": map[string]any{
+				"filters":          map[string]any{"true": isPurchaseableFilter().Map()},
+				"other_bucket_key": "false",
+			},
+		},
+	}
+}
+
+// adds the facet filters as a post_filter and the facet aggregations to dsl
+func (q *TrackSearchQuery) withFacets(dsl string) string {
+	var query map[string]any
+	if err := json.Unmarshal([]byte(dsl), &query); err != nil {
+		panic(err)
+	}
+
+	filters := q.facetFilters()
+
+	// every facet filter except facet's own
+	otherFilters := func(facet string) map[string]any {
+		others := []esquery.Mappable{}
+		for _, f := range filters {
+			if f.facet != facet {
+				others = append(others, f.filter)
+			}
+		}
+		if len(others) == 0 {
+			return esquery.MatchAll().Map()
+		}
+		return esquery.Bool().Filter(others...).Map()
+	}
+
+	if len(filters) > 0 {
+		query["post_filter"] = otherFilters("")
+	}
+
+	aggs := map[string]any{}
+	for facet, agg := range trackFacetAggs() {
+		aggs[facet] = map[string]any{
+			"filter": otherFilters(facet),
+			"aggs":   map[string]any{"values": agg},
+		}
+	}
+	query["aggs"] = aggs
+
+	j, err := json.Marshal(query)
+	if err != nil {
+		panic(err)
+	}
+	return string(j)
+}
+
+// reads the facet counts of a search response, if it has any
+func parseFacets(resBody []byte) map[string][]FacetBucket {
+	aggregations := gjson.GetBytes(resBody, "aggregations")
+	if !aggregations.Exists() {
+		return nil
+	}
+
+	facets := map[string][]FacetBucket{}
+	aggregations.ForEach(func(facet, agg gjson.Result) bool {
+		buckets := []FacetBucket{}
+		values := agg.Get("values.buckets")
+		values.ForEach(func(key, bucket gjson.Result) bool {
+			// filters buckets are keyed by name, the rest are listed
+			var value any = key.String()
+			if values.IsArray() {
+				if keyAsString := bucket.Get("key_as_string"); keyAsString.Exists() {
+					value = keyAsString.String()
+				} else {
+					value = bucket.Get("key").Value()
+				}
+			}
+			buckets = append(buckets, FacetBucket{
+				Value: value,
+				Count: bucket.Get("doc_count").Int(),
+			})
+			return true
+		})
+		facets[facet.String()] = buckets
+		return true
+	})
+	return facets
+}
@@ -0,0 +1,53 @@
+package searchv1
+
+import (
+	"testing"
+
+	"github.com/test-go/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestTrackFacetsDSL(t *testing.T) {
+	q := TrackSearchQuery{
+		Query:  "trap",
+		Genres: []string{"Trap"},
+		MinBPM: 80,
+		Facets: true,
+	}
+	dsl := q.DSL()
+
+	// facet filters move out of the query...
+	require.NotContains(t, gjson.Get(dsl, "query").Raw, "genre.keyword")
+	require.Len(t, gjson.Get(dsl, "post_filter.bool.filter").Array(), 2)
+
+	// ...and each facet applies every filter but its own
+	require.Equal(t, "Trap", gjson.Get(dsl, "aggs.bpm.filter.bool.filter.0.terms.genre\\.keyword.0").String())
+	require.Equal(t, float64(80), gjson.Get(dsl, "aggs.genre.filter.bool.filter.0.range.bpm.gte").Float())
+	require.True(t, gjson.Get(dsl, "aggs.mood.filter.bool.filter").IsArray())
+	require.Equal(t, "genre.keyword", gjson.Get(dsl, "aggs.genre.aggs.values.terms.field").String())
+
+	// without facets the filters stay in the query
+	q.Facets = false
+	dsl = q.DSL()
+	require.Contains(t, gjson.Get(dsl, "query").Raw, "genre.keyword")
+	require.False(t, gjson.Get(dsl, "post_filter").Exists())
+	require.False(t, gjson.Get(dsl, "aggs").Exists())
+}
+
+func TestParseFacets(t *testing.T) {
+	require.Nil(t, parseFacets([]byte(`{"hits": {"hits": []}}`)))
+
+	facets := parseFacets([]byte(`{
+		"aggregations": {
+			"genre": {"doc_count": 3, "values": {"buckets": [{"key": "Trap", "doc_count": 2}, {"key": "Jazz", "doc_count": 1}]}},
+			"bpm": {"doc_count": 3, "values": {"buckets": [{"key": 80.0, "doc_count": 1}, {"key": 90.0, "doc_count": 2}]}},
+			"is_downloadable": {"doc_count": 3, "values": {"buckets": [{"key": 0, "key_as_string": "false", "doc_count": 2}]}},
+			"is_purchaseable": {"doc_count": 3, "values": {"buckets": {"true": {"doc_count": 1}, "false": {"doc_count": 2}}}}
+		}
+	}`))
+
+	require.Equal(t, []FacetBucket{{"Trap", 2}, {"Jazz", 1}}, facets["genre"])
+	require.Equal(t, []FacetBucket{{float64(80), 1}, {float64(90), 2}}, facets["bpm"])
+	require.Equal(t, []FacetBucket{{"false", 2}}, facets["is_downloadable"])
+	require.Equal(t, []FacetBucket{{"true", 1}, {"false", 2}}, facets["is_purchaseable"])
+}
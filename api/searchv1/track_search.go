@@ -3,24 +3,34 @@ package searchv1
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/aquasecurity/esquery"
 )
 
+// The json form is what saved searches store (see jobs/saved_searches.go),
+// so it only has the filters a user picks.
 type TrackSearchQuery struct {
-	Query          string
-	MinBPM         float64
-	MaxBPM         float64
-	IsDownloadable bool
-	IsPurchaseable bool
-	IsTagSearch    bool
-	HasDownloads   bool
-	OnlyVerified   bool
-	Genres         []string
-	Moods          []string
-	MusicalKeys    []string
-	MyID           int32
-	SortMethod     string
+	Query          string   `json:"query,omitempty"`
+	MinBPM         float64  `json:"min_bpm,omitempty"`
+	MaxBPM         float64  `json:"max_bpm,omitempty"`
+	IsDownloadable bool     `json:"is_downloadable,omitempty"`
+	IsPurchaseable bool     `json:"is_purchaseable,omitempty"`
+	IsTagSearch    bool     `json:"is_tag_search,omitempty"`
+	HasDownloads   bool     `json:"has_downloads,omitempty"`
+	OnlyVerified   bool     `json:"only_verified,omitempty"`
+	Genres         []string `json:"genres,omitempty"`
+	Moods          []string `json:"moods,omitempty"`
+	MusicalKeys    []string `json:"musical_keys,omitempty"`
+	MyID           int32    `json:"-"`
+	SortMethod     string   `json:"-"`
+	OnlyLibrary    bool     `json:"-"`
+
+	// only tracks created after this
+	CreatedAfter time.Time `json:"-"`
+
+	// adds facet counts (see track_facets.go)
+	Facets bool `json:"-"`
 }
 
 func (q *TrackSearchQuery) Map() map[string]any {
@@ -32,7 +42,7 @@ func (q *TrackSearchQuery) Map() map[string]any {
 		builder.Must(
 			esquery.MultiMatch().
 				Query(q.Query).
-				Fields("title^10", "suggest", "tags").
+				Fields(withMultilingual("title^10", "suggest", "tags")...).
 				MinimumShouldMatch("100%").
 				Fuzziness("AUTO").
 				Type(esquery.MatchTypeBoolPrefix),
@@ -41,7 +51,7 @@ func (q *TrackSearchQuery) Map() map[string]any {
 		// for exact title / handle / artist name match
 		builder.Should(
 			esquery.MultiMatch().Query(q.Query).
-				Fields("title^10", "user.name", "user.handle").
+				Fields(withMultilingual("title^10", "user.name", "user.handle")...).
 				Boost(10).
 				Operator(esquery.OperatorAnd),
 		)
@@ -50,7 +60,7 @@ func (q *TrackSearchQuery) Map() map[string]any {
 		// so 'Pure Component' ranks 'PureComponent' higher
 		builder.Should(
 			esquery.MultiMatch().Query(strings.ReplaceAll(q.Query, " ", "")).
-				Fields("title^10", "user.name", "user.handle").
+				Fields(withMultilingual("title^10", "user.name", "user.handle")...).
 				Boost(10).
 				Operator(esquery.OperatorAnd),
 		)
@@ -58,31 +68,16 @@ func (q *TrackSearchQuery) Map() map[string]any {
 		builder.Must(esquery.MatchAll())
 	}
 
-	if q.MinBPM > 0 || q.MaxBPM > 0 {
-		bpmRange := esquery.Range("bpm")
-		if q.MinBPM > 0 {
-			bpmRange.Gte(q.MinBPM)
+	// with facets on, these move to the post_filter,
+	// so each facet can count the values its own filter leaves out
+	if !q.Facets {
+		for _, f := range q.facetFilters() {
+			builder.Filter(f.filter)
 		}
-		if q.MaxBPM > 0 {
-			bpmRange.Lte(q.MaxBPM)
-		}
-		builder.Filter(bpmRange)
-	}
-
-	if len(q.Genres) > 0 {
-		builder.Filter(esquery.Terms("genre.keyword", toAnySlice(q.Genres)...))
-	}
-
-	if len(q.Moods) > 0 {
-		builder.Filter(esquery.Terms("mood.keyword", toAnySlice(q.Moods)...))
-	}
-
-	if len(q.MusicalKeys) > 0 {
-		builder.Filter(esquery.Terms("musical_key.keyword", toAnySlice(q.MusicalKeys)...))
 	}
 
-	if q.IsDownloadable {
-		builder.Filter(esquery.Term("is_downloadable", true))
+	if !q.CreatedAfter.IsZero() {
+		builder.Filter(esquery.Range("created_at").Gt(q.CreatedAfter))
 	}
 
 	if q.HasDownloads {
@@ -92,15 +87,6 @@ func (q *TrackSearchQuery) Map() map[string]any {
 		))
 	}
 
-	if q.IsPurchaseable {
-		// stream or download
-		builder.Filter(
-			esquery.Bool().
-				Should(esquery.Exists("stream_conditions.usdc_purchase")).
-				Should(esquery.Exists("download_conditions.usdc_purchase")),
-		)
-	}
-
 	if q.OnlyVerified {
 		builder.Must(esquery.Term("user.is_verified", true))
 	} else {
@@ -132,16 +118,27 @@ func (q *TrackSearchQuery) Map() map[string]any {
 		}))
 	}
 
+	// only search the user's saves and reposts
+	if q.OnlyLibrary {
+		builder.Filter(libraryFilter(q.MyID, "saved_track_ids", "reposted_track_ids"))
+	}
+
 	return builder.Map()
 }
 
 func (q *TrackSearchQuery) DSL() string {
+	var dsl string
 	switch q.SortMethod {
 	case "recent":
-		return sortWithField(q.Map(), "created_at", "desc")
+		dsl = sortWithField(q.Map(), "created_at", "desc")
 	case "popular":
-		return BuildFunctionScoreDSL("play_count", 200, q.Map())
+		dsl = BuildFunctionScoreDSL("play_count", 200, q.Map())
 	default:
-		return BuildFunctionScoreDSL("repost_count", 20, q.Map())
+		dsl = BuildFunctionScoreDSL("repost_count", 20, q.Map())
+	}
+
+	if q.Facets {
+		dsl = q.withFacets(dsl)
 	}
+	return dsl
 }
@@ -0,0 +1,44 @@
+package searchv1
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/test-go/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func TestTrackSearchQueryJSON(t *testing.T) {
+	q := TrackSearchQuery{
+		Genres:         []string{"Techno"},
+		MinBPM:         125,
+		MaxBPM:         130,
+		IsPurchaseable: true,
+		MyID:           1,
+		SortMethod:     "recent",
+		Facets:         true,
+	}
+
+	// only the filters a user picks are kept
+	j, err := json.Marshal(q)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"genres": ["Techno"], "min_bpm": 125, "max_bpm": 130, "is_purchaseable": true}`, string(j))
+
+	var decoded TrackSearchQuery
+	require.NoError(t, json.Unmarshal(j, &decoded))
+	require.Equal(t, []string{"Techno"}, decoded.Genres)
+	require.Equal(t, int32(0), decoded.MyID)
+	require.Equal(t, "", decoded.SortMethod)
+}
+
+func TestTrackSearchCreatedAfter(t *testing.T) {
+	q := TrackSearchQuery{Genres: []string{"Techno"}}
+	require.NotContains(t, q.DSL(), "created_at")
+
+	q.CreatedAfter = time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	q.SortMethod = "recent"
+	dsl := q.DSL()
+	require.Equal(t, "2025-10-01T12:00:00Z", gjson.Get(dsl, "query.bool.filter.#.range.created_at.gt|0").String())
+	require.Equal(t, "desc", gjson.Get(dsl, "sort.0.created_at.order").String())
+}
@@ -14,6 +14,7 @@ type UserSearchQuery struct {
 	Genres      []string
 	MyID        int32 `json:"my_id"`
 	SortMethod  string
+	OnlyLibrary bool
 }
 
 func (q *UserSearchQuery) Map() map[string]any {
@@ -23,7 +24,7 @@ func (q *UserSearchQuery) Map() map[string]any {
 		builder.Must(esquery.MultiMatch().Query(q.Query).Fields("tracks.tags").Type(esquery.MatchTypeBoolPrefix))
 	} else if q.Query != "" {
 		builder.Must(esquery.MultiMatch(q.Query).
-			Fields("suggest", "name", "handle").
+			Fields(withMultilingual("suggest", "name", "handle")...).
 			MinimumShouldMatch("80%").
 			Fuzziness("AUTO").
 			Type(esquery.MatchTypeBoolPrefix))
@@ -31,7 +32,7 @@ func (q *UserSearchQuery) Map() map[string]any {
 		// for exact title match
 		builder.Should(
 			esquery.MultiMatch().Query(q.Query).
-				Fields("name", "handle").
+				Fields(withMultilingual("name", "handle")...).
 				Boost(1000).
 				Operator(esquery.OperatorAnd),
 		)
@@ -40,7 +41,7 @@ func (q *UserSearchQuery) Map() map[string]any {
 		// so 'Stereo Steve' ranks 'StereoSteve' higher
 		builder.Should(
 			esquery.MultiMatch().Query(strings.ReplaceAll(q.Query, " ", "")).
-				Fields("name", "handle").
+				Fields(withMultilingual("name", "handle")...).
 				Boost(10).
 				Operator(esquery.OperatorAnd),
 		)
@@ -76,6 +77,11 @@ func (q *UserSearchQuery) Map() map[string]any {
 		builder.Should(esquery.Term("is_verified", true))
 	}
 
+	// only search the users they follow
+	if q.OnlyLibrary {
+		builder.Filter(libraryFilter(q.MyID, "following_user_ids"))
+	}
+
 	return builder.Map()
 }
 
@@ -171,6 +171,15 @@ func NewApiServer(config config.Config) *ApiServer {
 		metricsCollector = NewMetricsCollector(logger, writePool)
 	}
 
+	// Tests make their own, so responses don't outlive fixture changes
+	var responseCache *ResponseCache
+	if config.Env != "test" {
+		responseCache, err = NewResponseCache(logger, config.ResponseCacheRedisUrl)
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	commsRpcProcessor, err := comms.NewProcessor(pool, writePool, &config, logger)
 	if err != nil {
 		panic(err)
@@ -203,9 +212,11 @@ func NewApiServer(config config.Config) *ApiServer {
 		claimableTokensClient: claimableTokensClient,
 		solanaConfig:          &config.SolanaConfig,
 		antiAbuseOracles:      config.AntiAbuseOracles,
+		adminWallets:          config.AdminWallets,
 		validators:            config.Nodes,
 		auds:                  auds,
 		metricsCollector:      metricsCollector,
+		responseCache:         responseCache,
 		birdeyeClient:         birdeye.New(config.BirdeyeToken),
 		solanaRpcClient:       solanaRpc,
 		meteoraDbcClient:      meteoraDbcClient,
@@ -291,6 +302,7 @@ func NewApiServer(config config.Config) *ApiServer {
 
 	// resolve myId
 	app.Use(app.isFullMiddleware)
+	app.Use(app.conditionalRequestMiddleware)
 	app.Use(app.resolveMyIdMiddleware)
 	app.Use(app.authMiddleware)
 
@@ -321,7 +333,7 @@ func NewApiServer(config config.Config) *ApiServer {
 		g.Get("/users/verify_token", app.v1UsersVerifyToken)
 
 		g.Use("/users/handle/:handle", app.requireHandleMiddleware)
-		g.Get("/users/handle/:handle", app.v1User)
+		g.Get("/users/handle/:handle", app.responseCacheMiddleware(5*time.Minute), app.v1User)
 		g.Get("/users/handle/:handle/tracks", app.v1UserTracks)
 		g.Get("/users/handle/:handle/albums", app.v1UserAlbums)
 		g.Get("/users/handle/:handle/playlists", app.v1UserPlaylists)
@@ -374,15 +386,19 @@ func NewApiServer(config config.Config) *ApiServer {
 		g.Get("/users/:userId/coins/:mint", app.v1UsersCoin)
 		g.Get("/users/:userId/authorized_apps", app.v1UsersAuthorizedApps)
 		g.Get("/users/:userId/authorized-apps", app.v1UsersAuthorizedApps)
+		g.Get("/users/:userId/saved_searches", app.requireSavedSearchOwnerMiddleware, app.v1UsersSavedSearches)
+		g.Post("/users/:userId/saved_searches", app.requireSavedSearchOwnerMiddleware, app.v1UsersCreateSavedSearch)
+		g.Delete("/users/:userId/saved_searches/:savedSearchId", app.requireSavedSearchOwnerMiddleware, app.v1UsersDeleteSavedSearch)
+		g.Get("/users/:userId/saved_searches/:savedSearchId/new", app.requireSavedSearchOwnerMiddleware, app.v1UsersSavedSearchNew)
 
 		// Tracks
 		g.Get("/tracks", app.v1Tracks)
 		g.Get("/tracks/search", app.v1TracksSearch)
 		g.Get("/tracks/unclaimed_id", app.v1TracksUnclaimedId)
 
-		g.Get("/tracks/trending", app.v1TracksTrending)
+		g.Get("/tracks/trending", app.responseCacheMiddleware(time.Minute), app.v1TracksTrending)
 		g.Get("/tracks/trending/ids", app.v1TracksTrendingIds)
-		g.Get("/tracks/trending/underground", app.v1TracksTrendingUnderground)
+		g.Get("/tracks/trending/underground", app.responseCacheMiddleware(time.Minute), app.v1TracksTrendingUnderground)
 		g.Get("/tracks/recommended", app.v1TracksTrending)
 		g.Get("/tracks/recent-premium", app.v1TracksRecentPremium)
 		g.Get("/tracks/usdc-purchase", app.v1TracksUsdcPurchase)
@@ -392,7 +408,7 @@ func NewApiServer(config config.Config) *ApiServer {
 		g.Get("/tracks/most-shared", app.v1TracksMostShared)
 
 		g.Use("/tracks/:trackId", app.requireTrackIdMiddleware)
-		g.Get("/tracks/:trackId", app.v1Track)
+		g.Get("/tracks/:trackId", app.responseCacheMiddleware(5*time.Minute), app.v1Track)
 		g.Get("/tracks/:trackId/stream", app.v1TrackStream)
 		g.Get("/tracks/:trackId/download", app.v1TrackDownload)
 		g.Get("/tracks/:trackId/inspect", app.v1TrackInspect)
@@ -415,12 +431,12 @@ func NewApiServer(config config.Config) *ApiServer {
 		g.Get("/playlists/search", app.v1PlaylistsSearch)
 		g.Get("/playlists/unclaimed_id", app.v1PlaylistsUnclaimedId)
 		g.Get("/playlists/unclaimed-id", app.v1PlaylistsUnclaimedId)
-		g.Get("/playlists/trending", app.v1PlaylistsTrending)
-		g.Get("/playlists/by_permalink/:handle/:slug", app.v1PlaylistByPermalink)
-		g.Get("/playlists/by-permalink/:handle/:slug", app.v1PlaylistByPermalink)
+		g.Get("/playlists/trending", app.responseCacheMiddleware(time.Minute), app.v1PlaylistsTrending)
+		g.Get("/playlists/by_permalink/:handle/:slug", app.responseCacheMiddleware(5*time.Minute), app.v1PlaylistByPermalink)
+		g.Get("/playlists/by-permalink/:handle/:slug", app.responseCacheMiddleware(5*time.Minute), app.v1PlaylistByPermalink)
 
 		g.Use("/playlists/:playlistId", app.requirePlaylistIdMiddleware)
-		g.Get("/playlists/:playlistId", app.v1Playlist)
+		g.Get("/playlists/:playlistId", app.responseCacheMiddleware(5*time.Minute), app.v1Playlist)
 		g.Get("/playlists/:playlistId/stream", app.v1PlaylistStream)
 		g.Get("/playlists/:playlistId/reposts", app.v1PlaylistReposts)
 		g.Get("/playlists/:playlistId/favorites", app.v1PlaylistFavorites)
@@ -429,9 +445,11 @@ func NewApiServer(config config.Config) *ApiServer {
 		g.Get("/explore/best-selling", app.v1ExploreBestSelling)
 
 		// Search
-		g.Get("/search/autocomplete", app.v1SearchFull)
+		g.Get("/search/autocomplete", app.v1SearchAutocomplete)
 		g.Get("/search/full", app.v1SearchFull)
+		g.Get("/search/suggest", app.v1SearchSuggest)
 		g.Get("/search/tags", app.v1SearchFull)
+		g.Get("/search/explain", app.requireAdminOrDevMiddleware, app.v1SearchExplain)
 
 		// Developer Apps
 		g.Get("/developer_apps/:address", app.v1DeveloperApps)
@@ -514,6 +532,7 @@ func NewApiServer(config config.Config) *ApiServer {
 	comms.Get("/chats/ws", app.validateWebsocketMiddleware, websocket.New(app.getChatWebsocket))
 
 	comms.Get("/chats/:chatId/messages", app.getChatMessages)
+	comms.Get("/chats/:chatId/presence", app.getChatPresence)
 	comms.Get("/chats/:chatId", app.getChat)
 
 	comms.Get("/blasts", app.getNewBlasts)
@@ -594,11 +613,13 @@ type ApiServer struct {
 	transactionSender     *spl.TransactionSender
 	solanaConfig          *config.SolanaConfig
 	antiAbuseOracles      []string
+	adminWallets          []string
 	validators            []config.Node
 	env                   string
 	auds                  *sdk.AudiusdSDK
 	skipAuthCheck         bool // set to true in a test if you don't care about auth middleware
 	metricsCollector      *MetricsCollector
+	responseCache         *ResponseCache
 	birdeyeClient         BirdeyeClient
 	solanaRpcClient       *rpc.Client
 	meteoraDbcClient      *meteora_dbc.Client
@@ -674,11 +695,21 @@ func (as *ApiServer) Serve() {
 		}
 	}()
 
+	jobsCtx, cancelJobs := context.WithCancel(context.Background())
+	if as.responseCache != nil && config.Cfg.WriteDbUrl != "" {
+		go func() {
+			if err := as.responseCache.Listen(jobsCtx, config.Cfg.WriteDbUrl); err != nil && jobsCtx.Err() == nil {
+				as.logger.Error("response cache listener stopped", zap.Error(err))
+			}
+		}()
+	}
+
 	// Graceful shutdown
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 	go func() {
 		<-c
+		cancelJobs()
 		as.commsRpcProcessor.Shutdown()
 		flushTicker.Stop()
 
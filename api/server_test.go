@@ -315,3 +315,20 @@ func testPostWithWallet(t *testing.T, app *ApiServer, path string, walletAddress
 
 	return res.StatusCode, resBody
 }
+
+// testDeleteWithWallet makes a DELETE request with authentication headers for the given wallet address
+func testDeleteWithWallet(t *testing.T, app *ApiServer, path string, walletAddress string) (int, []byte) {
+	req := httptest.NewRequest("DELETE", path, nil)
+
+	if walletAddress != "" {
+		sigData := testdata.GetSignatureData(walletAddress)
+		req.Header.Set("Encoded-Data-Message", sigData.Message)
+		req.Header.Set("Encoded-Data-Signature", sigData.Signature)
+	}
+
+	res, err := app.Test(req, -1)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(res.Body)
+
+	return res.StatusCode, body
+}
@@ -68,7 +68,7 @@ func (app *ApiServer) v1DashboardWalletUsers(c *fiber.Ctx) error {
 	for i, row := range walletUserRows {
 		userIds[i] = row.UserID
 	}
-	users, err := app.queries.FullUsers(c.Context(), dbv1.GetUsersParams{MyID: myId, Ids: userIds})
+	users, err := app.queries.FullUsers(c.Context(), dbv1.FullUsersParams{GetUsersParams: dbv1.GetUsersParams{MyID: myId, Ids: userIds}})
 	if err != nil {
 		return err
 	}
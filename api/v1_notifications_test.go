@@ -207,3 +207,51 @@ func TestV1Notifications_Comment(t *testing.T) {
 		"data.notifications.0.actions.0.data.type": "Track",
 	})
 }
+
+func TestV1Notifications_SavedSearch(t *testing.T) {
+	app := emptyTestApp(t)
+
+	fixtures := database.FixtureMap{
+		"notification": []map[string]any{
+			{
+				"id":        1,
+				"specifier": "10",
+				"group_id":  "saved_search:100:1760000000",
+				"type":      "saved_search",
+				"user_ids":  []int{1},
+				"data":      []byte(`{"saved_search_id": 100, "track_id": 10}`),
+			},
+			{
+				"id":        2,
+				"specifier": "11",
+				"group_id":  "saved_search:100:1760000000",
+				"type":      "saved_search",
+				"user_ids":  []int{1},
+				"data":      []byte(`{"saved_search_id": 100, "track_id": 11}`),
+			},
+			{
+				"id":        3,
+				"specifier": "12",
+				"group_id":  "saved_search:101:1760000000",
+				"type":      "saved_search",
+				"user_ids":  []int{2},
+				"data":      []byte(`{"saved_search_id": 101, "track_id": 12}`),
+			},
+		},
+	}
+
+	database.Seed(app.pool.Replicas[0], fixtures)
+
+	status, body := testGet(t, app, "/v1/notifications/"+trashid.MustEncodeHashID(1)+"?types=saved_search")
+	assert.Equal(t, 200, status)
+
+	jsonAssert(t, body, map[string]any{
+		"data.notifications.#":                                1,
+		"data.notifications.0.type":                           "saved_search",
+		"data.notifications.0.group_id":                       "saved_search:100:1760000000",
+		"data.notifications.0.actions.#":                      2,
+		"data.notifications.0.actions.0.data.saved_search_id": trashid.MustEncodeHashID(100),
+		"data.notifications.0.actions.0.data.track_id":        trashid.MustEncodeHashID(10),
+		"data.notifications.0.actions.1.data.track_id":        trashid.MustEncodeHashID(11),
+	})
+}
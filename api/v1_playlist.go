@@ -14,6 +14,7 @@ func (app *ApiServer) v1Playlist(c *fiber.Ctx) error {
 			MyID: myId,
 			Ids:  []int32{int32(playlistId)},
 		},
+		Fields: app.getFields(c),
 	})
 	if err != nil {
 		return err
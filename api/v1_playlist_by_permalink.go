@@ -33,6 +33,7 @@ func (app *ApiServer) v1PlaylistByPermalink(c *fiber.Ctx) error {
 			MyID: myId,
 			Ids:  ids,
 		},
+		Fields: app.getFields(c),
 	})
 	if err != nil {
 		return err
@@ -55,6 +55,7 @@ func (app *ApiServer) v1Playlists(c *fiber.Ctx) error {
 			Ids:  ids,
 		},
 		OmitTracks: !withTracks,
+		Fields:     app.getFields(c),
 	})
 	if err != nil {
 		return err
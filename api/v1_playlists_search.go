@@ -5,12 +5,14 @@ import (
 )
 
 func (app *ApiServer) v1PlaylistsSearch(c *fiber.Ctx) error {
-	playlists, err := app.searchPlaylists(c)
+	playlists, result, err := app.searchPlaylists(c, false)
 	if err != nil {
 		return err
 	}
 
 	return c.JSON(fiber.Map{
-		"data": playlists,
+		"data":        playlists,
+		"total":       result.Total,
+		"next_cursor": nullableCursor(result.NextCursor),
 	})
 }
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"errors"
 	"strconv"
 	"strings"
+	"sync"
 
 	"bridgerton.audius.co/api/dbv1"
 	"bridgerton.audius.co/api/searchv1"
@@ -13,96 +15,148 @@ import (
 func (app *ApiServer) v1SearchFull(c *fiber.Ctx) error {
 	kind := c.Query("kind", "all")
 
+	// the saved_* sections search within the caller's own library
+	includeLibrary := app.getMyId(c) > 0
+
 	g := errgroup.Group{}
 	var users = []dbv1.FullUser{}
 	var tracks = []dbv1.FullTrack{}
 	var playlists = []dbv1.FullPlaylist{}
 	var albums = []dbv1.FullPlaylist{}
-
-	// users
-	g.Go(func() (err error) {
-		if kind != "all" && kind != "users" {
-			return nil
+	var savedUsers = []dbv1.FullUser{}
+	var savedTracks = []dbv1.FullTrack{}
+	var savedPlaylists = []dbv1.FullPlaylist{}
+	var savedAlbums = []dbv1.FullPlaylist{}
+
+	// totals and next page cursors, by section
+	var mu sync.Mutex
+	totals := fiber.Map{}
+	nextCursors := fiber.Map{}
+	var trackFacets map[string][]searchv1.FacetBucket
+	setPage := func(section string, onlyLibrary bool, result searchv1.SearchResult) {
+		if onlyLibrary {
+			section = "saved_" + section
 		}
+		mu.Lock()
+		defer mu.Unlock()
+		totals[section] = result.Total
+		if result.NextCursor != "" {
+			nextCursors[section] = result.NextCursor
+		}
+	}
 
-		users, err = app.searchUsers(c)
-		return err
-	})
-
-	// tracks
-	g.Go(func() (err error) {
-		if kind != "all" && kind != "tracks" {
-			return nil
+	for _, onlyLibrary := range []bool{false, true} {
+		if onlyLibrary && !includeLibrary {
+			continue
 		}
 
-		tracks, err = app.searchTracks(c)
-		return err
-	})
+		// users
+		g.Go(func() error {
+			if kind != "all" && kind != "users" {
+				return nil
+			}
 
-	// playlists
-	g.Go(func() (err error) {
-		if kind != "all" && kind != "playlists" {
-			return nil
-		}
+			found, result, err := app.searchUsers(c, onlyLibrary)
+			if onlyLibrary {
+				savedUsers = found
+			} else {
+				users = found
+			}
+			setPage("users", onlyLibrary, result)
+			return err
+		})
+
+		// tracks
+		g.Go(func() error {
+			if kind != "all" && kind != "tracks" {
+				return nil
+			}
 
-		playlists, err = app.searchPlaylists(c)
-		return err
-	})
+			found, result, err := app.searchTracks(c, onlyLibrary)
+			if onlyLibrary {
+				savedTracks = found
+			} else {
+				tracks = found
+				trackFacets = result.Facets
+			}
+			setPage("tracks", onlyLibrary, result)
+			return err
+		})
+
+		// playlists
+		g.Go(func() error {
+			if kind != "all" && kind != "playlists" {
+				return nil
+			}
 
-	// albums
-	g.Go(func() (err error) {
-		if kind != "all" && kind != "albums" {
-			return nil
-		}
+			found, result, err := app.searchPlaylists(c, onlyLibrary)
+			if onlyLibrary {
+				savedPlaylists = found
+			} else {
+				playlists = found
+			}
+			setPage("playlists", onlyLibrary, result)
+			return err
+		})
+
+		// albums
+		g.Go(func() error {
+			if kind != "all" && kind != "albums" {
+				return nil
+			}
 
-		albums, err = app.searchAlbums(c)
-		return err
-	})
+			found, result, err := app.searchAlbums(c, onlyLibrary)
+			if onlyLibrary {
+				savedAlbums = found
+			} else {
+				albums = found
+			}
+			setPage("albums", onlyLibrary, result)
+			return err
+		})
+	}
 
 	err := g.Wait()
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(fiber.Map{
+	res := fiber.Map{
 		"data": fiber.Map{
 			"users":     users,
 			"tracks":    tracks,
 			"playlists": playlists,
 			"albums":    albums,
 
-			// todos
-			"saved_albums":    []any{},
-			"saved_users":     []any{},
-			"saved_tracks":    []any{},
-			"saved_playlists": []any{},
+			"saved_albums":    savedAlbums,
+			"saved_users":     savedUsers,
+			"saved_tracks":    savedTracks,
+			"saved_playlists": savedPlaylists,
 		},
-	})
+		"totals":       totals,
+		"next_cursors": nextCursors,
+	}
+
+	// facets=true
+	if trackFacets != nil {
+		res["facets"] = fiber.Map{"tracks": trackFacets}
+	}
+
+	return c.JSON(res)
 }
 
-func (app *ApiServer) searchUsers(c *fiber.Ctx) ([]dbv1.FullUser, error) {
-	isTagSearch := strings.Contains(c.Route().Path, "search/tags")
+func (app *ApiServer) searchUsers(c *fiber.Ctx, onlyLibrary bool) ([]dbv1.FullUser, searchv1.SearchResult, error) {
 	isFullSearch := strings.Contains(c.Route().Path, "search/full")
-	limit := c.QueryInt("limit", 10)
-	offset := c.QueryInt("offset", 0)
 	myId := app.getMyId(c)
-
-	q := searchv1.UserSearchQuery{
-		Query:       c.Query("query"),
-		IsVerified:  c.QueryBool("is_verified"),
-		IsTagSearch: isTagSearch,
-		Genres:      queryMulti(c, "genre"),
-		MyID:        myId,
-		SortMethod:  c.Query("sort_method"),
-	}
+	q := app.userSearchQuery(c, onlyLibrary)
 
 	if c.QueryBool("debug") {
 		c.Set("x-user-dsl", q.DSL())
 	}
 
-	userIds, err := searchv1.SearchAndPluck(app.esClient, "users", q.DSL(), limit, offset)
+	result, err := searchv1.Search(app.esClient, "users", q.DSL(), searchPage(c, "users", onlyLibrary))
 	if err != nil {
-		return nil, err
+		return nil, result, searchError(err)
 	}
 
 	// savings: only personalize results for "full" endpoint
@@ -110,58 +164,27 @@ func (app *ApiServer) searchUsers(c *fiber.Ctx) ([]dbv1.FullUser, error) {
 		myId = 0
 	}
 
-	users, err := app.queries.FullUsers(c.Context(), dbv1.GetUsersParams{
-		Ids:  userIds,
-		MyID: myId,
+	users, err := app.queries.FullUsers(c.Context(), dbv1.FullUsersParams{
+		GetUsersParams: dbv1.GetUsersParams{
+			Ids:  result.Ids,
+			MyID: myId,
+		},
 	})
-	return users, err
+	return users, result, err
 }
 
-func (app *ApiServer) searchTracks(c *fiber.Ctx) ([]dbv1.FullTrack, error) {
-	isTagSearch := strings.Contains(c.Route().Path, "search/tags")
+func (app *ApiServer) searchTracks(c *fiber.Ctx, onlyLibrary bool) ([]dbv1.FullTrack, searchv1.SearchResult, error) {
 	isFullSearch := strings.Contains(c.Route().Path, "search/full")
-	limit := c.QueryInt("limit", 10)
-	offset := c.QueryInt("offset", 0)
 	myId := app.getMyId(c)
-
-	// bpm range
-	minBpm := c.QueryFloat("bpm_min")
-	maxBpm := c.QueryFloat("bpm_max")
-	if bpmRange := c.Query("bpm"); bpmRange != "" {
-		parts := strings.Split(bpmRange, "-")
-		if len(parts) == 2 {
-			if min, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err == nil {
-				minBpm = min
-			}
-			if max, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err == nil {
-				maxBpm = max
-			}
-		}
-	}
-
-	q := searchv1.TrackSearchQuery{
-		MyID:           myId,
-		IsTagSearch:    isTagSearch,
-		Query:          c.Query("query"),
-		Genres:         queryMulti(c, "genre"),
-		Moods:          queryMulti(c, "mood"),
-		MinBPM:         minBpm,
-		MaxBPM:         maxBpm,
-		MusicalKeys:    queryMulti(c, "key"),
-		IsDownloadable: c.QueryBool("is_downloadable"),
-		HasDownloads:   c.QueryBool("has_downloads"),
-		IsPurchaseable: c.QueryBool("is_purchaseable"),
-		OnlyVerified:   c.QueryBool("is_verified"),
-		SortMethod:     c.Query("sort_method"),
-	}
+	q := app.trackSearchQuery(c, onlyLibrary)
 
 	if c.QueryBool("debug") {
 		c.Set("x-track-dsl", q.DSL())
 	}
 
-	tracksIds, err := searchv1.SearchAndPluck(app.esClient, "tracks", q.DSL(), limit, offset)
+	result, err := searchv1.Search(app.esClient, "tracks", q.DSL(), searchPage(c, "tracks", onlyLibrary))
 	if err != nil {
-		return nil, err
+		return nil, result, searchError(err)
 	}
 
 	// savings: only personalize results for "full" endpoint
@@ -171,37 +194,25 @@ func (app *ApiServer) searchTracks(c *fiber.Ctx) ([]dbv1.FullTrack, error) {
 
 	tracks, err := app.queries.FullTracks(c.Context(), dbv1.FullTracksParams{
 		GetTracksParams: dbv1.GetTracksParams{
-			Ids:  tracksIds,
+			Ids:  result.Ids,
 			MyID: myId,
 		},
 	})
-	return tracks, err
+	return tracks, result, err
 }
 
-func (app *ApiServer) searchPlaylists(c *fiber.Ctx) ([]dbv1.FullPlaylist, error) {
-	isTagSearch := strings.Contains(c.Route().Path, "search/tags")
+func (app *ApiServer) searchPlaylists(c *fiber.Ctx, onlyLibrary bool) ([]dbv1.FullPlaylist, searchv1.SearchResult, error) {
 	isFullSearch := strings.Contains(c.Route().Path, "search/full")
-	limit := c.QueryInt("limit", 10)
-	offset := c.QueryInt("offset", 0)
 	myId := app.getMyId(c)
-
-	q := searchv1.PlaylistSearchQuery{
-		MyID:         myId,
-		IsTagSearch:  isTagSearch,
-		Query:        c.Query("query"),
-		Genres:       queryMulti(c, "genre"),
-		Moods:        queryMulti(c, "mood"),
-		OnlyVerified: c.QueryBool("is_verified"),
-		SortMethod:   c.Query("sort_method"),
-	}
+	q := app.playlistSearchQuery(c, onlyLibrary, false)
 
 	if c.QueryBool("debug") {
 		c.Set("x-playlist-dsl", q.DSL())
 	}
 
-	playlistsIds, err := searchv1.SearchAndPluck(app.esClient, "playlists", q.DSL(), limit, offset)
+	result, err := searchv1.Search(app.esClient, "playlists", q.DSL(), searchPage(c, "playlists", onlyLibrary))
 	if err != nil {
-		return nil, err
+		return nil, result, searchError(err)
 	}
 
 	// savings: only personalize results for "full" endpoint
@@ -211,39 +222,26 @@ func (app *ApiServer) searchPlaylists(c *fiber.Ctx) ([]dbv1.FullPlaylist, error)
 
 	playlists, err := app.queries.FullPlaylists(c.Context(), dbv1.FullPlaylistsParams{
 		GetPlaylistsParams: dbv1.GetPlaylistsParams{
-			Ids:  playlistsIds,
+			Ids:  result.Ids,
 			MyID: myId,
 		},
 		OmitTracks: true,
 	})
-	return playlists, err
+	return playlists, result, err
 }
 
-func (app *ApiServer) searchAlbums(c *fiber.Ctx) ([]dbv1.FullPlaylist, error) {
-	isTagSearch := strings.Contains(c.Route().Path, "search/tags")
+func (app *ApiServer) searchAlbums(c *fiber.Ctx, onlyLibrary bool) ([]dbv1.FullPlaylist, searchv1.SearchResult, error) {
 	isFullSearch := strings.Contains(c.Route().Path, "search/full")
-	limit := c.QueryInt("limit", 10)
-	offset := c.QueryInt("offset", 0)
 	myId := app.getMyId(c)
-
-	q := searchv1.PlaylistSearchQuery{
-		MyID:         myId,
-		IsTagSearch:  isTagSearch,
-		Query:        c.Query("query"),
-		Genres:       queryMulti(c, "genre"),
-		Moods:        queryMulti(c, "mood"),
-		OnlyVerified: c.QueryBool("is_verified"),
-		SortMethod:   c.Query("sort_method"),
-		IsAlbum:      true,
-	}
+	q := app.playlistSearchQuery(c, onlyLibrary, true)
 
 	if c.QueryBool("debug") {
 		c.Set("x-album-dsl", q.DSL())
 	}
 
-	playlistsIds, err := searchv1.SearchAndPluck(app.esClient, "playlists", q.DSL(), limit, offset)
+	result, err := searchv1.Search(app.esClient, "playlists", q.DSL(), searchPage(c, "albums", onlyLibrary))
 	if err != nil {
-		return nil, err
+		return nil, result, searchError(err)
 	}
 
 	// savings: only personalize results for "full" endpoint
@@ -253,10 +251,101 @@ func (app *ApiServer) searchAlbums(c *fiber.Ctx) ([]dbv1.FullPlaylist, error) {
 
 	playlists, err := app.queries.FullPlaylists(c.Context(), dbv1.FullPlaylistsParams{
 		GetPlaylistsParams: dbv1.GetPlaylistsParams{
-			Ids:  playlistsIds,
+			Ids:  result.Ids,
 			MyID: myId,
 		},
 		OmitTracks: true,
 	})
-	return playlists, err
+	return playlists, result, err
+}
+
+// the search queries are built the same way for search and explain
+
+func (app *ApiServer) userSearchQuery(c *fiber.Ctx, onlyLibrary bool) searchv1.UserSearchQuery {
+	return searchv1.UserSearchQuery{
+		Query:       c.Query("query"),
+		IsVerified:  c.QueryBool("is_verified"),
+		IsTagSearch: strings.Contains(c.Route().Path, "search/tags"),
+		Genres:      queryMulti(c, "genre"),
+		MyID:        app.getMyId(c),
+		SortMethod:  c.Query("sort_method"),
+		OnlyLibrary: onlyLibrary,
+	}
+}
+
+func (app *ApiServer) trackSearchQuery(c *fiber.Ctx, onlyLibrary bool) searchv1.TrackSearchQuery {
+	// bpm range
+	minBpm := c.QueryFloat("bpm_min")
+	maxBpm := c.QueryFloat("bpm_max")
+	if bpmRange := c.Query("bpm"); bpmRange != "" {
+		parts := strings.Split(bpmRange, "-")
+		if len(parts) == 2 {
+			if min, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err == nil {
+				minBpm = min
+			}
+			if max, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err == nil {
+				maxBpm = max
+			}
+		}
+	}
+
+	return searchv1.TrackSearchQuery{
+		MyID:           app.getMyId(c),
+		IsTagSearch:    strings.Contains(c.Route().Path, "search/tags"),
+		Query:          c.Query("query"),
+		Genres:         queryMulti(c, "genre"),
+		Moods:          queryMulti(c, "mood"),
+		MinBPM:         minBpm,
+		MaxBPM:         maxBpm,
+		MusicalKeys:    queryMulti(c, "key"),
+		IsDownloadable: c.QueryBool("is_downloadable"),
+		HasDownloads:   c.QueryBool("has_downloads"),
+		IsPurchaseable: c.QueryBool("is_purchaseable"),
+		OnlyVerified:   c.QueryBool("is_verified"),
+		SortMethod:     c.Query("sort_method"),
+		OnlyLibrary:    onlyLibrary,
+		Facets:         c.QueryBool("facets") && !onlyLibrary,
+	}
+}
+
+func (app *ApiServer) playlistSearchQuery(c *fiber.Ctx, onlyLibrary, isAlbum bool) searchv1.PlaylistSearchQuery {
+	return searchv1.PlaylistSearchQuery{
+		MyID:         app.getMyId(c),
+		IsTagSearch:  strings.Contains(c.Route().Path, "search/tags"),
+		Query:        c.Query("query"),
+		Genres:       queryMulti(c, "genre"),
+		Moods:        queryMulti(c, "mood"),
+		OnlyVerified: c.QueryBool("is_verified"),
+		SortMethod:   c.Query("sort_method"),
+		IsAlbum:      isAlbum,
+		OnlyLibrary:  onlyLibrary,
+	}
+}
+
+// the cursor param pages the section it was issued for
+func searchPage(c *fiber.Ctx, scope string, onlyLibrary bool) searchv1.Page {
+	if onlyLibrary {
+		scope = "saved_" + scope
+	}
+	return searchv1.Page{
+		Limit:  c.QueryInt("limit", 10),
+		Offset: c.QueryInt("offset", 0),
+		Cursor: c.Query("cursor"),
+		Scope:  scope,
+	}
+}
+
+func searchError(err error) error {
+	if errors.Is(err, searchv1.ErrInvalidCursor) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return err
+}
+
+// next_cursor is null on the last page
+func nullableCursor(cursor string) *string {
+	if cursor == "" {
+		return nil
+	}
+	return &cursor
 }
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+
+	"bridgerton.audius.co/api/dbv1"
+	"bridgerton.audius.co/api/searchv1"
+	"bridgerton.audius.co/trashid"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+// Shows how /v1/search/full ranks a query:
+// the query each section builds, the score breakdown of its top hits,
+// and which hits are left once they are loaded from the database
+// (results keep the search order, and hits the database no longer has are dropped).
+//
+// Takes the same params as /v1/search/full,
+// with limit capped at searchv1.MaxExplainHits.
+
+type searchExplainSection struct {
+	Query       map[string]any       `json:"query"`
+	Explanation searchv1.Explanation `json:"explanation"`
+
+	// the ids returned, in order
+	Results []trashid.HashId `json:"results"`
+
+	// hits that weren't returned
+	Dropped []trashid.HashId `json:"dropped"`
+}
+
+func (app *ApiServer) v1SearchExplain(c *fiber.Ctx) error {
+	kind := c.Query("kind", "all")
+	myId := app.getMyId(c)
+
+	g := errgroup.Group{}
+	sections := map[string]*searchExplainSection{}
+
+	explain := func(section, index string, query map[string]any, dsl string, hydrated func([]int32) (map[int32]bool, error)) {
+		if kind != "all" && kind != section {
+			return
+		}
+
+		s := &searchExplainSection{Query: query}
+		sections[section] = s
+
+		g.Go(func() error {
+			explanation, err := searchv1.Explain(app.esClient, index, dsl, searchPage(c, section, false))
+			if err != nil {
+				return searchError(err)
+			}
+			s.Explanation = explanation
+
+			ids := []int32{}
+			for _, hit := range explanation.Hits {
+				ids = append(ids, hit.ID)
+			}
+			found, err := hydrated(ids)
+			if err != nil {
+				return err
+			}
+
+			s.Results = []trashid.HashId{}
+			s.Dropped = []trashid.HashId{}
+			for _, id := range ids {
+				if found[id] {
+					s.Results = append(s.Results, trashid.HashId(id))
+				} else {
+					s.Dropped = append(s.Dropped, trashid.HashId(id))
+				}
+			}
+			return nil
+		})
+	}
+
+	ctx := c.Context()
+
+	users := app.userSearchQuery(c, false)
+	explain("users", "users", users.Map(), users.DSL(), func(ids []int32) (map[int32]bool, error) {
+		found, err := app.queries.FullUsersKeyed(ctx, dbv1.FullUsersParams{GetUsersParams: dbv1.GetUsersParams{Ids: ids, MyID: myId}})
+		return keysOf(found), err
+	})
+
+	tracks := app.trackSearchQuery(c, false)
+	explain("tracks", "tracks", tracks.Map(), tracks.DSL(), func(ids []int32) (map[int32]bool, error) {
+		found, err := app.queries.FullTracksKeyed(ctx, dbv1.FullTracksParams{
+			GetTracksParams: dbv1.GetTracksParams{Ids: ids, MyID: myId},
+		})
+		return keysOf(found), err
+	})
+
+	playlists := app.playlistSearchQuery(c, false, false)
+	explain("playlists", "playlists", playlists.Map(), playlists.DSL(), app.hydratedPlaylists(ctx, myId))
+
+	albums := app.playlistSearchQuery(c, false, true)
+	explain("albums", "playlists", albums.Map(), albums.DSL(), app.hydratedPlaylists(ctx, myId))
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": sections,
+	})
+}
+
+func (app *ApiServer) hydratedPlaylists(ctx context.Context, myId int32) func([]int32) (map[int32]bool, error) {
+	return func(ids []int32) (map[int32]bool, error) {
+		found, err := app.queries.FullPlaylistsKeyed(ctx, dbv1.FullPlaylistsParams{
+			GetPlaylistsParams: dbv1.GetPlaylistsParams{Ids: ids, MyID: myId},
+			OmitTracks:         true,
+		})
+		return keysOf(found), err
+	}
+}
+
+func keysOf[T any](m map[int32]T) map[int32]bool {
+	keys := map[int32]bool{}
+	for k := range m {
+		keys[k] = true
+	}
+	return keys
+}
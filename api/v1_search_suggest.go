@@ -0,0 +1,218 @@
+package api
+
+import (
+	"fmt"
+
+	"bridgerton.audius.co/api/dbv1"
+	"bridgerton.audius.co/api/searchv1"
+	"bridgerton.audius.co/trashid"
+	"github.com/gofiber/fiber/v2"
+	"github.com/tidwall/gjson"
+	"golang.org/x/sync/errgroup"
+)
+
+// Suggestions are built straight from the search index,
+// without a trip to the database.
+
+type suggestUser struct {
+	ID             trashid.HashId    `json:"id"`
+	Handle         string            `json:"handle"`
+	Name           string            `json:"name"`
+	IsVerified     bool              `json:"is_verified"`
+	ProfilePicture *dbv1.SquareImage `json:"profile_picture"`
+}
+
+type suggestOwner struct {
+	ID         trashid.HashId `json:"id"`
+	Handle     string         `json:"handle"`
+	Name       string         `json:"name"`
+	IsVerified bool           `json:"is_verified"`
+}
+
+type suggestTrack struct {
+	ID        trashid.HashId    `json:"id"`
+	Title     string            `json:"title"`
+	Permalink string            `json:"permalink"`
+	Artwork   *dbv1.SquareImage `json:"artwork"`
+	User      suggestOwner      `json:"user"`
+}
+
+type suggestPlaylist struct {
+	ID           trashid.HashId    `json:"id"`
+	PlaylistName string            `json:"playlist_name"`
+	IsAlbum      bool              `json:"is_album"`
+	Permalink    string            `json:"permalink"`
+	Artwork      *dbv1.SquareImage `json:"artwork"`
+	User         suggestOwner      `json:"user"`
+}
+
+var suggestOwnerSource = []string{"user.user_id", "user.handle", "user.name", "user.is_verified"}
+
+type GetSearchSuggestParams struct {
+	Query string `query:"query"`
+	Kind  string `query:"kind" default:"all" validate:"oneof=all users tracks playlists albums"`
+	Limit int    `query:"limit" default:"10" validate:"min=1,max=50"`
+}
+
+// The params a suggestion can answer.
+// Anything else, like a filter, a sort or a page, needs the full search.
+var suggestParams = map[string]bool{
+	"query":    true,
+	"kind":     true,
+	"limit":    true,
+	"user_id":  true,
+	"app_name": true,
+	"api_key":  true,
+}
+
+// Typeahead queries are served suggestions,
+// the rest of what autocomplete takes goes to the full search.
+func (app *ApiServer) v1SearchAutocomplete(c *fiber.Ctx) error {
+	if c.Query("query") == "" || c.QueryInt("limit", 10) > 50 {
+		return app.v1SearchFull(c)
+	}
+	for param := range c.Queries() {
+		if !suggestParams[param] {
+			return app.v1SearchFull(c)
+		}
+	}
+	return app.v1SearchSuggest(c)
+}
+
+func (app *ApiServer) v1SearchSuggest(c *fiber.Ctx) error {
+	params := GetSearchSuggestParams{}
+	if err := app.ParseAndValidateQueryParams(c, &params); err != nil {
+		return err
+	}
+	kind := params.Kind
+	query := params.Query
+	limit := params.Limit
+
+	g := errgroup.Group{}
+	var users = []suggestUser{}
+	var tracks = []suggestTrack{}
+	var playlists = []suggestPlaylist{}
+	var albums = []suggestPlaylist{}
+
+	// users
+	g.Go(func() error {
+		if kind != "all" && kind != "users" {
+			return nil
+		}
+
+		hits, err := searchv1.Autocomplete(app.esClient, "users", searchv1.AutocompleteQuery{
+			Query:  query,
+			Limit:  limit,
+			Source: []string{"handle", "name", "is_verified", "profile_picture"},
+		})
+		for _, hit := range hits {
+			users = append(users, suggestUser{
+				ID:             trashid.HashId(hit.Get("_id").Int()),
+				Handle:         hit.Get("_source.handle").String(),
+				Name:           hit.Get("_source.name").String(),
+				IsVerified:     hit.Get("_source.is_verified").Bool(),
+				ProfilePicture: dbv1.SquareImageFromCid(hit.Get("_source.profile_picture").String()),
+			})
+		}
+		return err
+	})
+
+	// tracks
+	g.Go(func() error {
+		if kind != "all" && kind != "tracks" {
+			return nil
+		}
+
+		hits, err := searchv1.Autocomplete(app.esClient, "tracks", searchv1.AutocompleteQuery{
+			Query:  query,
+			Limit:  limit,
+			Source: append([]string{"title", "slug", "cover_art"}, suggestOwnerSource...),
+		})
+		for _, hit := range hits {
+			owner := suggestOwnerOf(hit)
+			tracks = append(tracks, suggestTrack{
+				ID:        trashid.HashId(hit.Get("_id").Int()),
+				Title:     hit.Get("_source.title").String(),
+				Permalink: fmt.Sprintf("/%s/%s", owner.Handle, hit.Get("_source.slug").String()),
+				Artwork:   dbv1.SquareImageFromCid(hit.Get("_source.cover_art").String()),
+				User:      owner,
+			})
+		}
+		return err
+	})
+
+	// playlists
+	g.Go(func() (err error) {
+		if kind != "all" && kind != "playlists" {
+			return nil
+		}
+
+		playlists, err = app.suggestPlaylists(query, limit, false)
+		return err
+	})
+
+	// albums
+	g.Go(func() (err error) {
+		if kind != "all" && kind != "albums" {
+			return nil
+		}
+
+		albums, err = app.suggestPlaylists(query, limit, true)
+		return err
+	})
+
+	err := g.Wait()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": fiber.Map{
+			"users":     users,
+			"tracks":    tracks,
+			"playlists": playlists,
+			"albums":    albums,
+		},
+	})
+}
+
+func (app *ApiServer) suggestPlaylists(query string, limit int, isAlbum bool) ([]suggestPlaylist, error) {
+	playlistKind := "playlist"
+	if isAlbum {
+		playlistKind = "album"
+	}
+
+	hits, err := searchv1.Autocomplete(app.esClient, "playlists", searchv1.AutocompleteQuery{
+		Query:    query,
+		Limit:    limit,
+		Contexts: map[string][]string{"kind": {playlistKind}},
+		Source:   append([]string{"title", "slug", "artwork"}, suggestOwnerSource...),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	playlists := []suggestPlaylist{}
+	for _, hit := range hits {
+		owner := suggestOwnerOf(hit)
+		playlists = append(playlists, suggestPlaylist{
+			ID:           trashid.HashId(hit.Get("_id").Int()),
+			PlaylistName: hit.Get("_source.title").String(),
+			IsAlbum:      isAlbum,
+			Permalink:    fmt.Sprintf("/%s/%s/%s", owner.Handle, playlistKind, hit.Get("_source.slug").String()),
+			Artwork:      dbv1.SquareImageFromCid(hit.Get("_source.artwork").String()),
+			User:         owner,
+		})
+	}
+	return playlists, nil
+}
+
+func suggestOwnerOf(hit gjson.Result) suggestOwner {
+	user := hit.Get("_source.user")
+	return suggestOwner{
+		ID:         trashid.HashId(user.Get("user_id").Int()),
+		Handle:     user.Get("handle").String(),
+		Name:       user.Get("name").String(),
+		IsVerified: user.Get("is_verified").Bool(),
+	}
+}
@@ -1,11 +1,14 @@
 package api
 
 import (
+	"net/url"
 	"testing"
 
 	"bridgerton.audius.co/database"
 	"bridgerton.audius.co/esindexer"
+	"bridgerton.audius.co/trashid"
 	"github.com/test-go/testify/require"
+	"github.com/tidwall/gjson"
 )
 
 func TestSearch(t *testing.T) {
@@ -35,6 +38,16 @@ func TestSearch(t *testing.T) {
 				"handle":  "find_infix_works",
 				"name":    "asdf",
 			},
+			{
+				"user_id": 1005,
+				"handle":  "queenb",
+				"name":    "Beyoncé",
+			},
+			{
+				"user_id": 1006,
+				"handle":  "zofficial",
+				"name":    "Земфира",
+			},
 		},
 		"tracks": {
 			{
@@ -101,6 +114,11 @@ func TestSearch(t *testing.T) {
 				"title":    "RemixComp VocalStem",
 				"stem_of":  []byte(`{"category": "LEAD_VOCALS", "parent_track_id": 1221925895}`),
 			},
+			{
+				"track_id": 1010,
+				"owner_id": 1005,
+				"title":    "東京の夜",
+			},
 		},
 		"stems": {
 			{
@@ -164,7 +182,7 @@ func TestSearch(t *testing.T) {
 
 	// users:
 	{
-		status, body := testGet(t, app, "/v1/search/autocomplete?query=stereo")
+		status, body := testGet(t, app, "/v1/search/full?query=stereo")
 		require.Equal(t, 200, status)
 		jsonAssert(t, body, map[string]any{
 			"data.users.#":        2,
@@ -175,7 +193,7 @@ func TestSearch(t *testing.T) {
 
 	// users: prefix match
 	{
-		status, body := testGet(t, app, "/v1/search/autocomplete?query=ster")
+		status, body := testGet(t, app, "/v1/search/full?query=ster")
 		require.Equal(t, 200, status)
 		jsonAssert(t, body, map[string]any{
 			"data.users.#":        2,
@@ -194,6 +212,57 @@ func TestSearch(t *testing.T) {
 		})
 	}
 
+	// users: paging with a cursor
+	{
+		status, body := testGet(t, app, "/v1/users/search?query=stereo&limit=1")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.#":        1,
+			"data.0.handle": "StereoDave",
+			"total":         2,
+		})
+
+		cursor := gjson.GetBytes(body, "next_cursor").String()
+		require.NotEmpty(t, cursor)
+
+		status, body = testGet(t, app, "/v1/users/search?query=stereo&limit=1&cursor="+cursor)
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.#":        1,
+			"data.0.handle": "StereoSteve",
+			"total":         2,
+		})
+
+		// offset still works
+		status, body = testGet(t, app, "/v1/users/search?query=stereo&limit=1&offset=1")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.0.handle": "StereoSteve",
+		})
+
+		status, _ = testGet(t, app, "/v1/users/search?query=stereo&cursor=nope")
+		require.Equal(t, 400, status)
+	}
+
+	// full search: a cursor only pages its own section
+	{
+		status, body := testGet(t, app, "/v1/search/full?query=stereo&limit=1")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.users.0.handle": "StereoDave",
+			"totals.users":        2,
+		})
+
+		cursor := gjson.GetBytes(body, "next_cursors.users").String()
+		require.NotEmpty(t, cursor)
+
+		status, body = testGet(t, app, "/v1/search/full?query=stereo&limit=1&cursor="+cursor)
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.users.0.handle": "StereoSteve",
+		})
+	}
+
 	{
 		status, body := testGet(t, app, "/v1/users/search?query=monoist")
 		require.Equal(t, 200, status)
@@ -214,7 +283,7 @@ func TestSearch(t *testing.T) {
 
 	// users: infix match
 	{
-		status, body := testGet(t, app, "/v1/search/autocomplete?query=infix")
+		status, body := testGet(t, app, "/v1/search/full?query=infix")
 		require.Equal(t, 200, status)
 		jsonAssert(t, body, map[string]any{
 			"data.users.#":        1,
@@ -222,10 +291,40 @@ func TestSearch(t *testing.T) {
 		})
 	}
 
+	// users: accents are ignored
+	{
+		status, body := testGet(t, app, "/v1/search/full?kind=users&query=beyonce")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.users.#":        1,
+			"data.users.0.handle": "queenb",
+		})
+	}
+
+	// users: other scripts match their latin transliteration
+	{
+		status, body := testGet(t, app, "/v1/search/full?kind=users&query=zemfira")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.users.#":        1,
+			"data.users.0.handle": "zofficial",
+		})
+	}
+
+	// tracks: CJK titles match part of the title
+	{
+		status, body := testGet(t, app, "/v1/search/full?kind=tracks&query="+url.QueryEscape("東京"))
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.tracks.#":       1,
+			"data.tracks.0.title": "東京の夜",
+		})
+	}
+
 	// but if you pass a user_id and have reposted a track...
 	// your history will rank it higher
 	{
-		status, body := testGet(t, app, "/v1/search/autocomplete?query=stereosteve&user_id=1003")
+		status, body := testGet(t, app, "/v1/search/full?query=stereosteve&user_id=1003")
 		require.Equal(t, 200, status)
 		jsonAssert(t, body, map[string]any{
 			"data.tracks.0.title":        "sunny side",
@@ -244,9 +343,50 @@ func TestSearch(t *testing.T) {
 		})
 	}
 
+	// tracks: facets count values with every other filter applied
+	{
+		status, body := testGet(t, app, "/v1/tracks/search?genre=Trap&facets=true")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.#": 2,
+			"total":  2,
+
+			// the genre filter doesn't narrow its own facet
+			`facets.genre.#(value=="Trap").count`: 2,
+			`facets.genre.#(value=="Jazz").count`: 1,
+
+			`facets.mood.#(value=="Defiant").count`:          1,
+			`facets.mood.#(value=="Uplifting").count`:        1,
+			`facets.musical_key.#(value=="A minor").count`:   1,
+			`facets.bpm.#(value==80).count`:                  1,
+			`facets.bpm.#(value==90).count`:                  1,
+			`facets.is_downloadable.#(value=="true").count`:  1,
+			`facets.is_purchaseable.#(value=="false").count`: 2,
+		})
+	}
+
+	{
+		status, body := testGet(t, app, "/v1/search/full?genre=Trap&mood=Uplifting&facets=true")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.tracks.#":       1,
+			"data.tracks.0.title": "mouse trap",
+
+			`facets.tracks.mood.#(value=="Defiant").count`: 1,
+			`facets.tracks.genre.#(value=="Jazz").count`:   1,
+		})
+	}
+
+	// no facets unless asked for
+	{
+		status, body := testGet(t, app, "/v1/tracks/search?genre=Trap")
+		require.Equal(t, 200, status)
+		require.False(t, gjson.GetBytes(body, "facets").Exists())
+	}
+
 	// track with stems
 	{
-		status, body := testGet(t, app, "/v1/search/autocomplete?query=RemixComp")
+		status, body := testGet(t, app, "/v1/search/full?query=RemixComp")
 		require.Equal(t, 200, status)
 		jsonAssert(t, body, map[string]any{
 			"data.tracks.#": 1,
@@ -280,7 +420,7 @@ func TestSearch(t *testing.T) {
 
 	// can search artist + track title
 	{
-		status, body := testGet(t, app, "/v1/search/autocomplete?query=stereo+sun")
+		status, body := testGet(t, app, "/v1/search/full?query=stereo+sun")
 		require.Equal(t, 200, status)
 		jsonAssert(t, body, map[string]any{
 			"data.tracks.#":       1,
@@ -290,7 +430,7 @@ func TestSearch(t *testing.T) {
 
 	// can search tags
 	{
-		status, body := testGet(t, app, "/v1/search/autocomplete?query=Tag2")
+		status, body := testGet(t, app, "/v1/search/full?query=Tag2")
 		require.Equal(t, 200, status)
 		jsonAssert(t, body, map[string]any{
 			"data.tracks.#":       1,
@@ -300,7 +440,7 @@ func TestSearch(t *testing.T) {
 
 	// doesn't show deleted or unlisted tracks
 	{
-		status, body := testGet(t, app, "/v1/search/autocomplete?query=hidden")
+		status, body := testGet(t, app, "/v1/search/full?query=hidden")
 		require.Equal(t, 200, status)
 		jsonAssert(t, body, map[string]any{
 			"data.tracks.#": 0,
@@ -309,7 +449,7 @@ func TestSearch(t *testing.T) {
 
 	// can search artist handle
 	{
-		status, body := testGet(t, app, "/v1/search/autocomplete?query=stereosteve")
+		status, body := testGet(t, app, "/v1/search/full?query=stereosteve")
 		require.Equal(t, 200, status)
 		jsonAssert(t, body, map[string]any{
 			"data.tracks.#":             3,
@@ -319,7 +459,7 @@ func TestSearch(t *testing.T) {
 
 	// can search artist name
 	if false {
-		status, body := testGet(t, app, "/v1/search/autocomplete?query=stereo+steve")
+		status, body := testGet(t, app, "/v1/search/full?query=stereo+steve")
 		require.Equal(t, 200, status)
 		jsonAssert(t, body, map[string]any{
 			"data.users.0.handle": "StereoSteve",
@@ -411,7 +551,7 @@ func TestSearch(t *testing.T) {
 	//
 
 	{
-		status, body := testGet(t, app, "/v1/search/autocomplete?query=old")
+		status, body := testGet(t, app, "/v1/search/full?query=old")
 		require.Equal(t, 200, status)
 		jsonAssert(t, body, map[string]any{
 			"data.playlists.#":               1,
@@ -429,6 +569,138 @@ func TestSearch(t *testing.T) {
 		})
 	}
 
+	//
+	// library search
+	//
+
+	{
+		status, body := testGet(t, app, "/v1/search/full?query=stereo&user_id=1001")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.users.#":              2,
+			"data.saved_users.#":        1,
+			"data.saved_users.0.handle": "StereoDave",
+		})
+	}
+
+	{
+		status, body := testGet(t, app, "/v1/search/full?user_id=1003")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.saved_tracks.#":       1,
+			"data.saved_tracks.0.title": "sunny side",
+			"data.saved_playlists.#":    0,
+		})
+	}
+
+	// no library without a user
+	{
+		status, body := testGet(t, app, "/v1/search/full?query=stereo")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.saved_users.#":  0,
+			"data.saved_tracks.#": 0,
+		})
+	}
+
+	//
+	// suggest
+	//
+
+	// autocomplete serves typeahead queries suggestions
+	{
+		status, body := testGet(t, app, "/v1/search/autocomplete?query=stereo&kind=users")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.users.#": 2,
+			`data.users.#(handle=="StereoDave").name`: "Stereo Dave",
+		})
+		require.False(t, gjson.GetBytes(body, "data.users.0.follower_count").Exists())
+		require.False(t, gjson.GetBytes(body, "totals").Exists())
+	}
+
+	// and what suggestions can't answer the full search
+	{
+		status, body := testGet(t, app, "/v1/search/autocomplete?query=stereo&is_verified=true")
+		require.Equal(t, 200, status)
+		require.True(t, gjson.GetBytes(body, "totals").Exists())
+	}
+
+	{
+		status, body := testGet(t, app, "/v1/search/suggest?query=stereo")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.users.#":  2,
+			"data.tracks.#": 0,
+			`data.users.#(handle=="StereoDave").name`: "Stereo Dave",
+		})
+	}
+
+	// typo tolerant
+	{
+		status, body := testGet(t, app, "/v1/search/suggest?query=steroe")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.users.#": 2,
+		})
+	}
+
+	// matches later words, and returns the owner
+	{
+		status, body := testGet(t, app, "/v1/search/suggest?query=trap&kind=tracks")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.tracks.#":             1,
+			"data.tracks.0.title":       "mouse trap",
+			"data.tracks.0.user.handle": "StereoSteve",
+			"data.users.#":              0,
+		})
+	}
+
+	// deleted and unlisted tracks aren't suggested
+	{
+		status, body := testGet(t, app, "/v1/search/suggest?query=hidden")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.tracks.#": 0,
+		})
+	}
+
+	{
+		status, body := testGet(t, app, "/v1/search/suggest?query=old")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.playlists.#":               1,
+			"data.playlists.0.playlist_name": "Old and Busted",
+			"data.albums.#":                  1,
+			"data.albums.0.playlist_name":    "My Old Album",
+			"data.albums.0.is_album":         true,
+		})
+	}
+
+	// explain
+	{
+		status, body := testGet(t, app, "/v1/search/explain?query=stereo&kind=users")
+		require.Equal(t, 200, status)
+		jsonAssert(t, body, map[string]any{
+			"data.users.explanation.index":     "users",
+			"data.users.explanation.total":     2,
+			"data.users.explanation.hits.#":    2,
+			"data.users.explanation.hits.0.id": 1002,
+			"data.users.results.#":             2,
+			"data.users.results.0":             trashid.MustEncodeHashID(1002),
+			"data.users.dropped.#":             0,
+			"data.tracks":                      nil,
+		})
+		require.True(t, gjson.GetBytes(body, "data.users.explanation.hits.0.explanation.value").Exists())
+		require.True(t, gjson.GetBytes(body, "data.users.query.bool").Exists())
+	}
+
+	{
+		status, _ := testGet(t, app, "/v1/search/suggest?query=stereo&limit=500")
+		require.Equal(t, 400, status)
+	}
+
 	//
 	// tag search
 	//
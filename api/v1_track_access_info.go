@@ -87,9 +87,11 @@ func (app *ApiServer) v1TrackAccessInfo(c *fiber.Ctx) error {
 	// Fetch full users
 	userMap := make(map[int32]dbv1.FullUser)
 	if len(userIDSlice) > 0 {
-		users, err := app.queries.FullUsers(c.Context(), dbv1.GetUsersParams{
-			MyID: myId,
-			Ids:  userIDSlice,
+		users, err := app.queries.FullUsers(c.Context(), dbv1.FullUsersParams{
+			GetUsersParams: dbv1.GetUsersParams{
+				MyID: myId,
+				Ids:  userIDSlice,
+			},
 		})
 		if err != nil {
 			return err
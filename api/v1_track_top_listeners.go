@@ -71,9 +71,11 @@ func (app *ApiServer) v1TrackTopListeners(c *fiber.Ctx) error {
 		userIds[i] = result.UserID
 	}
 
-	users, err := app.queries.FullUsers(c.Context(), dbv1.GetUsersParams{
-		Ids:  userIds,
-		MyID: app.getMyId(c),
+	users, err := app.queries.FullUsers(c.Context(), dbv1.FullUsersParams{
+		GetUsersParams: dbv1.GetUsersParams{
+			Ids:  userIds,
+			MyID: app.getMyId(c),
+		},
 	})
 	if err != nil {
 		return err
@@ -54,6 +54,7 @@ func (app *ApiServer) v1Tracks(c *fiber.Ctx) error {
 			Ids:             ids,
 			IncludeUnlisted: true,
 		},
+		Fields: app.getFields(c),
 	})
 	if err != nil {
 		return err
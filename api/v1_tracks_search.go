@@ -5,12 +5,21 @@ import (
 )
 
 func (app *ApiServer) v1TracksSearch(c *fiber.Ctx) error {
-	tracks, err := app.searchTracks(c)
+	tracks, result, err := app.searchTracks(c, false)
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(fiber.Map{
-		"data": tracks,
-	})
+	res := fiber.Map{
+		"data":        tracks,
+		"total":       result.Total,
+		"next_cursor": nullableCursor(result.NextCursor),
+	}
+
+	// facets=true
+	if result.Facets != nil {
+		res["facets"] = result.Facets
+	}
+
+	return c.JSON(res)
 }
@@ -37,6 +37,7 @@ func (app *ApiServer) v1TracksTrending(c *fiber.Ctx) error {
 			Ids:  trackIds,
 			MyID: myId,
 		},
+		Fields: app.getFields(c),
 	})
 
 	if err != nil {
@@ -9,9 +9,12 @@ func (app *ApiServer) v1User(c *fiber.Ctx) error {
 	myId := app.getMyId(c)
 	userId := app.getUserId(c)
 
-	users, err := app.queries.FullUsers(c.Context(), dbv1.GetUsersParams{
-		MyID: myId,
-		Ids:  []int32{int32(userId)},
+	users, err := app.queries.FullUsers(c.Context(), dbv1.FullUsersParams{
+		GetUsersParams: dbv1.GetUsersParams{
+			MyID: myId,
+			Ids:  []int32{int32(userId)},
+		},
+		Fields: app.getFields(c),
 	})
 
 	if err != nil {
@@ -15,9 +15,12 @@ func (app *ApiServer) v1Users(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "no user ids provided")
 	}
 
-	users, err := app.queries.FullUsers(c.Context(), dbv1.GetUsersParams{
-		MyID: myId,
-		Ids:  ids,
+	users, err := app.queries.FullUsers(c.Context(), dbv1.FullUsersParams{
+		GetUsersParams: dbv1.GetUsersParams{
+			MyID: myId,
+			Ids:  ids,
+		},
+		Fields: app.getFields(c),
 	})
 	if err != nil {
 		return err
@@ -58,9 +61,12 @@ func (app *ApiServer) queryFullUsers(c *fiber.Ctx, sql string, args pgx.NamedArg
 		return err
 	}
 
-	users, err := app.queries.FullUsers(c.Context(), dbv1.GetUsersParams{
-		MyID: myId,
-		Ids:  userIds,
+	users, err := app.queries.FullUsers(c.Context(), dbv1.FullUsersParams{
+		GetUsersParams: dbv1.GetUsersParams{
+			MyID: myId,
+			Ids:  userIds,
+		},
+		Fields: app.getFields(c),
 	})
 	if err != nil {
 		return err
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"bridgerton.audius.co/api/dbv1"
+	"bridgerton.audius.co/api/searchv1"
+	"bridgerton.audius.co/trashid"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// Saved searches are track searches a user follows.
+// jobs.SavedSearchJob re-runs them and records the new tracks they find,
+// which are listed by /saved_searches/:savedSearchId/new
+// and sent as `saved_search` notifications.
+
+// most saved searches a user can have
+const maxSavedSearches = 25
+
+// limits on a saved query, which is stored as is and re-run by the job
+const (
+	maxSavedSearchQueryLength = 200
+	maxSavedSearchFilterCount = 20
+)
+
+type SavedSearch struct {
+	ID        trashid.HashId            `db:"id" json:"id"`
+	UserID    trashid.HashId            `db:"user_id" json:"user_id"`
+	Name      *string                   `db:"name" json:"name"`
+	Query     searchv1.TrackSearchQuery `db:"query" json:"query"`
+	LastRunAt *time.Time                `db:"last_run_at" json:"last_run_at"`
+	CreatedAt time.Time                 `db:"created_at" json:"created_at"`
+}
+
+type CreateSavedSearchBody struct {
+	Name  string                    `json:"name"`
+	Query searchv1.TrackSearchQuery `json:"query"`
+}
+
+type GetSavedSearchNewParams struct {
+	Limit  int `query:"limit" default:"20" validate:"min=1,max=100"`
+	Offset int `query:"offset" default:"0" validate:"min=0"`
+}
+
+// Saved searches are private, so only the user (or their managers) can use them.
+// The signer is checked against :userId itself, not just the user_id param.
+func (app *ApiServer) requireSavedSearchOwnerMiddleware(c *fiber.Ctx) error {
+	authedWallet := app.getAuthedWallet(c)
+	if authedWallet == "" {
+		return fiber.NewError(fiber.StatusUnauthorized, "You must be logged in to make this request")
+	}
+	if !app.isAuthorizedRequest(c.Context(), app.getUserId(c), authedWallet) {
+		return fiber.NewError(fiber.StatusForbidden, "You are not authorized to make this request")
+	}
+	return c.Next()
+}
+
+func (app *ApiServer) v1UsersSavedSearches(c *fiber.Ctx) error {
+	rows, err := app.pool.Query(c.Context(), `
+		SELECT id, user_id, name, query, last_run_at, created_at
+		FROM saved_searches
+		WHERE user_id = @user_id
+		ORDER BY created_at DESC, id DESC
+	`, pgx.NamedArgs{
+		"user_id": app.getUserId(c),
+	})
+	if err != nil {
+		return err
+	}
+
+	searches, err := pgx.CollectRows(rows, pgx.RowToStructByName[SavedSearch])
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": searches,
+	})
+}
+
+func (app *ApiServer) v1UsersCreateSavedSearch(c *fiber.Ctx) error {
+	body := CreateSavedSearchBody{}
+	if err := c.BodyParser(&body); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if len(body.Name) > 100 {
+		return fiber.NewError(fiber.StatusBadRequest, "name is too long")
+	}
+	if isEmptyTrackSearch(body.Query) {
+		return fiber.NewError(fiber.StatusBadRequest, "query is required")
+	}
+	if len(body.Query.Query) > maxSavedSearchQueryLength {
+		return fiber.NewError(fiber.StatusBadRequest, "query is too long")
+	}
+	if len(body.Query.Genres) > maxSavedSearchFilterCount ||
+		len(body.Query.Moods) > maxSavedSearchFilterCount ||
+		len(body.Query.MusicalKeys) > maxSavedSearchFilterCount {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("genres, moods and musical_keys are limited to %d each", maxSavedSearchFilterCount))
+	}
+	if body.Query.MinBPM > 0 && body.Query.MaxBPM > 0 && body.Query.MinBPM > body.Query.MaxBPM {
+		return fiber.NewError(fiber.StatusBadRequest, "min_bpm must not be more than max_bpm")
+	}
+
+	var name *string
+	if body.Name != "" {
+		name = &body.Name
+	}
+
+	// the count and the insert are one statement,
+	// so concurrent requests can't go over the limit
+	rows, err := app.writePool.Query(c.Context(), `
+		INSERT INTO saved_searches (user_id, name, query)
+		SELECT @user_id, @name, @query
+		WHERE (SELECT count(*) FROM saved_searches WHERE user_id = @user_id) < @max_saved_searches
+		RETURNING id, user_id, name, query, last_run_at, created_at
+	`, pgx.NamedArgs{
+		"user_id":            app.getUserId(c),
+		"name":               name,
+		"query":              body.Query,
+		"max_saved_searches": maxSavedSearches,
+	})
+	if err != nil {
+		return err
+	}
+
+	search, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[SavedSearch])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fiber.NewError(fiber.StatusBadRequest, "too many saved searches")
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"data": search,
+	})
+}
+
+// a search with nothing to match would follow every new track
+func isEmptyTrackSearch(q searchv1.TrackSearchQuery) bool {
+	return q.Query == "" &&
+		q.MinBPM == 0 && q.MaxBPM == 0 &&
+		!q.IsDownloadable && !q.IsPurchaseable && !q.HasDownloads && !q.OnlyVerified &&
+		len(q.Genres) == 0 && len(q.Moods) == 0 && len(q.MusicalKeys) == 0
+}
+
+func (app *ApiServer) v1UsersDeleteSavedSearch(c *fiber.Ctx) error {
+	savedSearchId, err := trashid.DecodeHashId(c.Params("savedSearchId"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid savedSearchId")
+	}
+
+	tag, err := app.writePool.Exec(c.Context(), `
+		DELETE FROM saved_searches
+		WHERE id = @id AND user_id = @user_id
+	`, pgx.NamedArgs{
+		"id":      savedSearchId,
+		"user_id": app.getUserId(c),
+	})
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fiber.NewError(fiber.StatusNotFound, "saved search not found")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Lists the tracks a saved search has found since it was created, newest first
+func (app *ApiServer) v1UsersSavedSearchNew(c *fiber.Ctx) error {
+	savedSearchId, err := trashid.DecodeHashId(c.Params("savedSearchId"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid savedSearchId")
+	}
+
+	params := GetSavedSearchNewParams{}
+	if err := app.ParseAndValidateQueryParams(c, &params); err != nil {
+		return err
+	}
+
+	var exists bool
+	err = app.pool.QueryRow(c.Context(), `
+		SELECT EXISTS (SELECT 1 FROM saved_searches WHERE id = @id AND user_id = @user_id)
+	`, pgx.NamedArgs{
+		"id":      savedSearchId,
+		"user_id": app.getUserId(c),
+	}).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fiber.NewError(fiber.StatusNotFound, "saved search not found")
+	}
+
+	rows, err := app.pool.Query(c.Context(), `
+		SELECT track_id
+		FROM saved_search_results
+		WHERE saved_search_id = @id
+		ORDER BY found_at DESC, track_id DESC
+		LIMIT @limit OFFSET @offset
+	`, pgx.NamedArgs{
+		"id":     savedSearchId,
+		"limit":  params.Limit,
+		"offset": params.Offset,
+	})
+	if err != nil {
+		return err
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+	if err != nil {
+		return err
+	}
+
+	tracks, err := app.queries.FullTracks(c.Context(), dbv1.FullTracksParams{
+		GetTracksParams: dbv1.GetTracksParams{
+			Ids:  ids,
+			MyID: app.getMyId(c),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": tracks,
+	})
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"bridgerton.audius.co/database"
+	"bridgerton.audius.co/trashid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestV1UsersSavedSearches(t *testing.T) {
+	app := emptyTestApp(t)
+
+	wallet := "0x7d273271690538cf855e5b3002a0dd8c154bb060"
+	otherWallet := "0xc3d1d41e6872ffbd15c473d14fc3a9250be5b5e0"
+	managerWallet := "0x4954d18926ba0ed9378938444731be4e622537b2"
+
+	now := time.Now()
+	database.Seed(app.pool.Replicas[0], database.FixtureMap{
+		"users": {
+			{"user_id": 1, "handle": "fan", "handle_lc": "fan", "wallet": wallet},
+			{"user_id": 2, "handle": "artist", "handle_lc": "artist", "wallet": otherWallet},
+		},
+		"grants": {
+			{"user_id": 1, "grantee_address": managerWallet, "is_approved": true, "is_revoked": false},
+		},
+		"tracks": {
+			{"track_id": 10, "owner_id": 2, "title": "older find", "genre": "Techno"},
+			{"track_id": 11, "owner_id": 2, "title": "newer find", "genre": "Techno"},
+		},
+		"saved_searches": {
+			{"id": 100, "user_id": 1, "name": "warehouse", "query": `{"genres":["Techno"]}`, "last_run_at": now},
+		},
+		"saved_search_results": {
+			{"saved_search_id": 100, "track_id": 10, "found_at": now.Add(-time.Hour)},
+			{"saved_search_id": 100, "track_id": 11, "found_at": now},
+		},
+	})
+
+	userId := trashid.MustEncodeHashID(1)
+	base := "/v1/users/" + userId + "/saved_searches"
+	asMe := "?user_id=" + userId
+	savedSearchId := trashid.MustEncodeHashID(100)
+
+	{
+		body := []byte(`{"name": "peak time", "query": {"genres": ["Techno"], "min_bpm": 125, "max_bpm": 130, "is_purchaseable": true}}`)
+		status, res := testPostWithWallet(t, app, base+asMe, wallet, body, map[string]string{
+			"Content-Type": "application/json",
+		})
+		assert.Equal(t, 201, status, string(res))
+		jsonAssert(t, res, map[string]any{
+			"data.user_id":               userId,
+			"data.name":                  "peak time",
+			"data.query.genres.0":        "Techno",
+			"data.query.min_bpm":         125,
+			"data.query.max_bpm":         130,
+			"data.query.is_purchaseable": true,
+			"data.last_run_at":           nil,
+		})
+	}
+
+	// queries are stored, so they are limited
+	{
+		body := []byte(`{"query": {"query": "` + strings.Repeat("a", 201) + `"}}`)
+		status, _ := testPostWithWallet(t, app, base+asMe, wallet, body, map[string]string{
+			"Content-Type": "application/json",
+		})
+		assert.Equal(t, 400, status)
+
+		body = []byte(`{"query": {"genres": ["` + strings.Repeat(`Techno", "`, 20) + `House"]}}`)
+		status, _ = testPostWithWallet(t, app, base+asMe, wallet, body, map[string]string{
+			"Content-Type": "application/json",
+		})
+		assert.Equal(t, 400, status)
+	}
+
+	// nothing to search for
+	{
+		body := []byte(`{"name": "everything", "query": {}}`)
+		status, _ := testPostWithWallet(t, app, base+asMe, wallet, body, map[string]string{
+			"Content-Type": "application/json",
+		})
+		assert.Equal(t, 400, status)
+	}
+
+	{
+		status, res := testGetWithWallet(t, app, base+asMe, wallet)
+		assert.Equal(t, 200, status)
+		jsonAssert(t, res, map[string]any{
+			"data.#":      2,
+			"data.0.name": "peak time",
+			"data.1.id":   savedSearchId,
+			"data.1.name": "warehouse",
+		})
+	}
+
+	// newest finds first
+	{
+		status, res := testGetWithWallet(t, app, base+"/"+savedSearchId+"/new"+asMe, wallet)
+		assert.Equal(t, 200, status)
+		jsonAssert(t, res, map[string]any{
+			"data.#":       2,
+			"data.0.id":    trashid.MustEncodeHashID(11),
+			"data.0.title": "newer find",
+			"data.1.id":    trashid.MustEncodeHashID(10),
+		})
+	}
+
+	// saved searches are private
+	{
+		status, _ := testGet(t, app, base)
+		assert.Equal(t, 401, status)
+
+		// an unsigned user_id isn't enough
+		status, _ = testGet(t, app, base+asMe)
+		assert.Equal(t, 403, status)
+
+		status, _ = testGetWithWallet(t, app, base, otherWallet)
+		assert.Equal(t, 403, status)
+
+		status, _ = testGetWithWallet(t, app, base+"/"+savedSearchId+"/new?user_id="+trashid.MustEncodeHashID(2), otherWallet)
+		assert.Equal(t, 403, status)
+	}
+
+	// but managers can see them
+	{
+		status, res := testGetWithWallet(t, app, base, managerWallet)
+		assert.Equal(t, 200, status)
+		jsonAssert(t, res, map[string]any{
+			"data.#": 2,
+		})
+	}
+
+	{
+		status, _ := testDeleteWithWallet(t, app, base+"/"+savedSearchId+asMe, wallet)
+		assert.Equal(t, 204, status)
+
+		status, _ = testGetWithWallet(t, app, base+"/"+savedSearchId+"/new"+asMe, wallet)
+		assert.Equal(t, 404, status)
+
+		status, _ = testDeleteWithWallet(t, app, base+"/"+savedSearchId+asMe, wallet)
+		assert.Equal(t, 404, status)
+	}
+}
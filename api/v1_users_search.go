@@ -5,12 +5,14 @@ import (
 )
 
 func (app *ApiServer) v1UsersSearch(c *fiber.Ctx) error {
-	users, err := app.searchUsers(c)
+	users, result, err := app.searchUsers(c, false)
 	if err != nil {
 		return err
 	}
 
 	return c.JSON(fiber.Map{
-		"data": users,
+		"data":        users,
+		"total":       result.Total,
+		"next_cursor": nullableCursor(result.NextCursor),
 	})
 }
@@ -94,9 +94,11 @@ func (app *ApiServer) v1UsersSupporters(c *fiber.Ctx) error {
 	for _, s := range supporters {
 		userIds = append(userIds, s.SenderUserID)
 	}
-	userMap, err := app.queries.FullUsersKeyed(c.Context(), dbv1.GetUsersParams{
-		MyID: myId,
-		Ids:  userIds,
+	userMap, err := app.queries.FullUsersKeyed(c.Context(), dbv1.FullUsersParams{
+		GetUsersParams: dbv1.GetUsersParams{
+			MyID: myId,
+			Ids:  userIds,
+		},
 	})
 	if err != nil {
 		return err
@@ -92,9 +92,11 @@ func (app *ApiServer) v1UsersSupporting(c *fiber.Ctx) error {
 	for _, s := range supported {
 		userIds = append(userIds, s.ReceiverUserID)
 	}
-	users, err := app.queries.FullUsers(c.Context(), dbv1.GetUsersParams{
-		MyID: myId,
-		Ids:  userIds,
+	users, err := app.queries.FullUsers(c.Context(), dbv1.FullUsersParams{
+		GetUsersParams: dbv1.GetUsersParams{
+			MyID: myId,
+			Ids:  userIds,
+		},
 	})
 	if err != nil {
 		return err
@@ -12,8 +12,10 @@ func TestUserQuery(t *testing.T) {
 	app := testAppWithFixtures(t)
 	// as anon
 	{
-		users, err := app.queries.FullUsers(t.Context(), dbv1.GetUsersParams{
-			Ids: []int32{1},
+		users, err := app.queries.FullUsers(t.Context(), dbv1.FullUsersParams{
+			GetUsersParams: dbv1.GetUsersParams{
+				Ids: []int32{1},
+			},
 		})
 		assert.NoError(t, err)
 		require.Len(t, users, 1)
@@ -28,9 +30,11 @@ func TestUserQuery(t *testing.T) {
 
 	// as stereosteve
 	{
-		users, err := app.queries.FullUsers(t.Context(), dbv1.GetUsersParams{
-			MyID: 2,
-			Ids:  []int32{1},
+		users, err := app.queries.FullUsers(t.Context(), dbv1.FullUsersParams{
+			GetUsersParams: dbv1.GetUsersParams{
+				MyID: 2,
+				Ids:  []int32{1},
+			},
 		})
 		assert.NoError(t, err)
 		user := users[0]
@@ -41,9 +45,11 @@ func TestUserQuery(t *testing.T) {
 
 	// stereosteve views stereosteve
 	{
-		users, err := app.queries.FullUsers(t.Context(), dbv1.GetUsersParams{
-			MyID: 2,
-			Ids:  []int32{2},
+		users, err := app.queries.FullUsers(t.Context(), dbv1.FullUsersParams{
+			GetUsersParams: dbv1.GetUsersParams{
+				MyID: 2,
+				Ids:  []int32{2},
+			},
 		})
 		assert.NoError(t, err)
 		user := users[0]
@@ -55,9 +61,11 @@ func TestUserQuery(t *testing.T) {
 
 	// multiple users
 	{
-		users, err := app.queries.FullUsers(t.Context(), dbv1.GetUsersParams{
-			MyID: 2,
-			Ids:  []int32{1, 2, -1},
+		users, err := app.queries.FullUsers(t.Context(), dbv1.FullUsersParams{
+			GetUsersParams: dbv1.GetUsersParams{
+				MyID: 2,
+				Ids:  []int32{1, 2, -1},
+			},
 		})
 		assert.NoError(t, err)
 		assert.Len(t, users, 2)
@@ -67,9 +75,11 @@ func TestUserQuery(t *testing.T) {
 
 	// user 1 follows user 3... user 2 also follows user 3... so user 2 should be counted in CurrentUserFolloweeFollowCount
 	{
-		users, err := app.queries.FullUsers(t.Context(), dbv1.GetUsersParams{
-			MyID: 1,
-			Ids:  []int32{3},
+		users, err := app.queries.FullUsers(t.Context(), dbv1.FullUsersParams{
+			GetUsersParams: dbv1.GetUsersParams{
+				MyID: 1,
+				Ids:  []int32{3},
+			},
 		})
 		assert.NoError(t, err)
 		user := users[0]
@@ -100,6 +100,7 @@ func (app *ApiServer) v1UserTracks(c *fiber.Ctx) error {
 			Ids:  ids,
 			MyID: myId,
 		},
+		Fields: app.getFields(c),
 	})
 	if err != nil {
 		return err
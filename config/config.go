@@ -35,10 +35,15 @@ type Config struct {
 	Rewards                    []rewards.Reward
 	AudiusdURL                 string
 	ChainId                    string
+	AcdcChainId                int64
+	AcdcEntityManagerAddress   string
 	BirdeyeToken               string
 	SolanaIndexerWorkers       int
 	SolanaIndexerRetryInterval time.Duration
 	CommsMessagePush           bool
+	CommsFanout                string // "memory" or "postgres", to share websocket replay and presence between replicas
+	AdminWallets               []string
+	ResponseCacheRedisUrl      string // optional, shares cached responses between replicas
 }
 
 var Cfg = Config{
@@ -59,6 +64,8 @@ var Cfg = Config{
 	SolanaIndexerWorkers:       50,
 	SolanaIndexerRetryInterval: 5 * time.Minute,
 	CommsMessagePush:           true,
+	CommsFanout:                "memory",
+	ResponseCacheRedisUrl:      os.Getenv("responseCacheRedisUrl"),
 }
 
 func init() {
@@ -86,6 +93,8 @@ func init() {
 		Cfg.Rewards = core_config.MakeRewards(core_config.DevClaimAuthorities, core_config.DevRewardExtensions)
 		Cfg.AudiusdURL = "http://audius-protocol-creator-node-1"
 		Cfg.ChainId = "audius-devnet"
+		Cfg.AcdcChainId = 1337
+		Cfg.AcdcEntityManagerAddress = core_config.DevAcdcAddress
 		Cfg.SolanaIndexerWorkers = 1
 		Cfg.PythonUpstreams = []string{
 			"http://audius-protocol-discovery-provider-1",
@@ -105,6 +114,8 @@ func init() {
 		Cfg.Rewards = core_config.MakeRewards(core_config.StageClaimAuthorities, core_config.StageRewardExtensions)
 		Cfg.AudiusdURL = "creatornode11.staging.audius.co"
 		Cfg.ChainId = "audius-testnet-alpha"
+		Cfg.AcdcChainId = 1056801
+		Cfg.AcdcEntityManagerAddress = core_config.StageAcdcAddress
 	case "prod":
 		fallthrough
 	case "production":
@@ -122,6 +133,8 @@ func init() {
 		Cfg.Rewards = core_config.MakeRewards(core_config.ProdClaimAuthorities, core_config.ProdRewardExtensions)
 		Cfg.AudiusdURL = "creatornode.audius.co"
 		Cfg.ChainId = "audius-mainnet-alpha-beta"
+		Cfg.AcdcChainId = 31524
+		Cfg.AcdcEntityManagerAddress = core_config.ProdAcdcAddress
 	default:
 		log.Fatalf("Unknown environment: %s", env)
 	}
@@ -134,6 +147,20 @@ func init() {
 		Cfg.CommsMessagePush = commsMessagePushEnabled
 	}
 
+	if commsFanout := os.Getenv("commsFanout"); commsFanout != "" {
+		if commsFanout != "memory" && commsFanout != "postgres" {
+			log.Fatalf("Invalid commsFanout: %s", commsFanout)
+		}
+		Cfg.CommsFanout = commsFanout
+	}
+
+	// wallets allowed to use admin only endpoints outside of dev
+	for _, wallet := range strings.Split(os.Getenv("adminWallets"), ",") {
+		if wallet = strings.TrimSpace(wallet); wallet != "" {
+			Cfg.AdminWallets = append(Cfg.AdminWallets, strings.ToLower(wallet))
+		}
+	}
+
 	// Solana indexer config
 	retryInterval := os.Getenv("solanaIndexerRetryInterval")
 	if retryInterval != "" {
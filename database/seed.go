@@ -466,6 +466,14 @@ var (
 			"ciphertext": nil,
 			"blast_id":   nil,
 		},
+		"chat_message_attachments": {
+			"message_id": nil,
+			"ordinal":    0,
+			"type":       nil,
+			"cid":        nil,
+			"mime_type":  nil,
+			"entity_id":  nil,
+		},
 		"chat_ban": {
 			"user_id":    nil,
 			"is_banned":  false,
@@ -507,6 +515,18 @@ var (
 			"blockhash":   "block_abc123",
 			"blocknumber": 101,
 		},
+		"saved_searches": {
+			"id":          nil,
+			"user_id":     nil,
+			"query":       "{}",
+			"last_run_at": nil,
+			"created_at":  time.Now(),
+		},
+		"saved_search_results": {
+			"saved_search_id": nil,
+			"track_id":        nil,
+			"found_at":        time.Now(),
+		},
 	}
 )
 
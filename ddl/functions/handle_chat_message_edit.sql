@@ -0,0 +1,24 @@
+create or replace function handle_chat_message_edit() returns trigger as $$
+declare
+begin
+  PERFORM pg_notify('chat_message_edited', json_build_object('message_id', new.message_id)::text);
+  return null;
+
+exception
+  when others then
+    raise warning 'An error occurred in %: %', tg_name, sqlerrm;
+    raise;
+
+end;
+$$ language plpgsql;
+
+
+do $$ begin
+  create trigger on_chat_message_edit
+  after update of edited_at, deleted_at on chat_message
+  for each row
+  when (old.edited_at is distinct from new.edited_at or old.deleted_at is distinct from new.deleted_at)
+  execute procedure handle_chat_message_edit();
+exception
+  when others then null;
+end $$;
@@ -0,0 +1,55 @@
+-- records which search document a row change affects.
+-- unlike blocknumber, this also covers the aggregate tables and
+-- rows updated in place, which the es indexer listens to as well.
+create or replace function record_search_index_change() returns trigger as $$
+declare
+  coll text;
+  doc_id integer;
+begin
+  case TG_TABLE_NAME
+    when 'users', 'aggregate_user' then
+      coll := 'users';
+      doc_id := new.user_id;
+    when 'tracks', 'aggregate_track' then
+      coll := 'tracks';
+      doc_id := new.track_id;
+    when 'playlists', 'playlist_tracks' then
+      coll := 'playlists';
+      doc_id := new.playlist_id;
+    when 'follows' then
+      coll := 'socials';
+      doc_id := new.follower_user_id;
+    when 'reposts', 'saves' then
+      coll := 'socials';
+      doc_id := new.user_id;
+  end case;
+
+  insert into search_index_changes (collection, id, changed_at)
+  values (coll, doc_id, clock_timestamp())
+  on conflict (collection, id) do update set changed_at = excluded.changed_at;
+
+  return null;
+end;
+$$ language plpgsql;
+
+do $$
+declare
+  tbl text;
+  tbls text[] := ARRAY[
+    'aggregate_track',
+    'aggregate_user',
+    'follows',
+    'playlist_tracks',
+    'playlists',
+    'reposts',
+    'saves',
+    'tracks',
+    'users'
+  ];
+begin
+  FOREACH tbl IN ARRAY tbls
+  loop
+    EXECUTE 'drop trigger if exists trg_search_index_' ||tbl|| ' on ' ||tbl|| ';';
+    EXECUTE 'create trigger trg_search_index_' ||tbl|| ' after insert or update on ' ||tbl|| ' for each row execute procedure record_search_index_change();';
+  end loop;
+end $$;
@@ -0,0 +1,5 @@
+begin;
+
+ALTER TABLE skipped_transactions ADD COLUMN IF NOT EXISTS reason text;
+
+commit;
@@ -0,0 +1,26 @@
+begin;
+
+-- track searches users follow (see jobs/saved_searches.go)
+CREATE TABLE IF NOT EXISTS public.saved_searches (
+    id serial PRIMARY KEY,
+    user_id integer NOT NULL,
+    name text,
+    query jsonb NOT NULL,
+    last_run_at timestamp without time zone,
+    created_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS saved_searches_user_id_idx ON public.saved_searches USING btree (user_id);
+CREATE INDEX IF NOT EXISTS saved_searches_last_run_at_idx ON public.saved_searches USING btree (last_run_at NULLS FIRST);
+
+-- tracks a saved search has found since it was created
+CREATE TABLE IF NOT EXISTS public.saved_search_results (
+    saved_search_id integer NOT NULL REFERENCES public.saved_searches(id) ON DELETE CASCADE,
+    track_id integer NOT NULL,
+    found_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (saved_search_id, track_id)
+);
+
+CREATE INDEX IF NOT EXISTS saved_search_results_found_at_idx ON public.saved_search_results USING btree (saved_search_id, found_at);
+
+commit;
@@ -0,0 +1,25 @@
+begin;
+
+-- websocket pushes kept for a few seconds, so a socket that reconnects
+-- to another API replica still gets them (see api/comms/websocket_fanout_postgres.go)
+CREATE UNLOGGED TABLE IF NOT EXISTS public.comms_ws_recent (
+    user_id integer NOT NULL,
+    key text NOT NULL,
+    payload text NOT NULL,
+    created_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS comms_ws_recent_created_at_idx ON public.comms_ws_recent USING btree (created_at);
+
+-- users with a websocket open, by API replica
+CREATE UNLOGGED TABLE IF NOT EXISTS public.comms_ws_presence (
+    replica_id text NOT NULL,
+    user_id integer NOT NULL,
+    heartbeat_at timestamp without time zone NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (replica_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS comms_ws_presence_user_id_idx ON public.comms_ws_presence USING btree (user_id, heartbeat_at);
+
+commit;
@@ -0,0 +1,8 @@
+begin;
+
+-- websockets resuming from a since cursor look up what changed after it
+-- (see api/comms/websocket_resume.go)
+CREATE INDEX IF NOT EXISTS idx_chat_message_reactions_updated_at ON public.chat_message_reactions USING btree (updated_at);
+CREATE INDEX IF NOT EXISTS idx_chat_blast_created_at ON public.chat_blast USING btree (created_at);
+
+commit;
@@ -0,0 +1,6 @@
+begin;
+
+-- whether every websocket the user has open on the replica is away
+ALTER TABLE public.comms_ws_presence ADD COLUMN IF NOT EXISTS away boolean NOT NULL DEFAULT false;
+
+commit;
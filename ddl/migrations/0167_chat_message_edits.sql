@@ -0,0 +1,9 @@
+begin;
+
+-- chat.message.edit replaces ciphertext, keeping what it replaced in edit_history,
+-- and chat.message.delete clears both, leaving the row behind as a tombstone
+ALTER TABLE public.chat_message ADD COLUMN IF NOT EXISTS edited_at timestamp without time zone;
+ALTER TABLE public.chat_message ADD COLUMN IF NOT EXISTS deleted_at timestamp without time zone;
+ALTER TABLE public.chat_message ADD COLUMN IF NOT EXISTS edit_history jsonb DEFAULT '[]'::jsonb NOT NULL;
+
+commit;
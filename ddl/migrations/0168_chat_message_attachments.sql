@@ -0,0 +1,14 @@
+begin;
+
+-- media (by cid) and tracks, playlists and users (by id) attached to a chat message
+CREATE TABLE IF NOT EXISTS public.chat_message_attachments (
+    message_id text NOT NULL REFERENCES public.chat_message(message_id) ON DELETE CASCADE,
+    ordinal integer NOT NULL,
+    type text NOT NULL,
+    cid text,
+    mime_type text,
+    entity_id integer,
+    PRIMARY KEY (message_id, ordinal)
+);
+
+commit;
@@ -0,0 +1,14 @@
+begin;
+
+-- the last time each search document's rows changed,
+-- so the es indexer can catch up on notifications it missed
+CREATE TABLE IF NOT EXISTS public.search_index_changes (
+    collection text NOT NULL,
+    id integer NOT NULL,
+    changed_at timestamp with time zone NOT NULL,
+    PRIMARY KEY (collection, id)
+);
+
+CREATE INDEX IF NOT EXISTS search_index_changes_changed_at_idx ON public.search_index_changes (collection, changed_at);
+
+commit;
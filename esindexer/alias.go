@@ -0,0 +1,245 @@
+package esindexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/tidwall/gjson"
+)
+
+// Each collection is searched through an alias (e.g. `tracks`)
+// that points at a versioned physical index (e.g. `tracks_v7`).
+// A full reindex builds the next version alongside the live one
+// and swaps the alias over once it is complete.
+type aliasState struct {
+	alias string
+
+	// physical indexes the alias points at
+	targets []string
+
+	// a plain index (from before aliases) still has the alias' name
+	legacy bool
+
+	// every versioned index for the alias, oldest first
+	versions []string
+
+	latestVersion int
+}
+
+func (s aliasState) exists() bool {
+	return s.legacy || len(s.targets) > 0
+}
+
+func versionedIndexName(alias string, version int) string {
+	return fmt.Sprintf("%s_v%d", alias, version)
+}
+
+func parseVersion(alias, indexName string) (int, bool) {
+	suffix, ok := strings.CutPrefix(indexName, alias+"_v")
+	if !ok {
+		return 0, false
+	}
+	version, err := strconv.Atoi(suffix)
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+// parses a `GET /<alias>,<alias>_v*` response into an aliasState
+func parseAliasState(alias string, body []byte) aliasState {
+	state := aliasState{alias: alias}
+	versions := map[string]int{}
+
+	gjson.ParseBytes(body).ForEach(func(key, value gjson.Result) bool {
+		indexName := key.String()
+		if indexName == alias {
+			state.legacy = true
+		} else if version, ok := parseVersion(alias, indexName); ok {
+			versions[indexName] = version
+			state.latestVersion = max(state.latestVersion, version)
+		}
+		if value.Get("aliases").Get(gjson.Escape(alias)).Exists() {
+			state.targets = append(state.targets, indexName)
+		}
+		return true
+	})
+
+	for indexName := range versions {
+		state.versions = append(state.versions, indexName)
+	}
+	slices.SortFunc(state.versions, func(a, b string) int {
+		return versions[a] - versions[b]
+	})
+	slices.Sort(state.targets)
+
+	return state
+}
+
+func getAliasState(ctx context.Context, esc *elasticsearch.Client, alias string) (aliasState, error) {
+	res, err := esc.Indices.Get(
+		[]string{alias, alias + "_v*"},
+		esc.Indices.Get.WithContext(ctx),
+		esc.Indices.Get.WithIgnoreUnavailable(true),
+		esc.Indices.Get.WithAllowNoIndices(true),
+		esc.Indices.Get.WithFeatures("aliases"),
+	)
+	if err != nil {
+		return aliasState{}, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return aliasState{}, err
+	}
+	if res.StatusCode == 404 {
+		return aliasState{alias: alias}, nil
+	}
+	if res.IsError() {
+		return aliasState{}, fmt.Errorf("error getting alias %s: %d %s", alias, res.StatusCode, body)
+	}
+
+	return parseAliasState(alias, body), nil
+}
+
+// builds the `_aliases` actions that point the alias at newIndex alone.
+// A legacy index holding the alias' name is removed in the same request,
+// since an alias can't be added while an index has its name.
+func aliasSwapActions(state aliasState, newIndex string) []map[string]any {
+	actions := []map[string]any{}
+	for _, target := range state.targets {
+		if target == newIndex {
+			continue
+		}
+		actions = append(actions, map[string]any{
+			"remove": map[string]any{"index": target, "alias": state.alias},
+		})
+	}
+	if state.legacy {
+		actions = append(actions, map[string]any{
+			"remove_index": map[string]any{"index": state.alias},
+		})
+	}
+	actions = append(actions, map[string]any{
+		"add": map[string]any{"index": newIndex, "alias": state.alias, "is_write_index": true},
+	})
+	return actions
+}
+
+// atomically points the alias at newIndex
+func swapAlias(ctx context.Context, esc *elasticsearch.Client, state aliasState, newIndex string) error {
+	body, err := json.Marshal(map[string]any{
+		"actions": aliasSwapActions(state, newIndex),
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := esc.Indices.UpdateAliases(
+		strings.NewReader(string(body)),
+		esc.Indices.UpdateAliases.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		problem, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("error swapping alias %s to %s: %d %s", state.alias, newIndex, res.StatusCode, problem)
+	}
+	return nil
+}
+
+// removes the versioned indexes other than keep
+func deleteOldVersions(ctx context.Context, esc *elasticsearch.Client, state aliasState, keep string) error {
+	old := slices.DeleteFunc(slices.Clone(state.versions), func(indexName string) bool {
+		return indexName == keep
+	})
+	if len(old) == 0 {
+		return nil
+	}
+
+	res, err := esc.Indices.Delete(old, esc.Indices.Delete.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		problem, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("error deleting %v: %d %s", old, res.StatusCode, problem)
+	}
+	return nil
+}
+
+type IndexStatus struct {
+	Alias    string
+	Targets  []string
+	Legacy   bool
+	Versions []string
+	DocCount int64
+}
+
+// Status reports which physical index each collection's alias points at.
+func Status(ctx context.Context, esc *elasticsearch.Client) ([]IndexStatus, error) {
+	collections := []string{}
+	for collection := range collectionConfigs {
+		collections = append(collections, collection)
+	}
+	slices.Sort(collections)
+
+	statuses := []IndexStatus{}
+	for _, collection := range collections {
+		alias := collectionConfigs[collection].indexName
+		state, err := getAliasState(ctx, esc, alias)
+		if err != nil {
+			return nil, err
+		}
+
+		status := IndexStatus{
+			Alias:    alias,
+			Targets:  state.targets,
+			Legacy:   state.legacy,
+			Versions: state.versions,
+		}
+
+		if state.exists() {
+			status.DocCount, err = countDocs(ctx, esc, alias)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func countDocs(ctx context.Context, esc *elasticsearch.Client, indexName string) (int64, error) {
+	res, err := esc.Count(
+		esc.Count.WithContext(ctx),
+		esc.Count.WithIndex(indexName),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, err
+	}
+	if res.IsError() {
+		return 0, fmt.Errorf("error counting %s: %d %s", indexName, res.StatusCode, body)
+	}
+	return gjson.GetBytes(body, "count").Int(), nil
+}
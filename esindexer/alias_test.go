@@ -0,0 +1,46 @@
+package esindexer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/test-go/testify/require"
+)
+
+func TestParseAliasState(t *testing.T) {
+	state := parseAliasState("tracks", []byte(`{
+		"tracks_v10": {"aliases": {}},
+		"tracks_v2": {"aliases": {"tracks": {}}},
+		"tracks_vx": {"aliases": {}},
+		"tracks_users": {"aliases": {}}
+	}`))
+	require.False(t, state.legacy)
+	require.Equal(t, []string{"tracks_v2"}, state.targets)
+	require.Equal(t, []string{"tracks_v2", "tracks_v10"}, state.versions)
+	require.Equal(t, 10, state.latestVersion)
+	require.True(t, state.exists())
+
+	legacy := parseAliasState("tracks", []byte(`{"tracks": {"aliases": {}}}`))
+	require.True(t, legacy.legacy)
+	require.Empty(t, legacy.targets)
+	require.Equal(t, 0, legacy.latestVersion)
+
+	missing := parseAliasState("tracks", []byte(`{}`))
+	require.False(t, missing.exists())
+}
+
+func TestAliasSwapActions(t *testing.T) {
+	state := aliasState{
+		alias:   "tracks",
+		targets: []string{"tracks_v2"},
+		legacy:  true,
+	}
+
+	actions, err := json.Marshal(aliasSwapActions(state, "tracks_v3"))
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		{"remove": {"index": "tracks_v2", "alias": "tracks"}},
+		{"remove_index": {"index": "tracks"}},
+		{"add": {"index": "tracks_v3", "alias": "tracks", "is_write_index": true}}
+	]`, string(actions))
+}
@@ -0,0 +1,31 @@
+package esindexer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SQL building the value of the `autocomplete` completion field
+// used by /v1/search/suggest.
+// Each input is added along with its word suffixes (up to five),
+// so "dave" finds "Stereo Dave" as well as "stereo" does.
+// weight is the popularity that orders suggestions.
+// contexts, if set, is a SQL json object of completion contexts.
+func autocompleteSql(weight string, contexts string, inputs ...string) string {
+	contextsSql := ""
+	if contexts != "" {
+		contextsSql = ", 'contexts', " + contexts
+	}
+
+	return fmt.Sprintf(`(
+		SELECT json_build_object(
+			'input', array_agg(DISTINCT array_to_string(words[i:], ' ')),
+			'weight', LEAST(COALESCE(%s, 0), 2147483647)%s
+		)
+		FROM unnest(ARRAY[%s]::text[]) AS input,
+			LATERAL regexp_split_to_array(trim(input), '\s+') AS words,
+			LATERAL generate_series(1, LEAST(array_length(words, 1), 5)) AS i
+		WHERE trim(input) != ''
+		HAVING count(*) > 0
+	)`, weight, contextsSql, strings.Join(inputs, ", "))
+}
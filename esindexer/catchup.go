@@ -0,0 +1,231 @@
+package esindexer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgxlisten"
+	"github.com/tidwall/gjson"
+)
+
+// Each index records, in its mapping `_meta`, the time it is known
+// to be complete up to (its high water mark).
+// When the listener starts or reconnects it re-indexes every document
+// whose rows changed after the mark, which covers notifications sent
+// while it was down or disconnected.
+// Changes are read from search_index_changes, which triggers fill in
+// for the entity, aggregate and social tables alike.
+const highWaterMarkKey = "indexed_changed_at"
+
+// how far before the mark to catch up from,
+// for changes that were written before it but committed after
+const catchUpOverlap = time.Minute
+
+// ids per query when catching up
+const catchUpChunkSize = 1000
+
+// how often the listener moves the marks up
+const highWaterMarkInterval = time.Minute
+
+func (indexer *EsIndexer) currentTime(ctx context.Context) (time.Time, error) {
+	var now time.Time
+	err := indexer.pool.QueryRow(ctx, `SELECT clock_timestamp()`).Scan(&now)
+	return now, err
+}
+
+// the ids of a collection's documents changed after a time
+func (indexer *EsIndexer) changedIds(ctx context.Context, collection string, since time.Time) ([]int64, error) {
+	rows, err := indexer.pool.Query(ctx, `
+		SELECT id FROM search_index_changes
+		WHERE collection = $1 AND changed_at > $2
+		`, collection, since)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+func getHighWaterMark(ctx context.Context, esc *elasticsearch.Client, indexName string) (time.Time, bool, error) {
+	res, err := esc.Indices.GetMapping(
+		esc.Indices.GetMapping.WithContext(ctx),
+		esc.Indices.GetMapping.WithIndex(indexName),
+	)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if res.IsError() {
+		return time.Time{}, false, fmt.Errorf("error getting mapping %s: %d %s", indexName, res.StatusCode, body)
+	}
+
+	// the response is keyed by the physical index the alias points at
+	mark := gjson.GetBytes(body, "*.mappings._meta."+highWaterMarkKey)
+	if !mark.Exists() {
+		return time.Time{}, false, nil
+	}
+	markedAt, err := time.Parse(time.RFC3339Nano, mark.String())
+	return markedAt, err == nil, err
+}
+
+func putHighWaterMark(ctx context.Context, esc *elasticsearch.Client, indexName string, markedAt time.Time) error {
+	res, err := esc.Indices.PutMapping(
+		[]string{indexName},
+		strings.NewReader(fmt.Sprintf(`{"_meta": {%q: %q}}`, highWaterMarkKey, markedAt.UTC().Format(time.RFC3339Nano))),
+		esc.Indices.PutMapping.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		problem, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("error putting high water mark %s: %d %s", indexName, res.StatusCode, problem)
+	}
+	return nil
+}
+
+// re-indexes the rows changed since the collection's high water mark
+// and moves the mark up to now
+func (indexer *EsIndexer) catchUp(ctx context.Context, collection string) error {
+	cc := collectionConfigs[collection]
+
+	head, err := indexer.currentTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	mark, ok, err := getHighWaterMark(ctx, indexer.esc, cc.indexName)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		// built before marks were recorded, so there is nothing to catch up from
+		slog.Warn("no high water mark", "collection", collection, "changed_at", head)
+	} else {
+		ids, err := indexer.changedIds(ctx, collection, mark.Add(-catchUpOverlap))
+		if err != nil {
+			return err
+		}
+
+		stats, err := indexer.withBulkIndexer(ctx, func(bulk esutil.BulkIndexer) error {
+			for chunk := range slices.Chunk(ids, catchUpChunkSize) {
+				if err := indexer.indexIdsWith(bulk, collection, chunk); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		slog.Info("caught up", "collection", collection, "from", mark, "to", head, "stats", stats)
+		if stats.NumFailed > 0 {
+			return fmt.Errorf("%d documents failed to index into %s", stats.NumFailed, cc.indexName)
+		}
+	}
+
+	return putHighWaterMark(ctx, indexer.esc, cc.indexName, head)
+}
+
+func (indexer *EsIndexer) catchUpAll(ctx context.Context) error {
+	for collection := range collectionConfigs {
+		if err := indexer.catchUp(ctx, collection); err != nil {
+			return fmt.Errorf("error catching up %s: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+func (indexer *EsIndexer) putHighWaterMarks(ctx context.Context, markedAt time.Time) error {
+	for _, cc := range collectionConfigs {
+		if err := putHighWaterMark(ctx, indexer.esc, cc.indexName, markedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// catchUpHandler runs the catch-up when the listener starts and after
+// each reconnect, once it is listening again.
+type catchUpHandler struct {
+	pgxlisten.Handler
+	indexer *EsIndexer
+	state   *listenState
+}
+
+func (h *catchUpHandler) HandleBacklog(ctx context.Context, channel string, conn *pgx.Conn) error {
+	generation := h.state.currentGeneration()
+
+	// this can take a while, and notifications shouldn't wait on it
+	go func() {
+		if err := h.indexer.catchUpAll(ctx); err != nil {
+			slog.Error("catch up failed", "err", err)
+			return
+		}
+		h.state.caughtUpWith(generation)
+	}()
+
+	return nil
+}
+
+// listenState tracks whether every notification since the last catch-up
+// has been received, which is what makes it safe to move the marks up.
+type listenState struct {
+	sync.Mutex
+	generation int
+	caughtUp   bool
+}
+
+// called before each (re)connect
+func (s *listenState) connecting() {
+	s.Lock()
+	defer s.Unlock()
+	s.generation++
+	s.caughtUp = false
+}
+
+// a catch-up still running for the lost connection no longer counts
+func (s *listenState) lost() {
+	s.Lock()
+	defer s.Unlock()
+	s.generation++
+	s.caughtUp = false
+}
+
+func (s *listenState) currentGeneration() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.generation
+}
+
+// marks the catch-up for a connection as done,
+// unless the listener has reconnected since
+func (s *listenState) caughtUpWith(generation int) {
+	s.Lock()
+	defer s.Unlock()
+	if s.generation == generation {
+		s.caughtUp = true
+	}
+}
+
+func (s *listenState) isCaughtUp() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.caughtUp
+}
@@ -0,0 +1,62 @@
+package esindexer
+
+import (
+	"context"
+	"testing"
+
+	"bridgerton.audius.co/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangedIds(t *testing.T) {
+	pool := database.CreateTestDatabase(t, "test_esindexer")
+	defer pool.Close()
+
+	ctx := context.Background()
+
+	database.Seed(pool, database.FixtureMap{
+		"users": {
+			{"user_id": 1, "handle": "one", "handle_lc": "one"},
+			{"user_id": 2, "handle": "two", "handle_lc": "two"},
+		},
+		"aggregate_user": {
+			{"user_id": 1},
+			{"user_id": 2},
+		},
+		"tracks": {
+			{"track_id": 10, "owner_id": 1},
+		},
+		"playlists": {
+			{"playlist_id": 20, "playlist_owner_id": 1},
+		},
+	})
+
+	indexer := &EsIndexer{pool: pool}
+	mark, err := indexer.currentTime(ctx)
+	require.NoError(t, err)
+
+	// none of these bump a blocknumber, and no listener hears the notify
+	_, err = pool.Exec(ctx, `UPDATE aggregate_user SET follower_count = 10 WHERE user_id = 2`)
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, `INSERT INTO aggregate_track (track_id, save_count) VALUES (10, 1)`)
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, `INSERT INTO playlist_tracks (playlist_id, track_id, is_removed) VALUES (20, 10, false)`)
+	require.NoError(t, err)
+
+	userIds, err := indexer.changedIds(ctx, "users", mark)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2}, userIds)
+
+	trackIds, err := indexer.changedIds(ctx, "tracks", mark)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{10}, trackIds)
+
+	playlistIds, err := indexer.changedIds(ctx, "playlists", mark)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{20}, playlistIds)
+
+	socialIds, err := indexer.changedIds(ctx, "socials", mark)
+	require.NoError(t, err)
+	assert.Empty(t, socialIds)
+}
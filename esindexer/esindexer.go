@@ -1,17 +1,22 @@
 package esindexer
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"log/slog"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tidwall/gjson"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -36,36 +41,29 @@ type EsIndexer struct {
 	drop bool
 }
 
-// create index from mapping
-func (indexer *EsIndexer) createIndex(collection string) error {
+// create a physical index from the collection mapping
+func (indexer *EsIndexer) createIndex(collection, indexName string) error {
 	cc := collectionConfigs[collection]
 
-	if indexer.drop {
-		_, err := indexer.esc.Indices.Delete([]string{cc.indexName})
-		if err != nil {
-			fmt.Println("drop error", cc.indexName, err)
-		}
-	}
-
 	indexSettings := commonIndexSettings(cc.mapping)
 	res, err := indexer.esc.Indices.Create(
-		cc.indexName,
+		indexName,
 		indexer.esc.Indices.Create.WithBody(
 			strings.NewReader(indexSettings),
 		),
 	)
 	if err != nil {
-		fmt.Println("create index error", cc.indexName, err)
+		fmt.Println("create index error", indexName, err)
 		return err
 	}
 	defer res.Body.Close()
 
 	if res.IsError() {
 		problem, _ := io.ReadAll(res.Body)
-		return fmt.Errorf("error creating index %s: %d %s", cc.indexName, res.StatusCode, problem)
+		return fmt.Errorf("error creating index %s: %d %s", indexName, res.StatusCode, problem)
 	}
 
-	fmt.Println("created index", cc.indexName)
+	fmt.Println("created index", indexName)
 	return nil
 }
 
@@ -79,19 +77,28 @@ func (indexer *EsIndexer) reindexAll() error {
 	return g.Wait()
 }
 
-// creates index mapping + indexes all documents
+// indexes all documents.
+// with drop set, or when the collection has no index yet,
+// the documents go into a new index version which then replaces the live one.
 func (indexer *EsIndexer) reindexCollection(collection string) error {
-	if err := indexer.createIndex(collection); err != nil {
+	cc := collectionConfigs[collection]
+
+	state, err := getAliasState(context.Background(), indexer.esc, cc.indexName)
+	if err != nil {
 		return err
 	}
-	return indexer.indexAll(collection)
+
+	if !indexer.drop && state.exists() {
+		return indexer.indexAll(collection)
+	}
+	return indexer.rebuild(collection, state)
 }
 
-// index all documents
+// index all documents into the live index
 func (indexer *EsIndexer) indexAll(collection string) error {
 	cc := collectionConfigs[collection]
 
-	err := indexer.indexSql(cc.indexName, cc.sql)
+	_, err := indexer.indexSql(indexer.bulk, cc.indexName, cc.sql)
 	if err != nil {
 		return err
 	}
@@ -101,8 +108,59 @@ func (indexer *EsIndexer) indexAll(collection string) error {
 	return nil
 }
 
-// index a list of IDs
+// builds the next index version and swaps the alias over to it.
+// search keeps using the previous version until the new one is complete.
+func (indexer *EsIndexer) rebuild(collection string, state aliasState) error {
+	ctx := context.Background()
+	cc := collectionConfigs[collection]
+	indexName := versionedIndexName(cc.indexName, state.latestVersion+1)
+
+	if err := indexer.createIndex(collection, indexName); err != nil {
+		return err
+	}
+
+	head, err := indexer.currentTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	stats, err := indexer.withBulkIndexer(ctx, func(bulk esutil.BulkIndexer) error {
+		_, err := indexer.indexSql(bulk, indexName, cc.sql)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info("index all stats", "collection", collection, "index", indexName, "stats", stats)
+	if stats.NumFailed > 0 {
+		return fmt.Errorf("%d documents failed to index into %s, %s was not swapped", stats.NumFailed, indexName, cc.indexName)
+	}
+
+	if err := putHighWaterMark(ctx, indexer.esc, indexName, head); err != nil {
+		return err
+	}
+
+	if err := swapAlias(ctx, indexer.esc, state, indexName); err != nil {
+		return err
+	}
+	slog.Info("swapped alias", "alias", cc.indexName, "from", state.targets, "to", indexName)
+
+	if err := deleteOldVersions(ctx, indexer.esc, state, indexName); err != nil {
+		return err
+	}
+
+	// rows changed while the index was being built went to the old version
+	return indexer.catchUp(ctx, collection)
+}
+
+// index a list of IDs.
+// IDs the collection query no longer returns (deleted, deactivated, unlisted...)
+// are deleted from the index.
 func (indexer *EsIndexer) indexIds(collection string, ids ...int64) error {
+	return indexer.indexIdsWith(indexer.bulk, collection, ids)
+}
+
+func (indexer *EsIndexer) indexIdsWith(bulk esutil.BulkIndexer, collection string, ids []int64) error {
 	if len(ids) == 0 {
 		return nil
 	}
@@ -118,53 +176,181 @@ func (indexer *EsIndexer) indexIds(collection string, ids ...int64) error {
 		cc.idColumn,
 		strings.Join(stringIds, ","))
 
-	slog.Info("index", "collection", collection, "ids", ids)
+	indexed, err := indexer.indexSql(bulk, cc.indexName, sql)
+	if err != nil {
+		return err
+	}
+
+	gone := slices.DeleteFunc(slices.Clone(ids), func(id int64) bool {
+		return slices.Contains(indexed, id)
+	})
+
+	slog.Info("index", "collection", collection, "ids", ids, "gone", gone)
+
+	if err := indexer.deleteIds(bulk, cc.indexName, gone); err != nil {
+		return err
+	}
+
+	// tracks and playlists of a deactivated user go with them
+	if collection == "users" && len(gone) > 0 {
+		return indexer.indexOwnedBy(bulk, gone)
+	}
+	return nil
+}
+
+func (indexer *EsIndexer) indexOwnedBy(bulk esutil.BulkIndexer, userIds []int64) error {
+	ctx := context.Background()
+	owned := map[string]string{
+		"tracks":    `SELECT track_id FROM tracks WHERE owner_id = ANY($1)`,
+		"playlists": `SELECT playlist_id FROM playlists WHERE playlist_owner_id = ANY($1)`,
+	}
+	for collection, sql := range owned {
+		rows, err := indexer.pool.Query(ctx, sql, userIds)
+		if err != nil {
+			return err
+		}
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+		if err != nil {
+			return err
+		}
+		if err := indexer.indexIdsWith(bulk, collection, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runs fn with a bulk indexer of its own,
+// and waits for everything fn adds to it to be indexed
+func (indexer *EsIndexer) withBulkIndexer(ctx context.Context, fn func(bulk esutil.BulkIndexer) error) (esutil.BulkIndexerStats, error) {
+	bulk, err := newBulkIndexer(indexer.esc)
+	if err != nil {
+		return esutil.BulkIndexerStats{}, err
+	}
 
-	return indexer.indexSql(cc.indexName, sql)
+	err = fn(bulk)
+	if closeErr := bulk.Close(ctx); err == nil {
+		err = closeErr
+	}
+	return bulk.Stats(), err
 }
 
 // runs a query + indexes documents
 // assumes query returns (id, json_doc) tuples
-func (indexer *EsIndexer) indexSql(indexName, sql string) error {
+// returns the ids it indexed
+func (indexer *EsIndexer) indexSql(bulk esutil.BulkIndexer, indexName, sql string, args ...any) ([]int64, error) {
 
 	ctx := context.Background()
 
-	rows, err := indexer.pool.Query(ctx, sql)
+	rows, err := indexer.pool.Query(ctx, sql, args...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer rows.Close()
 
+	indexed := []int64{}
 	for rows.Next() {
-		var id int
+		var id int64
 		var doc string
 		if err := rows.Scan(&id, &doc); err != nil {
 			fmt.Println("row scan error:", err)
 			continue
 		}
 
-		err = indexer.bulk.Add(ctx, esutil.BulkIndexerItem{
+		err = bulk.Add(ctx, esutil.BulkIndexerItem{
 			Action:     "index",
 			Index:      indexName,
 			DocumentID: fmt.Sprintf("%d", id),
 			Body:       strings.NewReader(doc),
 			OnSuccess:  func(ctx context.Context, item esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem) {},
-			OnFailure: func(ctx context.Context, item esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem, err error) {
-				if err != nil {
-					log.Printf("ERROR: %s", err)
-				} else {
-					log.Printf("ERROR: %s: %s", res.Error.Type, res.Error.Reason)
-				}
-			},
+			OnFailure:  logBulkFailure,
 		})
 
 		if err != nil {
 			fmt.Println("es index error:", err)
 			continue
 		}
+		indexed = append(indexed, id)
+	}
+
+	return indexed, rows.Err()
+}
+
+// deletes documents from the index.
+// Only ids that are in the index are deleted,
+// so the bulk stats count real deletes rather than 404s.
+func (indexer *EsIndexer) deleteIds(bulk esutil.BulkIndexer, indexName string, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+
+	existing, err := indexer.existingIds(ctx, indexName, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range existing {
+		err := bulk.Add(ctx, esutil.BulkIndexerItem{
+			Action:     "delete",
+			Index:      indexName,
+			DocumentID: id,
+			OnFailure:  logBulkFailure,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (indexer *EsIndexer) existingIds(ctx context.Context, indexName string, ids []int64) ([]string, error) {
+	stringIds := make([]string, len(ids))
+	for idx, id := range ids {
+		stringIds[idx] = strconv.FormatInt(id, 10)
+	}
+
+	body, err := json.Marshal(map[string]any{"ids": stringIds})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := indexer.esc.Mget(
+		bytes.NewReader(body),
+		indexer.esc.Mget.WithContext(ctx),
+		indexer.esc.Mget.WithIndex(indexName),
+		indexer.esc.Mget.WithSource("false"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("error getting %s docs: %d %s", indexName, res.StatusCode, resBody)
+	}
+
+	existing := []string{}
+	for _, doc := range gjson.GetBytes(resBody, "docs").Array() {
+		if doc.Get("found").Bool() {
+			existing = append(existing, doc.Get("_id").String())
+		}
 	}
+	return existing, nil
+}
 
-	return rows.Err()
+func logBulkFailure(ctx context.Context, item esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem, err error) {
+	if err != nil {
+		log.Printf("ERROR: %s %s: %s", item.Action, item.DocumentID, err)
+	} else {
+		log.Printf("ERROR: %s %s: %s: %s", item.Action, item.DocumentID, res.Error.Type, res.Error.Reason)
+	}
 }
 
 // this uses painless to 'patch' a `socials` doc to add / remove an entity ID from a list of ids.
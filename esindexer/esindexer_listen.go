@@ -47,13 +47,19 @@ func (pending *pendingChanges) take() map[string][]int64 {
 
 func (indexer *EsIndexer) listen(ctx context.Context) error {
 
+	state := &listenState{}
+
 	listener := &pgxlisten.Listener{
 		Connect: func(ctx context.Context) (*pgx.Conn, error) {
+			state.connecting()
 			// Provide a pgx connection for listening
 			// LISTEN needs to use the write leader
 			return pgx.Connect(ctx, config.Cfg.WriteDbUrl)
 		},
 		LogError: func(ctx context.Context, err error) {
+			// notifications may have been missed,
+			// so hold the marks until the next catch-up
+			state.lost()
 			log.Println("Listener error:", err)
 		},
 		ReconnectDelay: 10 * time.Second,
@@ -129,12 +135,16 @@ func (indexer *EsIndexer) listen(ctx context.Context) error {
 		return nil
 	}))
 
-	listener.Handle("reindex", pgxlisten.HandlerFunc(func(ctx context.Context, notification *pgconn.Notification, conn *pgx.Conn) error {
-		if err := indexer.reindexAll(); err != nil {
-			slog.Error("reindex failed", "err", err)
-		}
-		return nil
-	}))
+	listener.Handle("reindex", &catchUpHandler{
+		Handler: pgxlisten.HandlerFunc(func(ctx context.Context, notification *pgconn.Notification, conn *pgx.Conn) error {
+			if err := indexer.reindexAll(); err != nil {
+				slog.Error("reindex failed", "err", err)
+			}
+			return nil
+		}),
+		indexer: indexer,
+		state:   state,
+	})
 
 	// this would be useful for updating track.play_count
 	// but it also fires all the time...
@@ -144,13 +154,43 @@ func (indexer *EsIndexer) listen(ctx context.Context) error {
 	go func() {
 		ticker := time.NewTicker(10 * time.Second)
 		defer ticker.Stop()
+
+		// the database time on the previous tick:
+		// notifications for changes before it have all been received and indexed by now
+		var prevHead time.Time
+		var markedAt time.Time
+
 		for range ticker.C {
+			caughtUp := state.isCaughtUp()
+			head, err := indexer.currentTime(ctx)
+			if err != nil {
+				log.Printf("Error getting database time: %v", err)
+			}
+
+			failed := false
 			idMap := pending.take()
 			for collection, ids := range idMap {
 				if err := indexer.indexIds(collection, ids...); err != nil {
 					log.Printf("Error indexing %s: %v", collection, err)
+					failed = true
+				}
+			}
+			if len(idMap) > 0 {
+				slog.Info("bulk stats", "stats", indexer.bulk.Stats())
+			}
+
+			if !caughtUp || failed || err != nil {
+				prevHead = time.Time{}
+				continue
+			}
+
+			if !prevHead.IsZero() && time.Since(markedAt) > highWaterMarkInterval {
+				if err := indexer.putHighWaterMarks(ctx, prevHead); err != nil {
+					log.Printf("Error putting high water marks: %v", err)
 				}
+				markedAt = time.Now()
 			}
+			prevHead = head
 		}
 	}()
 
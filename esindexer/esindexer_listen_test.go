@@ -21,3 +21,24 @@ func TestPending(t *testing.T) {
 	require.Empty(t, pending.idMap["users"])
 
 }
+
+func TestListenState(t *testing.T) {
+	state := &listenState{}
+	require.False(t, state.isCaughtUp())
+
+	state.connecting()
+	generation := state.currentGeneration()
+	state.caughtUpWith(generation)
+	require.True(t, state.isCaughtUp())
+
+	// a catch-up that finishes after the connection is lost doesn't count
+	state.connecting()
+	generation = state.currentGeneration()
+	state.lost()
+	state.caughtUpWith(generation)
+	require.False(t, state.isCaughtUp())
+
+	state.connecting()
+	state.caughtUpWith(state.currentGeneration())
+	require.True(t, state.isCaughtUp())
+}
@@ -2,6 +2,21 @@ package esindexer
 
 import "github.com/qjebbs/go-jsons"
 
+// Mapping for names and titles, which come in any language.
+// Besides the default analysis, it has sub-fields for matching
+// without accents or case ("Beyonce" finds "Beyoncé"),
+// CJK text as overlapping bigrams (CJK has no spaces between words),
+// and other scripts transliterated to latin ("Zemfira" finds "Земфира").
+const multilingualText = `{
+	"type": "text",
+	"fields": {
+		"keyword": { "type": "keyword", "ignore_above": 256 },
+		"folded": { "type": "text", "analyzer": "folded_analyzer" },
+		"cjk": { "type": "text", "analyzer": "cjk_analyzer" },
+		"translit": { "type": "text", "analyzer": "translit_analyzer" }
+	}
+}`
+
 func commonIndexSettings(mapping string) string {
 	if mapping == "" {
 		mapping = "{}"
@@ -13,6 +28,12 @@ func commonIndexSettings(mapping string) string {
 			"number_of_shards": 1,
 			"number_of_replicas": 0,
 			"analysis": {
+				"char_filter": {
+					"normalize": {
+						"type": "icu_normalizer",
+						"name": "nfkc_cf"
+					}
+				},
 				"tokenizer": {
 					"ngram_tokenizer": {
 						"type": "ngram",
@@ -21,10 +42,37 @@ func commonIndexSettings(mapping string) string {
 						"token_chars": ["letter", "digit"]
 					}
 				},
+				"filter": {
+					"to_latin": {
+						"type": "icu_transform",
+						"id": "Any-Latin; NFD; [:Nonspacing Mark:] Remove; NFC"
+					}
+				},
 				"analyzer": {
 					"infix_analyzer": {
+						"char_filter": ["normalize"],
 						"tokenizer": "ngram_tokenizer",
-						"filter": ["lowercase"]
+						"filter": ["icu_folding"]
+					},
+					"autocomplete_analyzer": {
+						"char_filter": ["normalize"],
+						"tokenizer": "icu_tokenizer",
+						"filter": ["icu_folding"]
+					},
+					"folded_analyzer": {
+						"char_filter": ["normalize"],
+						"tokenizer": "icu_tokenizer",
+						"filter": ["icu_folding"]
+					},
+					"cjk_analyzer": {
+						"char_filter": ["normalize"],
+						"tokenizer": "standard",
+						"filter": ["cjk_width", "lowercase", "cjk_bigram"]
+					},
+					"translit_analyzer": {
+						"char_filter": ["normalize"],
+						"tokenizer": "icu_tokenizer",
+						"filter": ["to_latin", "icu_folding"]
 					}
 				}
 			}
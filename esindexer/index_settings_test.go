@@ -52,3 +52,26 @@ func TestCommonIndexSettings(t *testing.T) {
 		})
 	}
 }
+
+func TestMultilingualMappings(t *testing.T) {
+	for collection, field := range map[string]string{
+		"users":     "name",
+		"tracks":    "title",
+		"playlists": "title",
+	} {
+		settings := commonIndexSettings(collectionConfigs[collection].mapping)
+		utils.JsonAssert(t, []byte(settings), map[string]any{
+			"mappings.properties." + field + ".type":                     "text",
+			"mappings.properties." + field + ".fields.keyword.type":      "keyword",
+			"mappings.properties." + field + ".fields.folded.analyzer":   "folded_analyzer",
+			"mappings.properties." + field + ".fields.cjk.analyzer":      "cjk_analyzer",
+			"mappings.properties." + field + ".fields.translit.analyzer": "translit_analyzer",
+			"settings.analysis.analyzer.cjk_analyzer.tokenizer":          "standard",
+		})
+	}
+
+	tracks := commonIndexSettings(tracksConfig.mapping)
+	utils.JsonAssert(t, []byte(tracks), map[string]any{
+		"mappings.properties.user.properties.name.fields.folded.analyzer": "folded_analyzer",
+	})
+}
@@ -3,12 +3,42 @@ package esindexer
 var playlistsConfig = collectionConfig{
 	indexName: "playlists",
 	idColumn:  "playlist_id",
+	mapping: `
+	{
+		"mappings": {
+			"properties": {
+				"title": ` + multilingualText + `,
+				"user": {
+					"properties": {
+						"name": ` + multilingualText + `,
+						"handle": ` + multilingualText + `
+					}
+				},
+				"id": { "type": "long" },
+				"autocomplete": {
+					"type": "completion",
+					"analyzer": "autocomplete_analyzer",
+					"preserve_separators": false,
+					"max_input_length": 100,
+					"contexts": [{"name": "kind", "type": "category"}]
+				}
+			}
+		}
+	}`,
 	sql: `
 	SELECT
 		playlist_id,
 		json_build_object(
+			'id', playlists.playlist_id,
 			'suggest', CONCAT_WS(' ', playlist_name, users.name, users.handle),
+			'autocomplete', ` + autocompleteSql(
+		"aggregate_playlist.save_count",
+		"json_build_object('kind', CASE WHEN playlists.is_album THEN 'album' ELSE 'playlist' END)",
+		"playlist_name",
+	) + `,
 			'title', playlist_name,
+			'slug', (SELECT slug FROM playlist_routes WHERE playlist_id = playlists.playlist_id AND is_current = true),
+			'artwork', COALESCE(playlist_image_sizes_multihash, playlist_image_multihash),
 			'description', description,
 			'track_count', track_count,
 			'save_count', aggregate_playlist.save_count,
@@ -19,6 +49,7 @@ var playlistsConfig = collectionConfig{
 			'is_private', is_private,
 			'is_album', playlists.is_album,
 			'user', json_build_object(
+				'user_id', users.user_id,
 				'handle', users.handle,
 				'name', users.name,
 				'location', users.location,
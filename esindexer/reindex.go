@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"slices"
+	"strings"
 	"syscall"
 
 	"bridgerton.audius.co/config"
@@ -37,13 +38,17 @@ func mustDialElasticsearch() *elasticsearch.Client {
 	return esc
 }
 
-func Reindex(pool *pgxpool.Pool, esc *elasticsearch.Client, drop bool, collections ...string) {
-
-	bulk, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
+func newBulkIndexer(esc *elasticsearch.Client) (esutil.BulkIndexer, error) {
+	return esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
 		Client:     esc,
 		NumWorkers: 2,
 		Refresh:    "true",
 	})
+}
+
+func Reindex(pool *pgxpool.Pool, esc *elasticsearch.Client, drop bool, collections ...string) {
+
+	bulk, err := newBulkIndexer(esc)
 	if err != nil {
 		log.Fatalf("Error creating the indexer: %s", err)
 	}
@@ -111,11 +116,7 @@ func Reindex(pool *pgxpool.Pool, esc *elasticsearch.Client, drop bool, collectio
 
 func ReindexForTest(pool *pgxpool.Pool, esc *elasticsearch.Client) {
 
-	bulk, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
-		Client:     esc,
-		NumWorkers: 2,
-		Refresh:    "true",
-	})
+	bulk, err := newBulkIndexer(esc)
 	if err != nil {
 		log.Fatalf("Error creating the indexer: %s", err)
 	}
@@ -140,3 +141,28 @@ func ReindexLegacy(drop bool, collections ...string) {
 	esc := mustDialElasticsearch()
 	Reindex(pool, esc, drop, collections...)
 }
+
+func PrintStatus() {
+	esc := mustDialElasticsearch()
+
+	statuses, err := Status(context.Background(), esc)
+	if err != nil {
+		log.Fatalf("Error getting index status: %s", err)
+	}
+
+	for _, status := range statuses {
+		switch {
+		case status.Legacy:
+			fmt.Printf("%-10s  unversioned index (%d docs)\n", status.Alias, status.DocCount)
+		case len(status.Targets) == 0:
+			fmt.Printf("%-10s  missing\n", status.Alias)
+		default:
+			fmt.Printf("%-10s  -> %s (%d docs)\n", status.Alias, strings.Join(status.Targets, ", "), status.DocCount)
+		}
+		for _, version := range status.Versions {
+			if !slices.Contains(status.Targets, version) {
+				fmt.Printf("%-10s     %s (not live)\n", "", version)
+			}
+		}
+	}
+}
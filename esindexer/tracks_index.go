@@ -7,7 +7,21 @@ var tracksConfig = collectionConfig{
 	{
 		"mappings": {
 			"properties": {
-				"bpm":      { "type": "float" }
+				"title": ` + multilingualText + `,
+				"user": {
+					"properties": {
+						"name": ` + multilingualText + `,
+						"handle": ` + multilingualText + `
+					}
+				},
+				"id":       { "type": "long" },
+				"bpm":      { "type": "float" },
+				"autocomplete": {
+					"type": "completion",
+					"analyzer": "autocomplete_analyzer",
+					"preserve_separators": false,
+					"max_input_length": 100
+				}
 			}
 		}
 	}`,
@@ -15,8 +29,12 @@ var tracksConfig = collectionConfig{
 	SELECT
 		track_id,
 		json_build_object(
+			'id', track_id,
 			'suggest', CONCAT_WS(' ', title, users.name, users.handle),
+			'autocomplete', ` + autocompleteSql("aggregate_plays.count", "", "title") + `,
 			'title', title,
+			'slug', (SELECT slug FROM track_routes WHERE track_id = tracks.track_id AND is_current = true),
+			'cover_art', COALESCE(cover_art_sizes, cover_art),
 			'genre', genre,
 			'mood', mood,
 			'duration', duration,
@@ -35,6 +53,7 @@ var tracksConfig = collectionConfig{
 			'download_conditions', download_conditions,
 			'stream_conditions', stream_conditions,
 			'user', json_build_object(
+				'user_id', users.user_id,
 				'handle', users.handle,
 				'name', users.name,
 				'location', users.location,
@@ -3,13 +3,32 @@ package esindexer
 var userConfig = collectionConfig{
 	indexName: "users",
 	idColumn:  "user_id",
+	mapping: `
+	{
+		"mappings": {
+			"properties": {
+				"name": ` + multilingualText + `,
+				"handle": ` + multilingualText + `,
+				"id": { "type": "long" },
+				"autocomplete": {
+					"type": "completion",
+					"analyzer": "autocomplete_analyzer",
+					"preserve_separators": false,
+					"max_input_length": 100
+				}
+			}
+		}
+	}`,
 	sql: `
 	SELECT
 		user_id,
 		json_build_object(
+			'id', user_id,
 			'suggest', CONCAT_WS(' ', name, handle),
+			'autocomplete', ` + autocompleteSql("follower_count", "", "name", "handle") + `,
 			'name', name,
 			'handle', handle,
+			'profile_picture', COALESCE(profile_picture_sizes, profile_picture),
 			'bio', bio,
 			'location', location,
 			'created_at', created_at,
@@ -0,0 +1,223 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// EntityManagerDomain identifies the EIP-712 domain that clients sign
+// ManageEntity transactions against. It is the legacy ACDC EntityManager
+// contract, which is still what the SDK signs for.
+type EntityManagerDomain struct {
+	ChainId         int64
+	ContractAddress string
+}
+
+var manageEntityTypes = apitypes.Types{
+	"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	},
+	"ManageEntity": {
+		{Name: "userId", Type: "uint"},
+		{Name: "entityType", Type: "string"},
+		{Name: "entityId", Type: "uint"},
+		{Name: "action", Type: "string"},
+		{Name: "metadata", Type: "string"},
+		{Name: "nonce", Type: "bytes32"},
+	},
+}
+
+// Returns the EIP-712 hash that the signer of em signed.
+func manageEntityHash(domain EntityManagerDomain, em *core_proto.ManageEntityLegacy) ([]byte, error) {
+	typedData := apitypes.TypedData{
+		Types:       manageEntityTypes,
+		PrimaryType: "ManageEntity",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Entity Manager",
+			Version:           "1",
+			ChainId:           math.NewHexOrDecimal256(domain.ChainId),
+			VerifyingContract: domain.ContractAddress,
+		},
+		Message: apitypes.TypedDataMessage{
+			"userId":     math.NewHexOrDecimal256(em.UserId),
+			"entityType": em.EntityType,
+			"entityId":   math.NewHexOrDecimal256(em.EntityId),
+			"action":     em.Action,
+			"metadata":   em.Metadata,
+			"nonce":      em.Nonce,
+		},
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	return hash, err
+}
+
+// Recovers the lowercased wallet address that signed em.
+func recoverManageEntitySigner(domain EntityManagerDomain, em *core_proto.ManageEntityLegacy) (string, error) {
+	hash, err := manageEntityHash(domain, em)
+	if err != nil {
+		return "", err
+	}
+
+	signature := common.FromHex(em.Signature)
+	if len(signature) != crypto.SignatureLength {
+		return "", errors.New("malformed signature")
+	}
+	if signature[crypto.RecoveryIDOffset] >= 27 {
+		signature[crypto.RecoveryIDOffset] -= 27
+	}
+
+	publicKey, err := crypto.SigToPub(hash, signature)
+	if err != nil {
+		return "", err
+	}
+
+	signer := strings.ToLower(crypto.PubkeyToAddress(*publicKey).Hex())
+	if em.Signer != "" && !strings.EqualFold(em.Signer, signer) {
+		return "", fmt.Errorf("claimed signer %s does not match recovered signer %s", em.Signer, signer)
+	}
+	return signer, nil
+}
+
+// Recovers the lowercased wallet address that personal_signed message.
+func recoverPersonalSigner(message string, signatureHex string) (string, error) {
+	signature := common.FromHex(signatureHex)
+	if len(signature) != crypto.SignatureLength {
+		return "", errors.New("malformed signature")
+	}
+	if signature[crypto.RecoveryIDOffset] >= 27 {
+		signature[crypto.RecoveryIDOffset] -= 27
+	}
+
+	publicKey, err := crypto.SigToPub(accounts.TextHash([]byte(message)), signature)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(crypto.PubkeyToAddress(*publicKey).Hex()), nil
+}
+
+// Reports whether wallet may act as userId: either it is the user's own
+// wallet, or the user has approved a grant to it that is not revoked.
+// This is the same rule the api applies in isAuthorizedRequest.
+func (ci *CoreIndexer) isAuthorized(ctx context.Context, userId int64, wallet string) (bool, error) {
+	var isAuthorized bool
+	err := ci.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			-- I am the user
+			SELECT 1 FROM users
+			WHERE
+				user_id = $1
+				AND wallet = $2
+				AND is_current = true
+		) OR EXISTS (
+			-- I have a grant to the user
+			SELECT 1 FROM grants
+			WHERE
+				is_current = true
+				AND user_id = $1
+				AND grantee_address = $2
+				AND is_approved = true
+				AND is_revoked = false
+		);
+		`, userId, wallet).Scan(&isAuthorized)
+	return isAuthorized, err
+}
+
+// Verifies that em was signed by a wallet allowed to act for em.UserId,
+// returning the signer.
+// CreateUser is the exception: there is no user yet, so the signer
+// becomes the new user's wallet.
+func (ci *CoreIndexer) authorizeManageEntity(ctx context.Context, em *core_proto.ManageEntityLegacy) (string, error) {
+	signer, err := recoverManageEntitySigner(ci.emDomain, em)
+	if err != nil {
+		return "", invalidTx("invalid signature: %s", err)
+	}
+
+	if em.Action+em.EntityType == "CreateUser" {
+		return signer, nil
+	}
+
+	ok, err := ci.isAuthorized(ctx, em.UserId, signer)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", invalidTx("signer %s is not authorized for user %d", signer, em.UserId)
+	}
+	return signer, nil
+}
+
+// invalidTxError marks a transaction that must never be applied,
+// because it is unauthorized or malformed, as opposed to a database
+// failure that may succeed on retry.
+type invalidTxError struct {
+	reason string
+}
+
+func (e *invalidTxError) Error() string {
+	return e.reason
+}
+
+func invalidTx(format string, args ...any) error {
+	return &invalidTxError{reason: fmt.Sprintf(format, args...)}
+}
+
+// Returns database errors caused by what a transaction contains, like a
+// duplicate id or a value that doesn't fit its column, as invalidTxErrors:
+// they fail the same way on every node, so retrying can't help.
+// Any other error is passed through.
+func asInvalidTx(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	// class 22 is data exception, class 23 integrity constraint violation
+	if strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23") {
+		return invalidTx("%s (SQLSTATE %s)", pgErr.Message, pgErr.Code)
+	}
+	return err
+}
+
+// Records an invalid transaction as skipped by the network,
+// passing any other error through.
+func (ci *CoreIndexer) skipInvalidTx(txInfo TxInfo, err error) error {
+	var invalid *invalidTxError
+	if !errors.As(err, &invalid) {
+		return err
+	}
+	ci.logger.Info("skipping transaction",
+		zap.String("txhash", txInfo.txhash),
+		zap.String("reason", invalid.reason))
+	return ci.skipTx(ci.ctx, txInfo, "network", invalid.reason)
+}
+
+// Records a transaction the indexer did not apply.
+// level is 'network' for transactions no node should apply,
+// and 'node' for ones that failed on this node.
+func (ci *CoreIndexer) skipTx(ctx context.Context, txInfo TxInfo, level string, reason string) error {
+	return ci.db.QueueExec(ctx, `
+		INSERT INTO skipped_transactions (blocknumber, blockhash, txhash, level, reason)
+		VALUES (@blocknumber, @blockhash, @txhash, @level, @reason)
+		`, pgx.NamedArgs{
+		"blocknumber": txInfo.blocknumber,
+		"blockhash":   txInfo.blockhash,
+		"txhash":      txInfo.txhash,
+		"level":       level,
+		"reason":      reason,
+	})
+}
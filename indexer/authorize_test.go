@@ -0,0 +1,202 @@
+package indexer
+
+import (
+	"crypto/ecdsa"
+	"strings"
+	"testing"
+
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEmDomain = EntityManagerDomain{
+	ChainId:         1337,
+	ContractAddress: "0x254dffcd3277C0b1660F6d42EFbB754edaBAbC2B",
+}
+
+func walletOf(key *ecdsa.PrivateKey) string {
+	return strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex())
+}
+
+// Signs em the way the SDK does and wraps it in a transaction.
+func signedManageEntityTx(t *testing.T, key *ecdsa.PrivateKey, em *core_proto.ManageEntityLegacy) *core_proto.SignedTransaction {
+	t.Helper()
+	if em.Nonce == "" {
+		em.Nonce = hexutil.Encode(crypto.Keccak256([]byte(em.Metadata + em.Action + em.EntityType)))
+	}
+
+	hash, err := manageEntityHash(testEmDomain, em)
+	require.NoError(t, err)
+
+	signature, err := crypto.Sign(hash, key)
+	require.NoError(t, err)
+	signature[crypto.RecoveryIDOffset] += 27
+	em.Signature = hexutil.Encode(signature)
+
+	return manageEntityTx(em)
+}
+
+func TestRecoverManageEntitySigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	em := &core_proto.ManageEntityLegacy{
+		UserId:     1,
+		EntityId:   2,
+		Action:     "Create",
+		EntityType: "Track",
+		Metadata:   `{"cid":"","data":{"title":"hi"}}`,
+	}
+	signedManageEntityTx(t, key, em)
+
+	signer, err := recoverManageEntitySigner(testEmDomain, em)
+	require.NoError(t, err)
+	assert.Equal(t, walletOf(key), signer)
+
+	// claimed signer must agree with the recovered one
+	em.Signer = "0x0000000000000000000000000000000000000001"
+	_, err = recoverManageEntitySigner(testEmDomain, em)
+	assert.Error(t, err)
+	em.Signer = ""
+
+	// any change to the signed payload changes the signer
+	em.Metadata = `{"cid":"","data":{"title":"tampered"}}`
+	signer, err = recoverManageEntitySigner(testEmDomain, em)
+	require.NoError(t, err)
+	assert.NotEqual(t, walletOf(key), signer)
+
+	// so does signing for another chain
+	em.Metadata = `{"cid":"","data":{"title":"hi"}}`
+	signer, err = recoverManageEntitySigner(EntityManagerDomain{ChainId: 31524, ContractAddress: testEmDomain.ContractAddress}, em)
+	require.NoError(t, err)
+	assert.NotEqual(t, walletOf(key), signer)
+
+	em.Signature = "0x1234"
+	_, err = recoverManageEntitySigner(testEmDomain, em)
+	assert.Error(t, err)
+}
+
+// A fixture signed outside this package, over the SDK's EntityManager
+// typed data, so a domain or typehash that drifts from what clients sign
+// fails here rather than in production.
+// The key is the well known hardhat test account 0.
+func TestRecoverManageEntitySignerFixture(t *testing.T) {
+	domain := EntityManagerDomain{
+		ChainId:         31524,
+		ContractAddress: "0x1Cd8a543596D499B9b6E7a6eC15ECd2B7857Fd64",
+	}
+	em := &core_proto.ManageEntityLegacy{
+		UserId:     1,
+		EntityId:   2,
+		Action:     "Create",
+		EntityType: "Track",
+		Metadata:   `{"cid":"","data":{"title":"fixture"}}`,
+		Nonce:      "0x0000000000000000000000000000000000000000000000000000000000000001",
+		Signature:  "0x74fd70e7905ab09f3fd762cd333204260a2b726bde5e59d26d7e54c99fba51111c5585075cb9c574230200bb9a3666a56e55807d59e705b7964da8197b045a691b",
+	}
+
+	hash, err := manageEntityHash(domain, em)
+	require.NoError(t, err)
+	assert.Equal(t, "0xd168f17c3748f4466412d154af6144782d84ded6f3e8b830db3228ba2e65aad6", hexutil.Encode(hash))
+
+	signer, err := recoverManageEntitySigner(domain, em)
+	require.NoError(t, err)
+	assert.Equal(t, "0xf39fd6e51aad88f6f4ce6ab8827279cfffb92266", signer)
+}
+
+func TestManageEntityAuthorization(t *testing.T) {
+	owner, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	manager, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	stranger, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	txInfo := TxInfo{txhash: "auth_tx"}
+
+	tests := []struct {
+		name       string
+		key        *ecdsa.PrivateKey
+		em         *core_proto.ManageEntityLegacy
+		applied    string
+		skipReason string
+	}{
+		{
+			name: "create user makes the signer the wallet",
+			key:  owner,
+			em: &core_proto.ManageEntityLegacy{
+				Action: "Create", EntityType: "User", UserId: 81, EntityId: 81,
+				Metadata: toMetadata(map[string]any{"handle": "auth81"}),
+			},
+			applied: `select count(*) from users where user_id = 81 and wallet = '` + walletOf(owner) + `'`,
+		},
+		{
+			name: "owner can act as user",
+			key:  owner,
+			em: &core_proto.ManageEntityLegacy{
+				Action: "Create", EntityType: "Track", UserId: 81, EntityId: 81,
+				Metadata: toMetadata(map[string]any{"title": "owner track"}),
+			},
+			applied: `select count(*) from tracks where title = 'owner track'`,
+		},
+		{
+			name: "stranger cannot act as user",
+			key:  stranger,
+			em: &core_proto.ManageEntityLegacy{
+				Action: "Create", EntityType: "Track", UserId: 81, EntityId: 82,
+				Metadata: toMetadata(map[string]any{"title": "stranger track"}),
+			},
+			skipReason: "signer " + walletOf(stranger) + " is not authorized for user 81",
+		},
+		{
+			name: "manager with approved grant can act as user",
+			key:  manager,
+			em: &core_proto.ManageEntityLegacy{
+				Action: "Create", EntityType: "Track", UserId: 81, EntityId: 83,
+				Metadata: toMetadata(map[string]any{"title": "manager track"}),
+			},
+			applied: `select count(*) from tracks where title = 'manager track'`,
+		},
+	}
+
+	_, err = ci.pool.Exec(ci.ctx, `
+		insert into grants (grantee_address, user_id, is_revoked, is_current, is_approved, updated_at, created_at, txhash)
+		values ($1, 81, false, true, true, now(), now(), 'grant_tx')
+		`, walletOf(manager))
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ci.handleTx(txInfo, signedManageEntityTx(t, tt.key, tt.em))
+			require.NoError(t, err)
+			if tt.applied != "" {
+				assertCount(t, 1, tt.applied)
+			}
+			if tt.skipReason != "" {
+				assertCount(t, 1, `select count(*) from skipped_transactions where txhash = 'auth_tx' and reason = '`+tt.skipReason+`'`)
+			}
+		})
+	}
+
+	// revoked grants no longer authorize
+	_, err = ci.pool.Exec(ci.ctx, `update grants set is_revoked = true where user_id = 81`)
+	require.NoError(t, err)
+
+	err = ci.handleTx(TxInfo{txhash: "revoked_tx"}, signedManageEntityTx(t, manager, &core_proto.ManageEntityLegacy{
+		Action: "Create", EntityType: "Track", UserId: 81, EntityId: 84,
+		Metadata: toMetadata(map[string]any{"title": "revoked track"}),
+	}))
+	require.NoError(t, err)
+	assertCount(t, 0, `select count(*) from tracks where title = 'revoked track'`)
+	assertCount(t, 1, `select count(*) from skipped_transactions where txhash = 'revoked_tx'`)
+
+	// unsigned transactions are skipped
+	err = ci.handleTx(TxInfo{txhash: "unsigned_tx"}, manageEntityTx(&core_proto.ManageEntityLegacy{
+		Action: "Create", EntityType: "Track", UserId: 81, EntityId: 85,
+	}))
+	require.NoError(t, err)
+	assertCount(t, 1, `select count(*) from skipped_transactions where txhash = 'unsigned_tx' and reason like 'invalid signature%'`)
+}
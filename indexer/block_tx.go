@@ -0,0 +1,152 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"bridgerton.audius.co/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// indexerDB is what transaction handlers write through.
+// Outside of a block it is the pool itself; while a block is applied
+// it is a blockTx.
+type indexerDB interface {
+	database.DBTX
+	// QueueExec runs sql no later than the next read or Exec.
+	// Inside a blockTx its error is reported when the queue is flushed.
+	QueueExec(ctx context.Context, sql string, args ...any) error
+}
+
+type poolDB struct {
+	*pgxpool.Pool
+}
+
+func (p poolDB) QueueExec(ctx context.Context, sql string, args ...any) error {
+	_, err := p.Exec(ctx, sql, args...)
+	return err
+}
+
+// blockTx applies a whole block inside one database transaction.
+// Writes are queued into a pgx.Batch and sent in a single round trip
+// the next time the handler reads, so a transaction's inserts cost
+// one round trip rather than one each.
+type blockTx struct {
+	tx    pgx.Tx
+	batch *pgx.Batch
+}
+
+func newBlockTx(tx pgx.Tx) *blockTx {
+	return &blockTx{
+		tx:    tx,
+		batch: &pgx.Batch{},
+	}
+}
+
+func (b *blockTx) QueueExec(ctx context.Context, sql string, args ...any) error {
+	b.batch.Queue(sql, args...)
+	return nil
+}
+
+func (b *blockTx) flush(ctx context.Context) error {
+	if b.batch.Len() == 0 {
+		return nil
+	}
+	batch := b.batch
+	b.batch = &pgx.Batch{}
+	return b.tx.SendBatch(ctx, batch).Close()
+}
+
+func (b *blockTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if err := b.flush(ctx); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return b.tx.Exec(ctx, sql, args...)
+}
+
+func (b *blockTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if err := b.flush(ctx); err != nil {
+		return nil, err
+	}
+	return b.tx.Query(ctx, sql, args...)
+}
+
+func (b *blockTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if err := b.flush(ctx); err != nil {
+		return errRow{err}
+	}
+	return b.tx.QueryRow(ctx, sql, args...)
+}
+
+// Each core transaction runs under a savepoint, so one that turns out
+// to be invalid can be undone without giving up on the rest of the block.
+func (b *blockTx) savepoint(ctx context.Context) error {
+	_, err := b.Exec(ctx, "SAVEPOINT core_tx")
+	return err
+}
+
+func (b *blockTx) releaseSavepoint(ctx context.Context) error {
+	_, err := b.Exec(ctx, "RELEASE SAVEPOINT core_tx")
+	return err
+}
+
+func (b *blockTx) rollbackToSavepoint(ctx context.Context) error {
+	b.batch = &pgx.Batch{}
+	if _, err := b.tx.Exec(ctx, "ROLLBACK TO SAVEPOINT core_tx"); err != nil {
+		return err
+	}
+	_, err := b.tx.Exec(ctx, "RELEASE SAVEPOINT core_tx")
+	return err
+}
+
+func (b *blockTx) commit(ctx context.Context) error {
+	if err := b.flush(ctx); err != nil {
+		return err
+	}
+	return b.tx.Commit(ctx)
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...any) error {
+	return r.err
+}
+
+// Queues a copy of the rows matching where into revert_blocks
+// under tableName, so the block that changes them can be reverted.
+// It must be queued before the statement that changes the rows.
+func (ci *CoreIndexer) snapshot(txInfo TxInfo, tableName string, where string, args pgx.NamedArgs) error {
+	// only blocks with a legacy block number can be reverted
+	if txInfo.blocknumber == 0 {
+		return nil
+	}
+
+	stmt := fmt.Sprintf(`
+		WITH prev AS (
+			SELECT jsonb_agg(to_jsonb(t)) AS records
+			FROM %s t
+			WHERE %s
+		)
+		INSERT INTO revert_blocks (blocknumber, prev_records)
+		SELECT @revert_blocknumber, jsonb_build_object(@revert_table::text, prev.records)
+		FROM prev
+		WHERE prev.records IS NOT NULL
+		ON CONFLICT (blocknumber) DO UPDATE SET prev_records = revert_blocks.prev_records || jsonb_build_object(
+			@revert_table::text,
+			COALESCE(revert_blocks.prev_records->@revert_table::text, '[]'::jsonb) || EXCLUDED.prev_records->@revert_table::text
+		)
+		`, tableName, where)
+
+	snapshotArgs := pgx.NamedArgs{
+		"revert_blocknumber": txInfo.blocknumber,
+		"revert_table":       tableName,
+	}
+	maps.Copy(snapshotArgs, args)
+
+	return ci.db.QueueExec(ci.ctx, stmt, snapshotArgs)
+}
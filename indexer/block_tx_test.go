@@ -0,0 +1,114 @@
+package indexer
+
+import (
+	"testing"
+
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestIndexBlockTransaction(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	ti := &CoreIndexer{
+		ctx:      ci.ctx,
+		pool:     ci.pool,
+		logger:   zap.NewNop(),
+		emDomain: testEmDomain,
+		chainId:  "tx-chain",
+	}
+
+	block := testBlock(1,
+		signedManageEntityTx(t, key, &core_proto.ManageEntityLegacy{
+			Action: "Create", EntityType: "User", UserId: 1001, EntityId: 1001,
+			Metadata: toMetadata(map[string]any{"handle": "tx1001"}),
+		}),
+		signedManageEntityTx(t, key, &core_proto.ManageEntityLegacy{
+			Action: "Update", EntityType: "User", UserId: 1001, EntityId: 1003,
+			Metadata: toMetadata(map[string]any{"name": "not yours"}),
+		}),
+		signedManageEntityTx(t, key, &core_proto.ManageEntityLegacy{
+			Action: "Update", EntityType: "User", UserId: 1001, EntityId: 1001,
+			Metadata: toMetadata(map[string]any{"name": "tx after"}),
+		}),
+	)
+	block.Transactions[1].Hash = "bad_tx"
+	require.NoError(t, ti.indexBlock(ci.ctx, block))
+
+	// the invalid transaction is recorded, the rest of the block applies
+	assertCount(t, 1, `select count(*) from users where user_id = 1001 and name = 'tx after'`)
+	assertCount(t, 1, `select count(*) from skipped_transactions where txhash = 'bad_tx' and level = 'network' and reason = 'user 1001 cannot change user 1003'`)
+
+	// the update added a new current version of the user
+	assertCount(t, 2, `select count(*) from users where user_id = 1001`)
+	assertCount(t, 1, `select count(*) from users where user_id = 1001 and is_current = true and name = 'tx after'`)
+
+	// the user row as it was before the update is kept for reverts
+	assertCount(t, 1, `
+		select count(*)
+		from revert_blocks
+		join core_indexed_blocks on em_block = revert_blocks.blocknumber
+		where chain_id = 'tx-chain' and height = 1
+		and prev_records->'users'->0->>'handle' = 'tx1001'
+	`)
+
+	// a value the database rejects is the transaction's fault, not the block's
+	mistyped := testBlock(2,
+		signedManageEntityTx(t, key, &core_proto.ManageEntityLegacy{
+			Action: "Update", EntityType: "User", UserId: 1001, EntityId: 1001,
+			Metadata: toMetadata(map[string]any{"name": "tx kept"}),
+		}),
+		signedManageEntityTx(t, key, &core_proto.ManageEntityLegacy{
+			Action: "Update", EntityType: "User", UserId: 1001, EntityId: 1001,
+			Metadata: toMetadata(map[string]any{"artist_pick_track_id": "boom"}),
+		}),
+	)
+	mistyped.Transactions[1].Hash = "mistyped_tx"
+	require.NoError(t, ti.indexBlock(ci.ctx, mistyped))
+
+	assertCount(t, 1, `select count(*) from users where user_id = 1001 and is_current = true and name = 'tx kept'`)
+	assertCount(t, 1, `select count(*) from skipped_transactions where txhash = 'mistyped_tx' and level = 'network' and reason like '%SQLSTATE 22%'`)
+	assertCount(t, 1, `select count(*) from core_indexed_blocks where chain_id = 'tx-chain' and height = 2`)
+
+	// and so is a duplicate id
+	duplicate := testBlock(3,
+		signedManageEntityTx(t, key, &core_proto.ManageEntityLegacy{
+			Action: "Create", EntityType: "Comment", UserId: 1001, EntityId: 1010,
+			Metadata: toMetadata(map[string]any{"body": "first", "entity_id": 1001}),
+		}),
+		signedManageEntityTx(t, key, &core_proto.ManageEntityLegacy{
+			Action: "Create", EntityType: "Comment", UserId: 1001, EntityId: 1010,
+			Metadata: toMetadata(map[string]any{"body": "again", "entity_id": 1001}),
+		}),
+		signedManageEntityTx(t, key, &core_proto.ManageEntityLegacy{
+			Action: "Update", EntityType: "User", UserId: 1001, EntityId: 1001,
+			Metadata: toMetadata(map[string]any{"name": "after duplicate"}),
+		}),
+	)
+	duplicate.Transactions[1].Hash = "duplicate_comment_tx"
+	require.NoError(t, ti.indexBlock(ci.ctx, duplicate))
+
+	assertCount(t, 1, `select count(*) from comments where comment_id = 1010 and text = 'first'`)
+	assertCount(t, 1, `select count(*) from skipped_transactions where txhash = 'duplicate_comment_tx' and reason like '%comments_pkey%'`)
+	assertCount(t, 1, `select count(*) from users where user_id = 1001 and is_current = true and name = 'after duplicate'`)
+	assertCount(t, 1, `select count(*) from core_indexed_blocks where chain_id = 'tx-chain' and height = 3`)
+
+	var blockCount int
+	require.NoError(t, ci.pool.QueryRow(ci.ctx, `select count(*) from blocks`).Scan(&blockCount))
+
+	// height 1 is already indexed, so this block fails as a whole
+	conflicting := testBlock(1, signedManageEntityTx(t, key, &core_proto.ManageEntityLegacy{
+		Action: "Create", EntityType: "User", UserId: 1002, EntityId: 1002,
+		Metadata: toMetadata(map[string]any{"handle": "tx1002"}),
+	}))
+	err = ti.indexBlock(ci.ctx, conflicting)
+	assert.Error(t, err)
+
+	assertCount(t, 0, `select count(*) from users where user_id = 1002`)
+	assertCount(t, blockCount, `select count(*) from blocks`)
+	assertCount(t, 1, `select count(*) from blocks where is_current = true`)
+}
@@ -0,0 +1,202 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/AudiusProject/audiusd/pkg/common"
+	"github.com/jackc/pgx/v5"
+)
+
+// Sync indexes every block the source has past the last indexed height,
+// returning the height it stopped at.
+func (ci *CoreIndexer) Sync(ctx context.Context) (int64, error) {
+	height, err := ci.lastIndexedHeight(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return height, err
+		}
+
+		block, err := ci.source.GetBlock(ctx, height+1)
+		if err != nil {
+			return height, fmt.Errorf("error getting block %d: %w", height+1, err)
+		}
+		if block == nil {
+			return height, nil
+		}
+
+		if err := ci.indexBlock(ctx, block); err != nil {
+			return height, fmt.Errorf("error indexing block %d: %w", block.Height, err)
+		}
+		height = block.Height
+	}
+}
+
+func (ci *CoreIndexer) lastIndexedHeight(ctx context.Context) (int64, error) {
+	var height int64
+	err := ci.pool.QueryRow(ctx, `
+		SELECT COALESCE(MAX(height), 0)
+		FROM core_indexed_blocks
+		WHERE chain_id = $1
+		`, ci.chainId).Scan(&height)
+	return height, err
+}
+
+// Applies a block in a single database transaction, so a block
+// is either fully indexed or not indexed at all.
+func (ci *CoreIndexer) indexBlock(ctx context.Context, block *core_proto.Block) error {
+	tx, err := ci.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	btx := newBlockTx(tx)
+	bi := *ci
+	bi.db = btx
+
+	if err := bi.applyBlock(ctx, btx, block); err != nil {
+		return err
+	}
+	return btx.commit(ctx)
+}
+
+func (ci *CoreIndexer) applyBlock(ctx context.Context, btx *blockTx, block *core_proto.Block) error {
+	txInfo := TxInfo{
+		blockhash: block.Hash,
+		timestamp: block.Timestamp.AsTime(),
+		height:    block.Height,
+		seenPlays: map[string]bool{},
+	}
+
+	// Entity tables reference the legacy blocks table by number,
+	// so only blocks that carry ManageEntity transactions get a row there.
+	var emBlock *int
+	if hasManageEntity(block) {
+		number, err := ci.createEmBlock(ctx, block)
+		if err != nil {
+			return fmt.Errorf("error creating legacy block: %w", err)
+		}
+		emBlock = &number
+		txInfo.blocknumber = number
+	}
+
+	// plays use the block height as their slot
+	var playsSlot int64
+	if hasPlays(block) {
+		playsSlot = block.Height
+	}
+
+	for _, tx := range block.Transactions {
+		signedTx := tx.GetTransaction()
+		if signedTx == nil {
+			continue
+		}
+
+		txInfo.txhash = tx.Hash
+		if txInfo.txhash == "" {
+			txHash, err := common.ToTxHash(signedTx)
+			if err != nil {
+				return err
+			}
+			txInfo.txhash = txHash
+		}
+
+		if err := btx.savepoint(ctx); err != nil {
+			return err
+		}
+
+		// An invalid transaction is undone and recorded, since no node
+		// will ever apply it and it must not stall the chain.
+		// Anything else fails the whole block, so it is retried, not lost.
+		// Releasing the savepoint flushes the transaction's queued writes,
+		// so constraint and type errors in them are caught here too.
+		err := ci.applyTx(txInfo, signedTx)
+		if err == nil {
+			err = btx.releaseSavepoint(ctx)
+		}
+		err = asInvalidTx(err)
+		var invalid *invalidTxError
+		if errors.As(err, &invalid) {
+			if err := btx.rollbackToSavepoint(ctx); err != nil {
+				return fmt.Errorf("error rolling back transaction %s: %w", txInfo.txhash, err)
+			}
+			if skipErr := ci.skipInvalidTx(txInfo, err); skipErr != nil {
+				return skipErr
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error applying transaction %s: %w", txInfo.txhash, err)
+		}
+	}
+
+	return ci.db.QueueExec(ctx, `
+		INSERT INTO core_indexed_blocks (blockhash, parenthash, chain_id, height, em_block, plays_slot)
+		VALUES (
+			@blockhash,
+			(SELECT blockhash FROM core_indexed_blocks WHERE chain_id = @chain_id AND height = @height - 1),
+			@chain_id,
+			@height,
+			@em_block,
+			@plays_slot
+		)
+		`, pgx.NamedArgs{
+		"blockhash":  block.Hash,
+		"chain_id":   ci.chainId,
+		"height":     block.Height,
+		"em_block":   emBlock,
+		"plays_slot": playsSlot,
+	})
+}
+
+// Appends a row to the legacy blocks table for a core block,
+// returning its number.
+func (ci *CoreIndexer) createEmBlock(ctx context.Context, block *core_proto.Block) (int, error) {
+	var parenthash *string
+	var number int
+	err := ci.db.QueryRow(ctx, `
+		SELECT blockhash, number
+		FROM blocks
+		WHERE is_current = true
+		`).Scan(&parenthash, &number)
+	if err != nil && err != pgx.ErrNoRows {
+		return 0, err
+	}
+	number++
+
+	err = ci.db.QueueExec(ctx, `UPDATE blocks SET is_current = false WHERE is_current = true`)
+	if err != nil {
+		return 0, err
+	}
+
+	err = ci.db.QueueExec(ctx, `
+		INSERT INTO blocks (blockhash, parenthash, is_current, number)
+		VALUES ($1, $2, true, $3)
+		`, block.Hash, parenthash, number)
+	return number, err
+}
+
+func hasManageEntity(block *core_proto.Block) bool {
+	for _, tx := range block.Transactions {
+		if tx.GetTransaction().GetManageEntity() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPlays(block *core_proto.Block) bool {
+	for _, tx := range block.Transactions {
+		if tx.GetTransaction().GetPlays() != nil {
+			return true
+		}
+	}
+	return false
+}
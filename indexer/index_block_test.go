@@ -0,0 +1,124 @@
+package indexer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type recordingBlockSource struct {
+	BlockSource
+	requested []int64
+}
+
+func (s *recordingBlockSource) GetBlock(ctx context.Context, height int64) (*core_proto.Block, error) {
+	s.requested = append(s.requested, height)
+	return s.BlockSource.GetBlock(ctx, height)
+}
+
+func testBlock(height int64, txs ...*core_proto.SignedTransaction) *core_proto.Block {
+	block := &core_proto.Block{
+		Height:    height,
+		Hash:      "0xblock" + string(rune('a'+height)),
+		ChainId:   "test-chain",
+		Timestamp: timestamppb.New(time.Date(2025, 1, 1, 0, 0, int(height), 0, time.UTC)),
+	}
+	for _, tx := range txs {
+		block.Transactions = append(block.Transactions, &core_proto.Transaction{
+			Transaction: tx,
+		})
+	}
+	return block
+}
+
+func manageEntityTx(em *core_proto.ManageEntityLegacy) *core_proto.SignedTransaction {
+	return &core_proto.SignedTransaction{
+		Transaction: &core_proto.SignedTransaction_ManageEntity{
+			ManageEntity: em,
+		},
+	}
+}
+
+func TestSyncBlocks(t *testing.T) {
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	blocks := []*core_proto.Block{
+		testBlock(1, signedManageEntityTx(t, key, &core_proto.ManageEntityLegacy{
+			Action:     "Create",
+			EntityType: "User",
+			UserId:     71,
+			EntityId:   71,
+			Metadata: toMetadata(map[string]any{
+				"handle": "blocky71",
+			}),
+		})),
+		// empty blocks still advance the cursor
+		testBlock(2),
+		testBlock(3, signedManageEntityTx(t, key, &core_proto.ManageEntityLegacy{
+			Action:     "Update",
+			EntityType: "User",
+			UserId:     71,
+			EntityId:   71,
+			Metadata: toMetadata(map[string]any{
+				"name": "blocky after",
+			}),
+		})),
+	}
+
+	// write a fixture file, then serve the first two blocks from it
+	path := filepath.Join(t.TempDir(), "blocks.pb")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, WriteBlocks(file, blocks[:2]...))
+	require.NoError(t, file.Close())
+
+	fileSource, err := NewFileBlockSource(path)
+	require.NoError(t, err)
+
+	newTestIndexer := func(source BlockSource) *CoreIndexer {
+		return &CoreIndexer{
+			ctx:      ctx,
+			pool:     ci.pool,
+			source:   source,
+			logger:   zap.NewNop(),
+			emDomain: testEmDomain,
+			chainId:  "test-chain",
+		}
+	}
+
+	height, err := newTestIndexer(fileSource).Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), height)
+
+	assertCount(t, 1, `select count(*) from users where handle = 'blocky71'`)
+	assertCount(t, 2, `select count(*) from core_indexed_blocks where chain_id = 'test-chain'`)
+	assertCount(t, 1, `select count(*) from core_indexed_blocks where chain_id = 'test-chain' and height = 2 and parenthash = '0xblockb'`)
+
+	// block 2 had no ManageEntity txs so got no legacy block
+	assertCount(t, 1, `select count(*) from core_indexed_blocks where chain_id = 'test-chain' and em_block is not null`)
+
+	// the real block hash and timestamp are used, not placeholders
+	assertCount(t, 1, `select count(*) from users where handle = 'blocky71' and created_at = '2025-01-01 00:00:01'`)
+	assertCount(t, 1, `select count(*) from blocks where blockhash = '0xblockb' and is_current = true`)
+
+	// restart: a fresh indexer resumes after the last indexed height
+	source := &recordingBlockSource{BlockSource: NewStaticBlockSource(blocks...)}
+	height, err = newTestIndexer(source).Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), height)
+	assert.Equal(t, []int64{3, 4}, source.requested)
+
+	assertCount(t, 1, `select count(*) from users where handle = 'blocky71' and name = 'blocky after'`)
+	assertCount(t, 3, `select count(*) from core_indexed_blocks where chain_id = 'test-chain'`)
+}
@@ -1,29 +1,204 @@
 package indexer
 
 import (
-	"encoding/json"
-
 	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
 	"github.com/jackc/pgx/v5"
 )
 
 func (ci *CoreIndexer) createComment(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
 	var metadata GenericMetadata
-	if err := json.Unmarshal([]byte(em.Metadata), &metadata); err != nil {
+	if err := parseMetadata(em, &metadata); err != nil {
 		return err
 	}
 
+	entityType, _ := metadata.Data["entity_type"].(string)
+	if entityType == "" {
+		entityType = "Track"
+	}
+
 	args := pgx.NamedArgs{
-		"comment_id":  metadata.Data["comment_id"],
-		"user_id":     em.UserId,
-		"text":        metadata.Data["body"],
-		"entity_type": "track",
-		"entity_id":   metadata.Data["track_id"],
+		"comment_id":        em.EntityId,
+		"user_id":           em.UserId,
+		"text":              metadata.Data["body"],
+		"entity_type":       entityType,
+		"entity_id":         metadata.Data["entity_id"],
+		"track_timestamp_s": metadata.Data["track_timestamp_s"],
+		"created_at":        txInfo.timestamp,
+		"updated_at":        txInfo.timestamp,
 
 		"txhash":      txInfo.txhash,
 		"blockhash":   txInfo.blockhash,
 		"blocknumber": txInfo.blocknumber,
 	}
 
-	return ci.doInsert("comments", args)
+	if err := ci.doInsert("comments", args); err != nil {
+		return err
+	}
+
+	if parentId := metadata.Data["parent_comment_id"]; parentId != nil {
+		err := ci.doInsert("comment_threads", pgx.NamedArgs{
+			"comment_id":        em.EntityId,
+			"parent_comment_id": parentId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	mentions, _ := metadata.Data["mentions"].([]any)
+	for _, mentionedUserId := range mentions {
+		err := ci.doInsert("comment_mentions", pgx.NamedArgs{
+			"comment_id":  em.EntityId,
+			"user_id":     mentionedUserId,
+			"created_at":  txInfo.timestamp,
+			"updated_at":  txInfo.timestamp,
+			"txhash":      txInfo.txhash,
+			"blockhash":   txInfo.blockhash,
+			"blocknumber": txInfo.blocknumber,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ci *CoreIndexer) updateComment(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	var metadata GenericMetadata
+	if err := parseMetadata(em, &metadata); err != nil {
+		return err
+	}
+
+	return ci.doUpdate(txInfo, "comments",
+		pgx.NamedArgs{
+			"text":       metadata.Data["body"],
+			"is_edited":  true,
+			"updated_at": txInfo.timestamp,
+		},
+		pgx.NamedArgs{
+			"comment_id": em.EntityId,
+			"user_id":    em.UserId,
+		})
+}
+
+// Comments can be deleted by their author,
+// or by the owner of the track they were left on.
+func (ci *CoreIndexer) deleteComment(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	args := pgx.NamedArgs{
+		"comment_id": em.EntityId,
+		"user_id":    em.UserId,
+		"updated_at": txInfo.timestamp,
+	}
+	if err := ci.snapshot(txInfo, "comments", "comment_id = @comment_id", args); err != nil {
+		return err
+	}
+
+	return ci.db.QueueExec(ci.ctx, `
+		UPDATE comments
+		SET is_delete = true, updated_at = @updated_at
+		WHERE comment_id = @comment_id
+		AND (
+			user_id = @user_id
+			OR EXISTS (
+				SELECT 1 FROM tracks
+				WHERE tracks.track_id = comments.entity_id
+				AND tracks.owner_id = @user_id
+				AND tracks.is_current = true
+			)
+		)
+		`, args)
+}
+
+func (ci *CoreIndexer) reactComment(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	return ci.setCommentReaction(txInfo, em, false)
+}
+
+func (ci *CoreIndexer) unreactComment(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	return ci.setCommentReaction(txInfo, em, true)
+}
+
+func (ci *CoreIndexer) setCommentReaction(txInfo TxInfo, em *core_proto.ManageEntityLegacy, isDelete bool) error {
+	args := pgx.NamedArgs{
+		"comment_id":  em.EntityId,
+		"user_id":     em.UserId,
+		"is_delete":   isDelete,
+		"timestamp":   txInfo.timestamp,
+		"txhash":      txInfo.txhash,
+		"blockhash":   txInfo.blockhash,
+		"blocknumber": txInfo.blocknumber,
+	}
+	if err := ci.snapshot(txInfo, "comment_reactions", "comment_id = @comment_id AND user_id = @user_id", args); err != nil {
+		return err
+	}
+
+	return ci.db.QueueExec(ci.ctx, `
+		INSERT INTO comment_reactions (comment_id, user_id, created_at, updated_at, is_delete, txhash, blockhash, blocknumber)
+		VALUES (@comment_id, @user_id, @timestamp, @timestamp, @is_delete, @txhash, @blockhash, @blocknumber)
+		ON CONFLICT (comment_id, user_id) DO UPDATE SET
+			is_delete = @is_delete,
+			updated_at = @timestamp,
+			txhash = @txhash,
+			blockhash = @blockhash,
+			blocknumber = @blocknumber
+		`, args)
+}
+
+// Only the track owner can pin a comment, and only one on their own track.
+func (ci *CoreIndexer) pinComment(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	var metadata GenericMetadata
+	if err := parseMetadata(em, &metadata); err != nil {
+		return err
+	}
+
+	args := pgx.NamedArgs{
+		"comment_id": em.EntityId,
+		"track_id":   metadata.Data["entity_id"],
+		"user_id":    em.UserId,
+	}
+	if err := ci.snapshot(txInfo, "tracks", "track_id = @track_id AND owner_id = @user_id AND is_current = true", args); err != nil {
+		return err
+	}
+
+	return ci.db.QueueExec(ci.ctx, `
+		UPDATE tracks
+		SET pinned_comment_id = @comment_id
+		WHERE track_id = @track_id
+		AND owner_id = @user_id
+		AND is_current = true
+		AND EXISTS (
+			SELECT 1 FROM comments
+			WHERE comment_id = @comment_id
+			AND entity_id = @track_id
+			AND entity_type = 'Track'
+		)
+		`, args)
+}
+
+func (ci *CoreIndexer) unpinComment(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	args := pgx.NamedArgs{
+		"comment_id": em.EntityId,
+		"user_id":    em.UserId,
+	}
+	where := "pinned_comment_id = @comment_id AND owner_id = @user_id AND is_current = true"
+	if err := ci.snapshot(txInfo, "tracks", where, args); err != nil {
+		return err
+	}
+
+	return ci.db.QueueExec(ci.ctx, "UPDATE tracks SET pinned_comment_id = NULL WHERE "+where, args)
+}
+
+func (ci *CoreIndexer) reportComment(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	return ci.db.QueueExec(ci.ctx, `
+		INSERT INTO comment_reports (comment_id, user_id, created_at, updated_at, txhash, blockhash, blocknumber)
+		VALUES (@comment_id, @user_id, @timestamp, @timestamp, @txhash, @blockhash, @blocknumber)
+		ON CONFLICT DO NOTHING
+		`, pgx.NamedArgs{
+		"comment_id":  em.EntityId,
+		"user_id":     em.UserId,
+		"timestamp":   txInfo.timestamp,
+		"txhash":      txInfo.txhash,
+		"blockhash":   txInfo.blockhash,
+		"blocknumber": txInfo.blocknumber,
+	})
 }
@@ -0,0 +1,162 @@
+package indexer
+
+import (
+	"testing"
+
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComments(t *testing.T) {
+	// user 101 owns track 101, users 102 and 103 comment on it
+	err := ci.createTrack(TxInfo{}, &core_proto.ManageEntityLegacy{
+		UserId:   101,
+		EntityId: 101,
+		Metadata: toMetadata(map[string]any{"title": "commented track"}),
+	})
+	require.NoError(t, err)
+
+	runHandlerSteps(t, []handlerStep{
+		{
+			name:    "create",
+			handler: ci.createComment,
+			em: &core_proto.ManageEntityLegacy{
+				UserId:   102,
+				EntityId: 101,
+				Metadata: toMetadata(map[string]any{
+					"entity_id":   101,
+					"entity_type": "Track",
+					"body":        "first",
+					"mentions":    []int{101},
+				}),
+			},
+			count: 1,
+			query: `select count(*) from comments where comment_id = 101 and entity_id = 101 and entity_type = 'Track' and text = 'first'`,
+		},
+		{
+			name:    "reply",
+			handler: ci.createComment,
+			em: &core_proto.ManageEntityLegacy{
+				UserId:   103,
+				EntityId: 102,
+				Metadata: toMetadata(map[string]any{
+					"entity_id":         101,
+					"entity_type":       "Track",
+					"body":              "reply",
+					"parent_comment_id": 101,
+				}),
+			},
+			count: 1,
+			query: `select count(*) from comment_threads where comment_id = 102 and parent_comment_id = 101`,
+		},
+		{
+			name:    "update by author",
+			handler: ci.updateComment,
+			em: &core_proto.ManageEntityLegacy{
+				UserId:   102,
+				EntityId: 101,
+				Metadata: toMetadata(map[string]any{"body": "first edited"}),
+			},
+			count: 1,
+			query: `select count(*) from comments where comment_id = 101 and text = 'first edited' and is_edited = true`,
+		},
+		{
+			name:    "update by someone else is ignored",
+			handler: ci.updateComment,
+			em: &core_proto.ManageEntityLegacy{
+				UserId:   103,
+				EntityId: 101,
+				Metadata: toMetadata(map[string]any{"body": "hijacked"}),
+			},
+			count: 0,
+			query: `select count(*) from comments where text = 'hijacked'`,
+		},
+		{
+			name:    "react",
+			handler: ci.reactComment,
+			em:      &core_proto.ManageEntityLegacy{UserId: 103, EntityId: 101},
+			count:   1,
+			query:   `select count(*) from comment_reactions where comment_id = 101 and user_id = 103 and is_delete = false`,
+		},
+		{
+			name:    "unreact",
+			handler: ci.unreactComment,
+			em:      &core_proto.ManageEntityLegacy{UserId: 103, EntityId: 101},
+			count:   1,
+			query:   `select count(*) from comment_reactions where comment_id = 101 and user_id = 103 and is_delete = true`,
+		},
+		{
+			name:    "react again",
+			handler: ci.reactComment,
+			em:      &core_proto.ManageEntityLegacy{UserId: 103, EntityId: 101},
+			count:   1,
+			query:   `select count(*) from comment_reactions where comment_id = 101 and user_id = 103 and is_delete = false`,
+		},
+		{
+			name:    "pin by someone else is ignored",
+			handler: ci.pinComment,
+			em: &core_proto.ManageEntityLegacy{
+				UserId:   102,
+				EntityId: 101,
+				Metadata: toMetadata(map[string]any{"entity_id": 101}),
+			},
+			count: 0,
+			query: `select count(*) from tracks where track_id = 101 and pinned_comment_id is not null`,
+		},
+		{
+			name:    "pin by track owner",
+			handler: ci.pinComment,
+			em: &core_proto.ManageEntityLegacy{
+				UserId:   101,
+				EntityId: 101,
+				Metadata: toMetadata(map[string]any{"entity_id": 101}),
+			},
+			count: 1,
+			query: `select count(*) from tracks where track_id = 101 and pinned_comment_id = 101`,
+		},
+		{
+			name:    "unpin",
+			handler: ci.unpinComment,
+			em:      &core_proto.ManageEntityLegacy{UserId: 101, EntityId: 101},
+			count:   0,
+			query:   `select count(*) from tracks where track_id = 101 and pinned_comment_id is not null`,
+		},
+		{
+			name:    "report",
+			handler: ci.reportComment,
+			em:      &core_proto.ManageEntityLegacy{UserId: 103, EntityId: 101},
+			count:   1,
+			query:   `select count(*) from comment_reports where comment_id = 101 and user_id = 103`,
+		},
+		{
+			name:    "report twice is a no-op",
+			handler: ci.reportComment,
+			em:      &core_proto.ManageEntityLegacy{UserId: 103, EntityId: 101},
+			count:   1,
+			query:   `select count(*) from comment_reports where comment_id = 101 and user_id = 103`,
+		},
+		{
+			name:    "delete by someone else is ignored",
+			handler: ci.deleteComment,
+			em:      &core_proto.ManageEntityLegacy{UserId: 103, EntityId: 101},
+			count:   0,
+			query:   `select count(*) from comments where comment_id = 101 and is_delete = true`,
+		},
+		{
+			name:    "delete by author",
+			handler: ci.deleteComment,
+			em:      &core_proto.ManageEntityLegacy{UserId: 102, EntityId: 101},
+			count:   1,
+			query:   `select count(*) from comments where comment_id = 101 and is_delete = true`,
+		},
+		{
+			name:    "delete by track owner",
+			handler: ci.deleteComment,
+			em:      &core_proto.ManageEntityLegacy{UserId: 101, EntityId: 102},
+			count:   1,
+			query:   `select count(*) from comments where comment_id = 102 and is_delete = true`,
+		},
+	})
+
+	assertCount(t, 1, `select count(*) from comment_mentions where comment_id = 101 and user_id = 101`)
+}
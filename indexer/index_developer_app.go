@@ -0,0 +1,127 @@
+package indexer
+
+import (
+	"strings"
+
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/jackc/pgx/v5"
+)
+
+type developerAppMetadata struct {
+	Address          string  `json:"address"`
+	Name             string  `json:"name"`
+	Description      *string `json:"description"`
+	ImageUrl         *string `json:"image_url"`
+	IsPersonalAccess bool    `json:"is_personal_access"`
+	AppSignature     struct {
+		Message   string `json:"message"`
+		Signature string `json:"signature"`
+	} `json:"app_signature"`
+}
+
+func parseDeveloperAppMetadata(em *core_proto.ManageEntityLegacy) (developerAppMetadata, error) {
+	var metadata struct {
+		Data developerAppMetadata `json:"data"`
+	}
+	if err := parseMetadata(em, &metadata); err != nil {
+		return developerAppMetadata{}, err
+	}
+	if metadata.Data.Address == "" {
+		return developerAppMetadata{}, invalidTx("address is required")
+	}
+	metadata.Data.Address = strings.ToLower(metadata.Data.Address)
+	return metadata.Data, nil
+}
+
+// Creating an app requires a signature from the app's own key,
+// so a user can't register an address they don't control.
+func (ci *CoreIndexer) createDeveloperApp(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	app, err := parseDeveloperAppMetadata(em)
+	if err != nil {
+		return err
+	}
+	if app.Name == "" {
+		return invalidTx("name is required")
+	}
+
+	appSigner, err := recoverPersonalSigner(app.AppSignature.Message, app.AppSignature.Signature)
+	if err != nil {
+		return invalidTx("invalid app_signature: %s", err)
+	}
+	if appSigner != app.Address {
+		return invalidTx("app_signature signed by %s, not %s", appSigner, app.Address)
+	}
+	if err := ci.requireNewEntity("developer_apps", "address", app.Address); err != nil {
+		return err
+	}
+
+	return ci.doInsert("developer_apps", pgx.NamedArgs{
+		"address":            app.Address,
+		"blockhash":          txInfo.blockhash,
+		"blocknumber":        txInfo.blocknumber,
+		"user_id":            em.UserId,
+		"name":               app.Name,
+		"description":        app.Description,
+		"image_url":          app.ImageUrl,
+		"is_personal_access": app.IsPersonalAccess,
+		"is_delete":          false,
+		"is_current":         true,
+		"created_at":         txInfo.timestamp,
+		"updated_at":         txInfo.timestamp,
+		"txhash":             txInfo.txhash,
+	})
+}
+
+func (ci *CoreIndexer) updateDeveloperApp(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	app, err := parseDeveloperAppMetadata(em)
+	if err != nil {
+		return err
+	}
+
+	args := pgx.NamedArgs{
+		"description": app.Description,
+		"image_url":   app.ImageUrl,
+		"updated_at":  txInfo.timestamp,
+	}
+	if app.Name != "" {
+		args["name"] = app.Name
+	}
+
+	return ci.doUpdate(txInfo, "developer_apps", args, pgx.NamedArgs{
+		"address":   app.Address,
+		"user_id":   em.UserId,
+		"is_delete": false,
+	})
+}
+
+// Deleting an app also revokes every grant made to it.
+func (ci *CoreIndexer) deleteDeveloperApp(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	app, err := parseDeveloperAppMetadata(em)
+	if err != nil {
+		return err
+	}
+
+	args := pgx.NamedArgs{
+		"address":    app.Address,
+		"user_id":    em.UserId,
+		"updated_at": txInfo.timestamp,
+	}
+	where := "address = @address AND user_id = @user_id AND is_delete = false"
+	if err := ci.snapshot(txInfo, "developer_apps", where, args); err != nil {
+		return err
+	}
+
+	tag, err := ci.db.Exec(ci.ctx, `
+		UPDATE developer_apps
+		SET is_delete = true, updated_at = @updated_at
+		WHERE `+where, args)
+	if err != nil || tag.RowsAffected() == 0 {
+		return err
+	}
+
+	return ci.updateGrant(txInfo, pgx.NamedArgs{
+		"is_revoked": true,
+	}, "grantee_address = @grantee_address", pgx.NamedArgs{
+		"grantee_address": app.Address,
+	})
+}
@@ -0,0 +1,87 @@
+package indexer
+
+import (
+	"testing"
+
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeveloperApps(t *testing.T) {
+	appKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	appAddress := walletOf(appKey)
+
+	message := "Creating Audius developer app at 1735689600"
+	signature, err := crypto.Sign(accounts.TextHash([]byte(message)), appKey)
+	require.NoError(t, err)
+	signature[crypto.RecoveryIDOffset] += 27
+
+	otherKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	otherSignature, err := crypto.Sign(accounts.TextHash([]byte(message)), otherKey)
+	require.NoError(t, err)
+
+	appMetadata := func(name string, signature []byte) string {
+		return toMetadata(map[string]any{
+			"address":     appAddress,
+			"name":        name,
+			"description": name + " description",
+			"app_signature": map[string]any{
+				"message":   message,
+				"signature": hexutil.Encode(signature),
+			},
+		})
+	}
+
+	runHandlerSteps(t, []handlerStep{
+		{
+			name:    "create signed by another key",
+			handler: ci.createDeveloperApp,
+			em:      &core_proto.ManageEntityLegacy{UserId: 301, Metadata: appMetadata("stolen", otherSignature)},
+			wantErr: true,
+			count:   0,
+			query:   `select count(*) from developer_apps where address = '` + appAddress + `'`,
+		},
+		{
+			name:    "create",
+			handler: ci.createDeveloperApp,
+			em:      &core_proto.ManageEntityLegacy{UserId: 301, Metadata: appMetadata("my app", signature)},
+			count:   1,
+			query:   `select count(*) from developer_apps where address = '` + appAddress + `' and user_id = 301 and name = 'my app'`,
+		},
+		{
+			name:    "update by another user is ignored",
+			handler: ci.updateDeveloperApp,
+			em:      &core_proto.ManageEntityLegacy{UserId: 302, Metadata: appMetadata("hijacked", nil)},
+			count:   0,
+			query:   `select count(*) from developer_apps where name = 'hijacked'`,
+		},
+		{
+			name:    "update",
+			handler: ci.updateDeveloperApp,
+			em:      &core_proto.ManageEntityLegacy{UserId: 301, Metadata: appMetadata("renamed app", nil)},
+			count:   1,
+			query:   `select count(*) from developer_apps where address = '` + appAddress + `' and name = 'renamed app' and description = 'renamed app description'`,
+		},
+		{
+			name:    "grants to an app are approved immediately",
+			handler: ci.createGrant,
+			em:      &core_proto.ManageEntityLegacy{UserId: 302, Metadata: toMetadata(map[string]any{"grantee_address": appAddress})},
+			count:   1,
+			query:   `select count(*) from grants where user_id = 302 and grantee_address = '` + appAddress + `' and is_approved = true`,
+		},
+		{
+			name:    "delete revokes the app's grants",
+			handler: ci.deleteDeveloperApp,
+			em:      &core_proto.ManageEntityLegacy{UserId: 301, Metadata: toMetadata(map[string]any{"address": appAddress})},
+			count:   1,
+			query:   `select count(*) from grants where grantee_address = '` + appAddress + `' and is_revoked = true`,
+		},
+	})
+
+	assertCount(t, 1, `select count(*) from developer_apps where address = '`+appAddress+`' and is_delete = true`)
+}
@@ -0,0 +1,107 @@
+package indexer
+
+import (
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/jackc/pgx/v5"
+)
+
+type emailAccessGrant struct {
+	ReceivingUserId int64  `json:"receiving_user_id"`
+	GrantorUserId   int64  `json:"grantor_user_id"`
+	EncryptedKey    string `json:"encrypted_key"`
+}
+
+type emailMetadata struct {
+	EmailOwnerUserId int64              `json:"email_owner_user_id"`
+	EncryptedEmail   string             `json:"encrypted_email"`
+	AccessGrants     []emailAccessGrant `json:"access_grants"`
+}
+
+func parseEmailMetadata(em *core_proto.ManageEntityLegacy) (emailMetadata, error) {
+	var metadata struct {
+		Data emailMetadata `json:"data"`
+	}
+	err := parseMetadata(em, &metadata)
+	return metadata.Data, err
+}
+
+// A user stores their own encrypted email, along with the keys
+// that let the initial receivers decrypt it.
+func (ci *CoreIndexer) addEmail(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	email, err := parseEmailMetadata(em)
+	if err != nil {
+		return err
+	}
+	if email.EmailOwnerUserId != em.UserId {
+		return invalidTx("user %d cannot add an email for user %d", em.UserId, email.EmailOwnerUserId)
+	}
+
+	err = ci.db.QueueExec(ci.ctx, `
+		INSERT INTO encrypted_emails (email_owner_user_id, encrypted_email, created_at, updated_at)
+		VALUES (@email_owner_user_id, @encrypted_email, @timestamp, @timestamp)
+		ON CONFLICT (email_owner_user_id) DO NOTHING
+		`, pgx.NamedArgs{
+		"email_owner_user_id": email.EmailOwnerUserId,
+		"encrypted_email":     email.EncryptedEmail,
+		"timestamp":           txInfo.timestamp,
+	})
+	if err != nil {
+		return err
+	}
+
+	return ci.insertEmailAccess(txInfo, em, email, true)
+}
+
+// Shares an email with more receivers. The grantor must be the owner
+// or someone the email has already been shared with.
+func (ci *CoreIndexer) updateEmailAccess(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	email, err := parseEmailMetadata(em)
+	if err != nil {
+		return err
+	}
+
+	if email.EmailOwnerUserId != em.UserId {
+		var hasAccess bool
+		err := ci.db.QueryRow(ci.ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM email_access
+				WHERE email_owner_user_id = $1 AND receiving_user_id = $2
+			)
+			`, email.EmailOwnerUserId, em.UserId).Scan(&hasAccess)
+		if err != nil {
+			return err
+		}
+		if !hasAccess {
+			return invalidTx("user %d has no access to the email of user %d", em.UserId, email.EmailOwnerUserId)
+		}
+	}
+
+	return ci.insertEmailAccess(txInfo, em, email, false)
+}
+
+func (ci *CoreIndexer) insertEmailAccess(txInfo TxInfo, em *core_proto.ManageEntityLegacy, email emailMetadata, isInitial bool) error {
+	for _, grant := range email.AccessGrants {
+		if grant.GrantorUserId != em.UserId {
+			return invalidTx("user %d cannot grant email access as user %d", em.UserId, grant.GrantorUserId)
+		}
+	}
+
+	for _, grant := range email.AccessGrants {
+		err := ci.db.QueueExec(ci.ctx, `
+			INSERT INTO email_access (email_owner_user_id, receiving_user_id, grantor_user_id, encrypted_key, is_initial, created_at, updated_at)
+			VALUES (@email_owner_user_id, @receiving_user_id, @grantor_user_id, @encrypted_key, @is_initial, @timestamp, @timestamp)
+			ON CONFLICT (email_owner_user_id, receiving_user_id, grantor_user_id) DO NOTHING
+			`, pgx.NamedArgs{
+			"email_owner_user_id": email.EmailOwnerUserId,
+			"receiving_user_id":   grant.ReceivingUserId,
+			"grantor_user_id":     grant.GrantorUserId,
+			"encrypted_key":       grant.EncryptedKey,
+			"is_initial":          isInitial,
+			"timestamp":           txInfo.timestamp,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
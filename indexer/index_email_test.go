@@ -0,0 +1,65 @@
+package indexer
+
+import (
+	"testing"
+
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+)
+
+func TestEmailAccess(t *testing.T) {
+	email := func(ownerId int, grants ...map[string]any) string {
+		return toMetadata(map[string]any{
+			"email_owner_user_id": ownerId,
+			"encrypted_email":     "encrypted",
+			"access_grants":       grants,
+		})
+	}
+	grant := func(grantorId, receiverId int) map[string]any {
+		return map[string]any{
+			"grantor_user_id":   grantorId,
+			"receiving_user_id": receiverId,
+			"encrypted_key":     "key",
+		}
+	}
+
+	runHandlerSteps(t, []handlerStep{
+		{
+			name:    "add someone else's email",
+			handler: ci.addEmail,
+			em:      &core_proto.ManageEntityLegacy{UserId: 502, Metadata: email(501)},
+			wantErr: true,
+			count:   0,
+			query:   `select count(*) from encrypted_emails where email_owner_user_id = 501`,
+		},
+		{
+			name:    "add email shared with a seller",
+			handler: ci.addEmail,
+			em:      &core_proto.ManageEntityLegacy{UserId: 501, Metadata: email(501, grant(501, 502))},
+			count:   1,
+			query:   `select count(*) from email_access where email_owner_user_id = 501 and receiving_user_id = 502 and grantor_user_id = 501 and is_initial = true`,
+		},
+		{
+			name:    "receiver shares onward",
+			handler: ci.updateEmailAccess,
+			em:      &core_proto.ManageEntityLegacy{UserId: 502, Metadata: email(501, grant(502, 503))},
+			count:   1,
+			query:   `select count(*) from email_access where email_owner_user_id = 501 and receiving_user_id = 503 and grantor_user_id = 502 and is_initial = false`,
+		},
+		{
+			name:    "user without access cannot share",
+			handler: ci.updateEmailAccess,
+			em:      &core_proto.ManageEntityLegacy{UserId: 504, Metadata: email(501, grant(504, 505))},
+			wantErr: true,
+			count:   0,
+			query:   `select count(*) from email_access where receiving_user_id = 505`,
+		},
+		{
+			name:    "cannot grant as another user",
+			handler: ci.updateEmailAccess,
+			em:      &core_proto.ManageEntityLegacy{UserId: 501, Metadata: email(501, grant(502, 505))},
+			wantErr: true,
+			count:   0,
+			query:   `select count(*) from email_access where receiving_user_id = 505`,
+		},
+	})
+}
@@ -0,0 +1,80 @@
+package indexer
+
+import (
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/jackc/pgx/v5"
+)
+
+// Events are announced to followers by the on_event trigger,
+// so a user may only create an event about a track they own.
+func (ci *CoreIndexer) createEvent(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	var metadata GenericMetadata
+	if err := parseMetadata(em, &metadata); err != nil {
+		return err
+	}
+
+	if metadata.Data["entity_type"] == "track" {
+		var ownsTrack bool
+		err := ci.db.QueryRow(ci.ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM tracks
+				WHERE track_id = $1 AND owner_id = $2 AND is_current = true
+			)
+			`, metadata.Data["entity_id"], em.UserId).Scan(&ownsTrack)
+		if err != nil {
+			return err
+		}
+		if !ownsTrack {
+			return invalidTx("user %d does not own track %v", em.UserId, metadata.Data["entity_id"])
+		}
+	}
+
+	return ci.doInsert("events", pgx.NamedArgs{
+		"event_id":    em.EntityId,
+		"event_type":  metadata.Data["event_type"],
+		"user_id":     em.UserId,
+		"entity_type": metadata.Data["entity_type"],
+		"entity_id":   metadata.Data["entity_id"],
+		"end_date":    metadata.Data["end_date"],
+		"event_data":  metadata.Data["event_data"],
+		"is_deleted":  false,
+		"created_at":  txInfo.timestamp,
+		"updated_at":  txInfo.timestamp,
+		"txhash":      txInfo.txhash,
+		"blockhash":   txInfo.blockhash,
+		"blocknumber": txInfo.blocknumber,
+	})
+}
+
+func (ci *CoreIndexer) updateEvent(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	var metadata GenericMetadata
+	if err := parseMetadata(em, &metadata); err != nil {
+		return err
+	}
+
+	args := pgx.NamedArgs{
+		"updated_at": txInfo.timestamp,
+	}
+	for _, field := range []string{"end_date", "event_data"} {
+		if value, ok := metadata.Data[field]; ok {
+			args[field] = value
+		}
+	}
+
+	return ci.doUpdate(txInfo, "events", args, pgx.NamedArgs{
+		"event_id": em.EntityId,
+		"user_id":  em.UserId,
+	})
+}
+
+func (ci *CoreIndexer) deleteEvent(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	return ci.doUpdate(txInfo, "events",
+		pgx.NamedArgs{
+			"is_deleted": true,
+			"updated_at": txInfo.timestamp,
+		},
+		pgx.NamedArgs{
+			"event_id": em.EntityId,
+			"user_id":  em.UserId,
+		})
+}
@@ -0,0 +1,70 @@
+package indexer
+
+import (
+	"testing"
+
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvents(t *testing.T) {
+	err := ci.createTrack(TxInfo{}, &core_proto.ManageEntityLegacy{
+		UserId:   401,
+		EntityId: 401,
+		Metadata: toMetadata(map[string]any{"title": "contest track"}),
+	})
+	require.NoError(t, err)
+
+	contest := func(trackId int) string {
+		return toMetadata(map[string]any{
+			"event_type":  "remix_contest",
+			"entity_type": "track",
+			"entity_id":   trackId,
+			"end_date":    "2030-01-01T00:00:00Z",
+			"event_data":  map[string]any{"description": "remix me"},
+		})
+	}
+
+	runHandlerSteps(t, []handlerStep{
+		{
+			name:    "create for a track the user does not own",
+			handler: ci.createEvent,
+			em:      &core_proto.ManageEntityLegacy{UserId: 402, EntityId: 402, Metadata: contest(401)},
+			wantErr: true,
+			count:   0,
+			query:   `select count(*) from events where event_id = 402`,
+		},
+		{
+			name:    "create",
+			handler: ci.createEvent,
+			em:      &core_proto.ManageEntityLegacy{UserId: 401, EntityId: 401, Metadata: contest(401)},
+			count:   1,
+			query:   `select count(*) from events where event_id = 401 and event_type = 'remix_contest' and entity_id = 401 and event_data->>'description' = 'remix me'`,
+		},
+		{
+			name:    "update",
+			handler: ci.updateEvent,
+			em: &core_proto.ManageEntityLegacy{
+				UserId:   401,
+				EntityId: 401,
+				Metadata: toMetadata(map[string]any{"end_date": "2031-01-01T00:00:00Z"}),
+			},
+			count: 1,
+			query: `select count(*) from events where event_id = 401 and end_date = '2031-01-01' and event_data->>'description' = 'remix me'`,
+		},
+		{
+			name:    "delete by another user is ignored",
+			handler: ci.deleteEvent,
+			em:      &core_proto.ManageEntityLegacy{UserId: 402, EntityId: 401},
+			count:   0,
+			query:   `select count(*) from events where event_id = 401 and is_deleted = true`,
+		},
+		{
+			name:    "delete",
+			handler: ci.deleteEvent,
+			em:      &core_proto.ManageEntityLegacy{UserId: 401, EntityId: 401},
+			count:   1,
+			query:   `select count(*) from events where event_id = 401 and is_deleted = true`,
+		},
+	})
+}
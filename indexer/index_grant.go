@@ -0,0 +1,175 @@
+package indexer
+
+import (
+	"strings"
+
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/jackc/pgx/v5"
+)
+
+// Grants are updated in place rather than versioned,
+// so there is at most one row per grantor and grantee.
+func (ci *CoreIndexer) createGrant(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	granteeAddress, err := grantGranteeAddress(em)
+	if err != nil {
+		return err
+	}
+
+	// grants to a developer app take effect immediately,
+	// grants to another user wait for them to approve it
+	var isApproved *bool
+	var isDeveloperApp bool
+	err = ci.db.QueryRow(ci.ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM developer_apps
+			WHERE address = $1 AND is_current = true AND is_delete = false
+		)
+		`, granteeAddress).Scan(&isDeveloperApp)
+	if err != nil {
+		return err
+	}
+	if isDeveloperApp {
+		isApproved = &isDeveloperApp
+	}
+
+	args := pgx.NamedArgs{
+		"user_id":         em.UserId,
+		"grantee_address": granteeAddress,
+		"is_approved":     isApproved,
+		"timestamp":       txInfo.timestamp,
+		"txhash":          txInfo.txhash,
+		"blockhash":       txInfo.blockhash,
+		"blocknumber":     txInfo.blocknumber,
+	}
+	where := "user_id = @user_id AND grantee_address = @grantee_address AND is_current = true"
+	if err := ci.snapshot(txInfo, "grants", where, args); err != nil {
+		return err
+	}
+
+	tag, err := ci.db.Exec(ci.ctx, `
+		UPDATE grants
+		SET is_revoked = false,
+			is_approved = @is_approved,
+			updated_at = @timestamp,
+			txhash = @txhash,
+			blockhash = @blockhash,
+			blocknumber = @blocknumber
+		WHERE `+where, args)
+	if err != nil || tag.RowsAffected() > 0 {
+		return err
+	}
+
+	return ci.doInsert("grants", pgx.NamedArgs{
+		"blockhash":       txInfo.blockhash,
+		"blocknumber":     txInfo.blocknumber,
+		"grantee_address": granteeAddress,
+		"user_id":         em.UserId,
+		"is_revoked":      false,
+		"is_current":      true,
+		"is_approved":     isApproved,
+		"created_at":      txInfo.timestamp,
+		"updated_at":      txInfo.timestamp,
+		"txhash":          txInfo.txhash,
+	})
+}
+
+// The grantor revokes a grant they gave.
+func (ci *CoreIndexer) deleteGrant(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	granteeAddress, err := grantGranteeAddress(em)
+	if err != nil {
+		return err
+	}
+
+	return ci.updateGrant(txInfo, pgx.NamedArgs{
+		"is_revoked": true,
+	}, `
+		user_id = @user_id
+		AND grantee_address = @grantee_address
+		`, pgx.NamedArgs{
+		"user_id":         em.UserId,
+		"grantee_address": granteeAddress,
+	})
+}
+
+// The grantee accepts a pending grant.
+func (ci *CoreIndexer) approveGrant(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	return ci.respondToGrant(txInfo, em, pgx.NamedArgs{
+		"is_approved": true,
+	})
+}
+
+// The grantee declines a grant, which also revokes it.
+func (ci *CoreIndexer) rejectGrant(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	return ci.respondToGrant(txInfo, em, pgx.NamedArgs{
+		"is_approved": false,
+		"is_revoked":  true,
+	})
+}
+
+func (ci *CoreIndexer) respondToGrant(txInfo TxInfo, em *core_proto.ManageEntityLegacy, set pgx.NamedArgs) error {
+	var metadata GenericMetadata
+	if err := parseMetadata(em, &metadata); err != nil {
+		return err
+	}
+
+	grantorUserId := metadata.Data["grantor_user_id"]
+	if grantorUserId == nil {
+		return invalidTx("grantor_user_id is required")
+	}
+
+	return ci.updateGrant(txInfo, set, `
+		user_id = @grantor_user_id
+		AND is_revoked = false
+		AND grantee_address = (
+			SELECT lower(wallet) FROM users
+			WHERE user_id = @grantee_user_id AND is_current = true
+		)
+		`, pgx.NamedArgs{
+		"grantor_user_id": grantorUserId,
+		"grantee_user_id": em.UserId,
+	})
+}
+
+func (ci *CoreIndexer) updateGrant(txInfo TxInfo, set pgx.NamedArgs, where string, whereArgs pgx.NamedArgs) error {
+	sets := []string{
+		"updated_at = @updated_at",
+		"txhash = @txhash",
+		"blockhash = @blockhash",
+		"blocknumber = @blocknumber",
+	}
+	args := pgx.NamedArgs{
+		"updated_at":  txInfo.timestamp,
+		"txhash":      txInfo.txhash,
+		"blockhash":   txInfo.blockhash,
+		"blocknumber": txInfo.blocknumber,
+	}
+	for field, value := range set {
+		sets = append(sets, field+" = @"+field)
+		args[field] = value
+	}
+	for field, value := range whereArgs {
+		args[field] = value
+	}
+
+	where = "is_current = true AND " + where
+	if err := ci.snapshot(txInfo, "grants", where, whereArgs); err != nil {
+		return err
+	}
+
+	return ci.db.QueueExec(ci.ctx,
+		"UPDATE grants SET "+strings.Join(sets, ", ")+" WHERE "+where,
+		args)
+}
+
+func grantGranteeAddress(em *core_proto.ManageEntityLegacy) (string, error) {
+	var metadata GenericMetadata
+	if err := parseMetadata(em, &metadata); err != nil {
+		return "", err
+	}
+
+	granteeAddress, _ := metadata.Data["grantee_address"].(string)
+	if granteeAddress == "" {
+		return "", invalidTx("grantee_address is required")
+	}
+	return strings.ToLower(granteeAddress), nil
+}
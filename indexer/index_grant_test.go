@@ -0,0 +1,77 @@
+package indexer
+
+import (
+	"testing"
+
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrants(t *testing.T) {
+	managerWallet := "0x00000000000000000000000000000000000000cb"
+
+	// user 202 is a manager that user 201 wants to add
+	for _, userId := range []int64{201, 202} {
+		txInfo := TxInfo{}
+		if userId == 202 {
+			txInfo.signer = managerWallet
+		}
+		err := ci.createUser(txInfo, &core_proto.ManageEntityLegacy{
+			UserId:   userId,
+			EntityId: userId,
+			Metadata: toMetadata(map[string]any{"handle": "granter" + string(rune('a'+userId-201))}),
+		})
+		require.NoError(t, err)
+	}
+
+	grantTo := func(address string) string {
+		return toMetadata(map[string]any{"grantee_address": address})
+	}
+	fromGrantor := toMetadata(map[string]any{"grantor_user_id": 201})
+
+	runHandlerSteps(t, []handlerStep{
+		{
+			name:    "create grant to a user is pending",
+			handler: ci.createGrant,
+			em:      &core_proto.ManageEntityLegacy{UserId: 201, Metadata: grantTo("0x00000000000000000000000000000000000000CB")},
+			count:   1,
+			query:   `select count(*) from grants where user_id = 201 and grantee_address = '` + managerWallet + `' and is_approved is null and is_revoked = false`,
+		},
+		{
+			name:    "grantee approves",
+			handler: ci.approveGrant,
+			em:      &core_proto.ManageEntityLegacy{UserId: 202, Metadata: fromGrantor},
+			count:   1,
+			query:   `select count(*) from grants where user_id = 201 and grantee_address = '` + managerWallet + `' and is_approved = true`,
+		},
+		{
+			name:    "grantor revokes",
+			handler: ci.deleteGrant,
+			em:      &core_proto.ManageEntityLegacy{UserId: 201, Metadata: grantTo(managerWallet)},
+			count:   1,
+			query:   `select count(*) from grants where user_id = 201 and grantee_address = '` + managerWallet + `' and is_revoked = true`,
+		},
+		{
+			name:    "grant again reuses the row",
+			handler: ci.createGrant,
+			em:      &core_proto.ManageEntityLegacy{UserId: 201, Metadata: grantTo(managerWallet)},
+			count:   1,
+			query:   `select count(*) from grants where user_id = 201 and grantee_address = '` + managerWallet + `' and is_revoked = false and is_approved is null`,
+		},
+		{
+			name:    "grantee rejects",
+			handler: ci.rejectGrant,
+			em:      &core_proto.ManageEntityLegacy{UserId: 202, Metadata: fromGrantor},
+			count:   1,
+			query:   `select count(*) from grants where user_id = 201 and grantee_address = '` + managerWallet + `' and is_revoked = true and is_approved = false`,
+		},
+		{
+			name:    "missing grantee address",
+			handler: ci.createGrant,
+			em:      &core_proto.ManageEntityLegacy{UserId: 201, Metadata: toMetadata(map[string]any{})},
+			wantErr: true,
+			count:   1,
+			query:   `select count(*) from grants where user_id = 201`,
+		},
+	})
+}
@@ -0,0 +1,114 @@
+package indexer
+
+import (
+	"strconv"
+	"time"
+
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Inserts a batch of plays. Each insert fires the on_play trigger,
+// which maintains aggregate_plays, aggregate_monthly_plays and milestones;
+// the hourly counts and listen streaks are kept up to date here.
+func (ci *CoreIndexer) indexPlays(txInfo TxInfo, plays *core_proto.TrackPlays) error {
+	seen := txInfo.seenPlays
+	if seen == nil {
+		seen = map[string]bool{}
+	}
+
+	for _, play := range plays.GetPlays() {
+		trackId, err := strconv.ParseInt(play.TrackId, 10, 32)
+		if err != nil {
+			ci.logger.Debug("skipping play with invalid track id", zap.String("track_id", play.TrackId))
+			continue
+		}
+
+		// anonymous plays carry a non-numeric user id
+		var userId *int64
+		if id, err := strconv.ParseInt(play.UserId, 10, 32); err == nil {
+			userId = &id
+		}
+
+		createdAt := txInfo.timestamp
+		if play.Timestamp != nil {
+			createdAt = play.Timestamp.AsTime()
+		}
+
+		// clients may submit the same listen more than once,
+		// only the first copy in a block counts
+		key := play.UserId + ":" + play.TrackId + ":" + createdAt.String() + ":" + play.Signature
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		err = ci.doInsert("plays", pgx.NamedArgs{
+			"user_id":      userId,
+			"play_item_id": trackId,
+			"created_at":   createdAt,
+			"updated_at":   createdAt,
+			"slot":         txInfo.height,
+			"signature":    nullString(play.Signature),
+			"city":         nullString(play.City),
+			"region":       nullString(play.Region),
+			"country":      nullString(play.Country),
+		})
+		if err != nil {
+			return err
+		}
+
+		err = ci.db.QueueExec(ci.ctx, `
+			INSERT INTO hourly_play_counts (hourly_timestamp, play_count)
+			VALUES (date_trunc('hour', $1::timestamp), 1)
+			ON CONFLICT (hourly_timestamp) DO UPDATE SET
+				play_count = hourly_play_counts.play_count + 1
+			`, createdAt)
+		if err != nil {
+			return err
+		}
+
+		if userId != nil {
+			if err := ci.updateListenStreak(*userId, createdAt); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// A listen 16 to 48 hours after the last counted one extends the streak,
+// anything later starts a new one, and anything sooner is the same day.
+func (ci *CoreIndexer) updateListenStreak(userId int64, listenedAt time.Time) error {
+	return ci.db.QueueExec(ci.ctx, `
+		INSERT INTO challenge_listen_streak (user_id, last_listen_date, listen_streak)
+		VALUES (@user_id, @listened_at::timestamp, 1)
+		ON CONFLICT (user_id) DO UPDATE SET
+			listen_streak = CASE
+				WHEN challenge_listen_streak.last_listen_date IS NULL
+					OR @listened_at::timestamp - challenge_listen_streak.last_listen_date >= interval '48 hours'
+					THEN 1
+				WHEN @listened_at::timestamp - challenge_listen_streak.last_listen_date >= interval '16 hours'
+					THEN challenge_listen_streak.listen_streak + 1
+				ELSE challenge_listen_streak.listen_streak
+			END,
+			last_listen_date = CASE
+				WHEN challenge_listen_streak.last_listen_date IS NULL
+					OR @listened_at::timestamp - challenge_listen_streak.last_listen_date >= interval '16 hours'
+					THEN @listened_at::timestamp
+				ELSE challenge_listen_streak.last_listen_date
+			END
+		`, pgx.NamedArgs{
+		"user_id":     userId,
+		"listened_at": listenedAt,
+	})
+}
+
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,79 @@
+package indexer
+
+import (
+	"testing"
+	"time"
+
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func playsTx(plays ...*core_proto.TrackPlay) *core_proto.SignedTransaction {
+	return &core_proto.SignedTransaction{
+		Transaction: &core_proto.SignedTransaction_Plays{
+			Plays: &core_proto.TrackPlays{Plays: plays},
+		},
+	}
+}
+
+func TestPlays(t *testing.T) {
+	day := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	play := func(userId, trackId string, at time.Time) *core_proto.TrackPlay {
+		return &core_proto.TrackPlay{
+			UserId:    userId,
+			TrackId:   trackId,
+			Timestamp: timestamppb.New(at),
+			Signature: "sig-" + userId + "-" + at.String(),
+			City:      "Portland",
+			Region:    "OR",
+			Country:   "US",
+		}
+	}
+
+	// the same play arrives in two transactions of one block
+	block := testBlock(1,
+		playsTx(play("901", "901", day)),
+		playsTx(play("901", "901", day), play("not-a-user", "901", day)),
+		playsTx(play("901", "bogus", day)),
+	)
+	block.ChainId = "play-chain"
+	pi := &CoreIndexer{ctx: ci.ctx, pool: ci.pool, logger: ci.logger, chainId: "play-chain"}
+	require.NoError(t, pi.indexBlock(ci.ctx, block))
+
+	assertCount(t, 2, `select count(*) from plays where play_item_id = 901`)
+	assertCount(t, 1, `select count(*) from plays where play_item_id = 901 and user_id = 901 and city = 'Portland' and region = 'OR' and country = 'US' and slot = 1`)
+	assertCount(t, 1, `select count(*) from plays where play_item_id = 901 and user_id is null`)
+	assertCount(t, 2, `select count from aggregate_plays where play_item_id = 901`)
+	assertCount(t, 2, `select count from aggregate_monthly_plays where play_item_id = 901 and country = 'US'`)
+	assertCount(t, 1, `select plays_slot from core_indexed_blocks where chain_id = 'play-chain' and height = 1`)
+
+	tests := []struct {
+		name       string
+		listenedAt time.Time
+		streak     int
+	}{
+		{"same day does not extend", day.Add(2 * time.Hour), 1},
+		{"next day extends", day.Add(24 * time.Hour), 2},
+		{"day after extends again", day.Add(48 * time.Hour), 3},
+		{"gap of several days resets", day.Add(6 * 24 * time.Hour), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ci.indexPlays(TxInfo{}, &core_proto.TrackPlays{
+				Plays: []*core_proto.TrackPlay{play("901", "902", tt.listenedAt)},
+			})
+			require.NoError(t, err)
+			assertCount(t, tt.streak, `select listen_streak from challenge_listen_streak where user_id = 901`)
+		})
+	}
+
+	assertCount(t, 5, `select count(*) from plays where user_id = 901`)
+
+	var hourly int
+	err := ci.pool.QueryRow(ci.ctx, `select play_count from hourly_play_counts where hourly_timestamp = '2025-03-01 12:00:00'`).Scan(&hourly)
+	require.NoError(t, err)
+	assert.Equal(t, 2, hourly)
+}
@@ -1,17 +1,38 @@
 package indexer
 
 import (
-	"encoding/json"
-
-	"maps"
-
 	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
 	"github.com/jackc/pgx/v5"
 )
 
+// Columns a playlist's metadata may set.
+// Ids, ownership and block bookkeeping are the indexer's to set.
+var playlistMetadataFields = []string{
+	"playlist_name",
+	"description",
+	"is_private",
+	"playlist_contents",
+	"playlist_image_multihash",
+	"playlist_image_sizes_multihash",
+	"upc",
+	"is_image_autogenerated",
+	"stream_conditions",
+	"ddex_release_ids",
+	"artists",
+	"copyright_line",
+	"producer_copyright_line",
+	"parental_warning_type",
+	"is_scheduled_release",
+	"release_date",
+	"is_stream_gated",
+}
+
 func (ci *CoreIndexer) createPlaylist(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
 	var metadata GenericMetadata
-	if err := json.Unmarshal([]byte(em.Metadata), &metadata); err != nil {
+	if err := parseMetadata(em, &metadata); err != nil {
+		return err
+	}
+	if err := ci.requireNewEntity("playlists", "playlist_id", em.EntityId); err != nil {
 		return err
 	}
 
@@ -26,31 +47,50 @@ func (ci *CoreIndexer) createPlaylist(txInfo TxInfo, em *core_proto.ManageEntity
 		"created_at":             txInfo.timestamp,
 		"updated_at":             txInfo.timestamp,
 		"txhash":                 txInfo.txhash,
+		"blockhash":              txInfo.blockhash,
+		"blocknumber":            txInfo.blocknumber,
 		"is_image_autogenerated": false,
 		"is_scheduled_release":   false,
 	}
-	maps.Copy(args, metadata.Data)
-
-	delete(args, "cover_art_cid")
-	delete(args, "license")
-
-	args["playlist_id"] = em.EntityId
-	args["playlist_owner_id"] = em.UserId
+	copyMetadata(args, metadata, playlistMetadataFields)
 
 	return ci.doInsert("playlists", args)
 }
 
 func (ci *CoreIndexer) updatePlaylist(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
 	var metadata GenericMetadata
-	json.Unmarshal([]byte(em.Metadata), &metadata)
+	if err := parseMetadata(em, &metadata); err != nil {
+		return err
+	}
 
 	args := pgx.NamedArgs{}
-	maps.Copy(args, metadata.Data)
-
-	delete(args, "cover_art_cid")
+	copyMetadata(args, metadata, playlistMetadataFields)
+	if len(args) == 0 {
+		return nil
+	}
 
-	return ci.doUpdate("playlists", args, pgx.NamedArgs{
+	return ci.doVersionedUpdate(txInfo, "playlists", args, pgx.NamedArgs{
 		"playlist_id":       em.EntityId,
 		"playlist_owner_id": em.UserId,
 	})
 }
+
+func (ci *CoreIndexer) deletePlaylist(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	return ci.setPlaylistDeleted(txInfo, em, true)
+}
+
+func (ci *CoreIndexer) restorePlaylist(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	return ci.setPlaylistDeleted(txInfo, em, false)
+}
+
+func (ci *CoreIndexer) setPlaylistDeleted(txInfo TxInfo, em *core_proto.ManageEntityLegacy, isDelete bool) error {
+	return ci.doVersionedUpdate(txInfo, "playlists",
+		pgx.NamedArgs{
+			"is_delete":  isDelete,
+			"updated_at": txInfo.timestamp,
+		},
+		pgx.NamedArgs{
+			"playlist_id":       em.EntityId,
+			"playlist_owner_id": em.UserId,
+		})
+}
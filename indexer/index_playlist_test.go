@@ -8,10 +8,7 @@ import (
 )
 
 func TestUpdatePlaylist(t *testing.T) {
-
-	txInfo := TxInfo{}
-
-	err := ci.createPlaylist(txInfo, &core_proto.ManageEntityLegacy{
+	err := ci.createPlaylist(TxInfo{txhash: "playlist1_create"}, &core_proto.ManageEntityLegacy{
 		EntityId: 1,
 		UserId:   1,
 		Metadata: toMetadata(map[string]any{
@@ -21,7 +18,7 @@ func TestUpdatePlaylist(t *testing.T) {
 	assert.NoError(t, err)
 	assertCount(t, 1, `select count(*) from playlists where playlist_name = 'Test Playlist'`)
 
-	err = ci.updatePlaylist(txInfo, &core_proto.ManageEntityLegacy{
+	err = ci.updatePlaylist(TxInfo{txhash: "playlist1_update"}, &core_proto.ManageEntityLegacy{
 		EntityId: 1,
 		UserId:   1,
 		Metadata: toMetadata(map[string]any{
@@ -34,3 +31,38 @@ func TestUpdatePlaylist(t *testing.T) {
 	assert.NoError(t, err)
 	assertCount(t, 1, `select count(*) from playlists where playlist_name = 'Test Playlist 2'`)
 }
+
+func TestDeletePlaylist(t *testing.T) {
+	err := ci.createPlaylist(TxInfo{}, &core_proto.ManageEntityLegacy{
+		EntityId: 701,
+		UserId:   701,
+		Metadata: toMetadata(map[string]any{
+			"playlist_name": "Deleted Playlist",
+		}),
+	})
+	assert.NoError(t, err)
+
+	runHandlerSteps(t, []handlerStep{
+		{
+			name:    "delete by another user is ignored",
+			handler: ci.deletePlaylist,
+			em:      &core_proto.ManageEntityLegacy{UserId: 702, EntityId: 701},
+			count:   0,
+			query:   `select count(*) from playlists where playlist_id = 701 and is_current = true and is_delete = true`,
+		},
+		{
+			name:    "delete",
+			handler: ci.deletePlaylist,
+			em:      &core_proto.ManageEntityLegacy{UserId: 701, EntityId: 701},
+			count:   1,
+			query:   `select count(*) from playlists where playlist_id = 701 and is_current = true and is_delete = true`,
+		},
+		{
+			name:    "restore",
+			handler: ci.restorePlaylist,
+			em:      &core_proto.ManageEntityLegacy{UserId: 701, EntityId: 701},
+			count:   1,
+			query:   `select count(*) from playlists where playlist_id = 701 and is_current = true and is_delete = false`,
+		},
+	})
+}
@@ -23,7 +23,7 @@ func (ci *CoreIndexer) followUser(txInfo TxInfo, em *core_proto.ManageEntityLega
 }
 
 func (ci *CoreIndexer) unfollowUser(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
-	return ci.doUpdate("follows",
+	return ci.doUpdate(txInfo, "follows",
 		pgx.NamedArgs{
 			"is_delete": true,
 		},
@@ -74,7 +74,7 @@ func (ci *CoreIndexer) unfavorite(txInfo TxInfo, em *core_proto.ManageEntityLega
 		"txhash":      txInfo.txhash,
 		"slot":        500, // TODO
 	}
-	return ci.doUpdate("saves", args, pgx.NamedArgs{
+	return ci.doUpdate(txInfo, "saves", args, pgx.NamedArgs{
 		"user_id":      em.UserId,
 		"save_item_id": em.EntityId,
 		"save_type":    strings.ToLower(em.EntityType),
@@ -91,3 +91,85 @@ func (ci *CoreIndexer) viewNotification(txInfo TxInfo, em *core_proto.ManageEnti
 	}
 	return ci.doInsert("notification_seen", args)
 }
+
+func (ci *CoreIndexer) muteUser(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	return ci.setMutedUser(txInfo, em, false)
+}
+
+func (ci *CoreIndexer) unmuteUser(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	return ci.setMutedUser(txInfo, em, true)
+}
+
+func (ci *CoreIndexer) setMutedUser(txInfo TxInfo, em *core_proto.ManageEntityLegacy, isDelete bool) error {
+	args := pgx.NamedArgs{
+		"muted_user_id": em.EntityId,
+		"user_id":       em.UserId,
+		"is_delete":     isDelete,
+		"timestamp":     txInfo.timestamp,
+		"txhash":        txInfo.txhash,
+		"blockhash":     txInfo.blockhash,
+		"blocknumber":   txInfo.blocknumber,
+	}
+	if err := ci.snapshot(txInfo, "muted_users", "muted_user_id = @muted_user_id AND user_id = @user_id", args); err != nil {
+		return err
+	}
+
+	return ci.db.QueueExec(ci.ctx, `
+		INSERT INTO muted_users (muted_user_id, user_id, created_at, updated_at, is_delete, txhash, blockhash, blocknumber)
+		VALUES (@muted_user_id, @user_id, @timestamp, @timestamp, @is_delete, @txhash, @blockhash, @blocknumber)
+		ON CONFLICT (muted_user_id, user_id) DO UPDATE SET
+			is_delete = @is_delete,
+			updated_at = @timestamp,
+			txhash = @txhash,
+			blockhash = @blockhash,
+			blocknumber = @blocknumber
+		`, args)
+}
+
+func (ci *CoreIndexer) subscribeUser(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	return ci.setSubscription(txInfo, em, false)
+}
+
+func (ci *CoreIndexer) unsubscribeUser(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	return ci.setSubscription(txInfo, em, true)
+}
+
+// Subscriptions are versioned: the previous row is kept with
+// is_current = false and a new current row is added for each change.
+func (ci *CoreIndexer) setSubscription(txInfo TxInfo, em *core_proto.ManageEntityLegacy, isDelete bool) error {
+	where := "subscriber_id = @subscriber_id AND user_id = @user_id AND is_current = true"
+	whereArgs := pgx.NamedArgs{
+		"subscriber_id": em.UserId,
+		"user_id":       em.EntityId,
+	}
+	if err := ci.snapshot(txInfo, "subscriptions", where, whereArgs); err != nil {
+		return err
+	}
+	if err := ci.db.QueueExec(ci.ctx, "UPDATE subscriptions SET is_current = false WHERE "+where, whereArgs); err != nil {
+		return err
+	}
+
+	return ci.doInsert("subscriptions", pgx.NamedArgs{
+		"blockhash":     txInfo.blockhash,
+		"blocknumber":   txInfo.blocknumber,
+		"subscriber_id": em.UserId,
+		"user_id":       em.EntityId,
+		"is_current":    true,
+		"is_delete":     isDelete,
+		"created_at":    txInfo.timestamp,
+		"txhash":        txInfo.txhash,
+	})
+}
+
+func (ci *CoreIndexer) share(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	args := pgx.NamedArgs{
+		"blockhash":     txInfo.blockhash,
+		"blocknumber":   txInfo.blocknumber,
+		"user_id":       em.UserId,
+		"share_item_id": em.EntityId,
+		"share_type":    strings.ToLower(em.EntityType),
+		"created_at":    txInfo.timestamp,
+		"txhash":        txInfo.txhash,
+	}
+	return ci.doInsert("shares", args)
+}
@@ -32,3 +32,57 @@ func TestFollows(t *testing.T) {
 	// assertCount(t, 0, `select follower_count from aggregate_user where user_id = 22`)
 	// assertCount(t, 0, `select following_count from aggregate_user where user_id = 21`)
 }
+
+func TestMutesSubscriptionsShares(t *testing.T) {
+	runHandlerSteps(t, []handlerStep{
+		{
+			name:    "mute",
+			handler: ci.muteUser,
+			em:      &core_proto.ManageEntityLegacy{UserId: 601, EntityId: 602, EntityType: "User"},
+			count:   1,
+			query:   `select count(*) from muted_users where user_id = 601 and muted_user_id = 602 and is_delete = false`,
+		},
+		{
+			name:    "unmute",
+			handler: ci.unmuteUser,
+			em:      &core_proto.ManageEntityLegacy{UserId: 601, EntityId: 602, EntityType: "User"},
+			count:   1,
+			query:   `select count(*) from muted_users where user_id = 601 and muted_user_id = 602 and is_delete = true`,
+		},
+		{
+			name:    "subscribe",
+			handler: ci.subscribeUser,
+			em:      &core_proto.ManageEntityLegacy{UserId: 601, EntityId: 603, EntityType: "User"},
+			count:   1,
+			query:   `select count(*) from subscriptions where subscriber_id = 601 and user_id = 603 and is_current = true and is_delete = false`,
+		},
+		{
+			name:    "unsubscribe keeps history",
+			handler: ci.unsubscribeUser,
+			em:      &core_proto.ManageEntityLegacy{UserId: 601, EntityId: 603, EntityType: "User"},
+			count:   2,
+			query:   `select count(*) from subscriptions where subscriber_id = 601 and user_id = 603`,
+		},
+		{
+			name:    "only the latest subscription is current",
+			handler: ci.subscribeUser,
+			em:      &core_proto.ManageEntityLegacy{UserId: 601, EntityId: 603, EntityType: "User"},
+			count:   1,
+			query:   `select count(*) from subscriptions where subscriber_id = 601 and user_id = 603 and is_current = true and is_delete = false`,
+		},
+		{
+			name:    "share track",
+			handler: ci.share,
+			em:      &core_proto.ManageEntityLegacy{UserId: 601, EntityId: 604, EntityType: "Track"},
+			count:   1,
+			query:   `select count(*) from shares where user_id = 601 and share_item_id = 604 and share_type = 'track'`,
+		},
+		{
+			name:    "share playlist",
+			handler: ci.share,
+			em:      &core_proto.ManageEntityLegacy{UserId: 601, EntityId: 604, EntityType: "Playlist"},
+			count:   1,
+			query:   `select count(*) from shares where user_id = 601 and share_item_id = 604 and share_type = 'playlist'`,
+		},
+	})
+}
@@ -1,19 +1,88 @@
 package indexer
 
 import (
-	"encoding/json"
-	"maps"
-
 	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
 	"github.com/jackc/pgx/v5"
 )
 
+// Columns a track's metadata may set.
+// Ids, ownership and block bookkeeping are the indexer's to set.
+var trackMetadataFields = []string{
+	"title",
+	"cover_art",
+	"cover_art_sizes",
+	"tags",
+	"genre",
+	"mood",
+	"credits_splits",
+	"create_date",
+	"file_type",
+	"description",
+	"isrc",
+	"iswc",
+	"license",
+	"is_unlisted",
+	"field_visibility",
+	"stem_of",
+	"remix_of",
+	"stream_conditions",
+	"track_cid",
+	"is_playlist_upload",
+	"duration",
+	"ai_attribution_user_id",
+	"preview_cid",
+	"audio_upload_id",
+	"preview_start_seconds",
+	"release_date",
+	"track_segments",
+	"is_scheduled_release",
+	"is_downloadable",
+	"download_conditions",
+	"is_original_available",
+	"orig_file_cid",
+	"orig_filename",
+	"placement_hosts",
+	"ddex_release_ids",
+	"artists",
+	"resource_contributors",
+	"indirect_resource_contributors",
+	"rights_controller",
+	"copyright_line",
+	"producer_copyright_line",
+	"parental_warning_type",
+	"allowed_api_keys",
+	"bpm",
+	"musical_key",
+	"is_custom_bpm",
+	"is_custom_musical_key",
+	"comments_disabled",
+	"cover_original_song_title",
+	"cover_original_artist",
+	"is_stream_gated",
+	"is_download_gated",
+	"no_ai_use",
+	"territory_codes",
+}
+
+// Columns a download's metadata may set.
+var trackDownloadMetadataFields = []string{
+	"city",
+	"region",
+	"country",
+}
+
 func (ci *CoreIndexer) createTrack(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
 	var meta GenericMetadata
-	json.Unmarshal([]byte(em.Metadata), &meta)
+	if err := parseMetadata(em, &meta); err != nil {
+		return err
+	}
+	if err := ci.requireNewEntity("tracks", "track_id", em.EntityId); err != nil {
+		return err
+	}
 
 	args := pgx.NamedArgs{
 		"blockhash":                             txInfo.blockhash,
+		"blocknumber":                           txInfo.blocknumber,
 		"track_id":                              em.EntityId,
 		"is_current":                            true,
 		"is_delete":                             false,
@@ -34,30 +103,31 @@ func (ci *CoreIndexer) createTrack(txInfo TxInfo, em *core_proto.ManageEntityLeg
 		"is_owned_by_user":                      false,
 	}
 
-	for k, v := range meta.Data {
-		args[k] = v
-	}
+	copyMetadata(args, meta, trackMetadataFields)
 
 	return ci.doInsert("tracks", args)
 }
 
 func (ci *CoreIndexer) updateTrack(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
 	var metadata GenericMetadata
-	json.Unmarshal([]byte(em.Metadata), &metadata)
-
-	// todo: verify this user is authorized to edit
+	if err := parseMetadata(em, &metadata); err != nil {
+		return err
+	}
 
 	args := pgx.NamedArgs{}
-	maps.Copy(args, metadata.Data)
+	copyMetadata(args, metadata, trackMetadataFields)
+	if len(args) == 0 {
+		return nil
+	}
 
-	return ci.doUpdate("tracks", args, pgx.NamedArgs{
+	return ci.doVersionedUpdate(txInfo, "tracks", args, pgx.NamedArgs{
 		"track_id": em.EntityId,
 		"owner_id": em.UserId,
 	})
 }
 
 func (ci *CoreIndexer) deleteTrack(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
-	return ci.doUpdate("tracks",
+	return ci.doVersionedUpdate(txInfo, "tracks",
 		pgx.NamedArgs{
 			"is_delete": true,
 		},
@@ -69,7 +139,9 @@ func (ci *CoreIndexer) deleteTrack(txInfo TxInfo, em *core_proto.ManageEntityLeg
 
 func (ci *CoreIndexer) downloadTrack(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
 	var meta GenericMetadata
-	json.Unmarshal([]byte(em.Metadata), &meta)
+	if err := parseMetadata(em, &meta); err != nil {
+		return err
+	}
 
 	args := pgx.NamedArgs{
 		"blocknumber":     txInfo.blocknumber,
@@ -80,6 +152,6 @@ func (ci *CoreIndexer) downloadTrack(txInfo TxInfo, em *core_proto.ManageEntityL
 		"track_id":        em.EntityId,
 	}
 
-	maps.Copy(args, meta.Data)
+	copyMetadata(args, meta, trackDownloadMetadataFields)
 	return ci.doInsert("track_downloads", args)
 }
@@ -8,10 +8,8 @@ import (
 )
 
 func TestIndexTrack(t *testing.T) {
-	var txInfo TxInfo
-
 	// CREATE
-	err := ci.createTrack(txInfo, &core_proto.ManageEntityLegacy{
+	err := ci.createTrack(TxInfo{txhash: "track51_create"}, &core_proto.ManageEntityLegacy{
 		UserId:   51,
 		EntityId: 51,
 		Metadata: toMetadata(map[string]any{
@@ -22,7 +20,7 @@ func TestIndexTrack(t *testing.T) {
 	assertCount(t, 1, `select count(*) from tracks where title = 'track51'`)
 
 	// UPDATE
-	err = ci.updateTrack(txInfo, &core_proto.ManageEntityLegacy{
+	err = ci.updateTrack(TxInfo{txhash: "track51_update"}, &core_proto.ManageEntityLegacy{
 		UserId:   51,
 		EntityId: 51,
 		Metadata: toMetadata(map[string]any{
@@ -33,10 +31,60 @@ func TestIndexTrack(t *testing.T) {
 	assertCount(t, 1, `select count(*) from tracks where title = 'track51 update'`)
 
 	// DELETE
-	err = ci.deleteTrack(txInfo, &core_proto.ManageEntityLegacy{
+	err = ci.deleteTrack(TxInfo{txhash: "track51_delete"}, &core_proto.ManageEntityLegacy{
 		UserId:   51,
 		EntityId: 51,
 	})
 	assert.NoError(t, err)
 	assertCount(t, 1, `select count(*) from tracks where title = 'track51 update' and is_delete = true`)
+
+	// each change is a new version, and only the last is current
+	assertCount(t, 3, `select count(*) from tracks where track_id = 51`)
+	assertCount(t, 1, `select count(*) from tracks where track_id = 51 and is_current = true and is_delete = true`)
+}
+
+func TestTrackMetadataCannotSetOwner(t *testing.T) {
+	err := ci.createTrack(TxInfo{txhash: "track52_create"}, &core_proto.ManageEntityLegacy{
+		UserId:   52,
+		EntityId: 52,
+		Metadata: toMetadata(map[string]any{
+			"title":       "track52",
+			"owner_id":    53,
+			"track_id":    54,
+			"is_current":  false,
+			"blocknumber": 99,
+		}),
+	})
+	assert.NoError(t, err)
+	assertCount(t, 1, `select count(*) from tracks where track_id = 52 and owner_id = 52 and is_current = true and title = 'track52'`)
+
+	err = ci.updateTrack(TxInfo{txhash: "track52_update"}, &core_proto.ManageEntityLegacy{
+		UserId:   52,
+		EntityId: 52,
+		Metadata: toMetadata(map[string]any{"owner_id": 53}),
+	})
+	assert.NoError(t, err)
+	assertCount(t, 0, `select count(*) from tracks where owner_id = 53`)
+}
+
+func TestDownloadTrackMetadata(t *testing.T) {
+	err := ci.downloadTrack(TxInfo{txhash: "track53_download"}, &core_proto.ManageEntityLegacy{
+		UserId:   53,
+		EntityId: 53,
+		Metadata: toMetadata(map[string]any{
+			"city":                       "Paris",
+			"country":                    "France",
+			"blocknumber) values (1) --": 1,
+			"parent_track_id":            99,
+		}),
+	})
+	assert.NoError(t, err)
+	assertCount(t, 1, `select count(*) from track_downloads where track_id = 53 and parent_track_id = 53 and city = 'Paris' and country = 'France'`)
+
+	err = ci.downloadTrack(TxInfo{txhash: "track53_download_bad"}, &core_proto.ManageEntityLegacy{
+		UserId:   53,
+		EntityId: 53,
+		Metadata: "not json",
+	})
+	assert.Error(t, err)
 }
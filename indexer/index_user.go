@@ -1,67 +1,118 @@
 package indexer
 
 import (
-	"encoding/json"
 	"strings"
 
 	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
 	"github.com/jackc/pgx/v5"
 )
 
+// Columns a user's metadata may set.
+// Everything else, like the wallet or verification, is the indexer's to set.
+var userMetadataFields = []string{
+	"name",
+	"bio",
+	"location",
+	"profile_picture",
+	"profile_picture_sizes",
+	"cover_photo",
+	"cover_photo_sizes",
+	"creator_node_endpoint",
+	"playlist_library",
+	"artist_pick_track_id",
+	"allow_ai_attribution",
+	"spl_usdc_payout_wallet",
+	"twitter_handle",
+	"instagram_handle",
+	"tiktok_handle",
+	"website",
+	"donation",
+	"profile_type",
+}
+
 func (ci *CoreIndexer) createUser(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
 	var metadata GenericMetadata
-	json.Unmarshal([]byte(em.Metadata), &metadata)
+	if err := parseMetadata(em, &metadata); err != nil {
+		return err
+	}
+
+	handle, _ := metadata.Data["handle"].(string)
+	if handle == "" {
+		return invalidTx("user %d has no handle", em.EntityId)
+	}
+	if err := ci.requireNewEntity("users", "user_id", em.EntityId); err != nil {
+		return err
+	}
 
 	args := pgx.NamedArgs{
 		"user_id":              em.EntityId,
-		"handle_lc":            strings.ToLower(metadata.Data["handle"].(string)),
+		"handle":               handle,
+		"handle_lc":            strings.ToLower(handle),
 		"is_current":           true,
 		"is_verified":          false,
 		"created_at":           txInfo.timestamp,
 		"updated_at":           txInfo.timestamp,
 		"has_collectibles":     false,
 		"txhash":               txInfo.txhash,
+		"blockhash":            txInfo.blockhash,
+		"blocknumber":          txInfo.blocknumber,
 		"is_deactivated":       false,
 		"is_available":         true,
 		"is_storage_v2":        false,
 		"allow_ai_attribution": false,
 	}
+	copyMetadata(args, metadata, userMetadataFields)
 
-	for k, v := range metadata.Data {
-		if k == "events" {
-			continue
-		}
-		args[k] = v
+	// the wallet that signed the create owns the account
+	if txInfo.signer != "" {
+		args["wallet"] = txInfo.signer
 	}
 
 	return ci.doInsert("users", args)
 }
 
 func (ci *CoreIndexer) updateUser(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
-	var metadata GenericMetadata
-	json.Unmarshal([]byte(em.Metadata), &metadata)
-
-	args := pgx.NamedArgs{}
-
-	// todo: verify this user is authorized to edit
-	// todo: better validation of fields + values
+	if em.UserId != em.EntityId {
+		return invalidTx("user %d cannot change user %d", em.UserId, em.EntityId)
+	}
 
-	for k, v := range metadata.Data {
-		if k == "events" || k == "handle" {
-			continue
-		}
-		args[k] = v
+	var metadata GenericMetadata
+	if err := parseMetadata(em, &metadata); err != nil {
+		return err
 	}
 
-	if _, exists := args["isDeactivated"]; exists {
-		// sometimes metadata is camelCase
-		// which breaks doUpdate
-		// so silently skip for now...
-		// todo: better validation
+	// handles can't be changed by an update
+	args := pgx.NamedArgs{}
+	copyMetadata(args, metadata, userMetadataFields)
+	if len(args) == 0 {
 		return nil
 	}
 
-	return ci.doUpdate("users", args, pgx.NamedArgs{
+	return ci.doVersionedUpdate(txInfo, "users", args, pgx.NamedArgs{
 		"user_id": em.EntityId,
 	})
 }
+
+// Users are never removed, deleting one deactivates the account.
+func (ci *CoreIndexer) deleteUser(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	return ci.setUserDeactivated(txInfo, em, true)
+}
+
+func (ci *CoreIndexer) restoreUser(txInfo TxInfo, em *core_proto.ManageEntityLegacy) error {
+	return ci.setUserDeactivated(txInfo, em, false)
+}
+
+func (ci *CoreIndexer) setUserDeactivated(txInfo TxInfo, em *core_proto.ManageEntityLegacy, isDeactivated bool) error {
+	if em.UserId != em.EntityId {
+		return invalidTx("user %d cannot change user %d", em.UserId, em.EntityId)
+	}
+
+	return ci.doVersionedUpdate(txInfo, "users",
+		pgx.NamedArgs{
+			"is_deactivated": isDeactivated,
+			"updated_at":     txInfo.timestamp,
+		},
+		pgx.NamedArgs{
+			"user_id": em.EntityId,
+		})
+}
@@ -8,8 +8,7 @@ import (
 )
 
 func TestUser(t *testing.T) {
-	txInfo := TxInfo{}
-	err := ci.createUser(txInfo, &core_proto.ManageEntityLegacy{
+	err := ci.createUser(TxInfo{txhash: "user33_create"}, &core_proto.ManageEntityLegacy{
 		UserId:   33,
 		EntityId: 33,
 		Metadata: toMetadata(map[string]any{
@@ -20,7 +19,7 @@ func TestUser(t *testing.T) {
 	assert.NoError(t, err)
 	assertCount(t, 1, `select count(*) from users where name = 'test33_before'`)
 
-	err = ci.updateUser(txInfo, &core_proto.ManageEntityLegacy{
+	err = ci.updateUser(TxInfo{txhash: "user33_update"}, &core_proto.ManageEntityLegacy{
 		UserId:   33,
 		EntityId: 33,
 		Metadata: toMetadata(map[string]any{
@@ -29,10 +28,142 @@ func TestUser(t *testing.T) {
 		}),
 	})
 	assert.NoError(t, err)
-	assertCount(t, 1, `select count(*) from users where name = 'test33_after'`)
+	assertCount(t, 1, `select count(*) from users where name = 'test33_after' and is_current = true`)
+
+	// the previous version is kept
+	assertCount(t, 1, `select count(*) from users where name = 'test33_before' and is_current = false`)
 
 	// handle edit ignored
 	assertCount(t, 0, `select count(*) from users where handle = 'test33_after'`)
-	assertCount(t, 1, `select count(*) from users where handle = 'test33'`)
+	assertCount(t, 1, `select count(*) from users where handle = 'test33' and is_current = true`)
+
+}
+
+func TestDeleteUser(t *testing.T) {
+	err := ci.createUser(TxInfo{}, &core_proto.ManageEntityLegacy{
+		UserId:   801,
+		EntityId: 801,
+		Metadata: toMetadata(map[string]any{
+			"handle": "test801",
+		}),
+	})
+	assert.NoError(t, err)
+
+	runHandlerSteps(t, []handlerStep{
+		{
+			name:    "delete someone else",
+			handler: ci.deleteUser,
+			em:      &core_proto.ManageEntityLegacy{UserId: 802, EntityId: 801},
+			wantErr: true,
+			count:   0,
+			query:   `select count(*) from users where user_id = 801 and is_current = true and is_deactivated = true`,
+		},
+		{
+			name:    "delete deactivates",
+			handler: ci.deleteUser,
+			em:      &core_proto.ManageEntityLegacy{UserId: 801, EntityId: 801},
+			count:   1,
+			query:   `select count(*) from users where user_id = 801 and is_current = true and is_deactivated = true`,
+		},
+		{
+			name:    "restore reactivates",
+			handler: ci.restoreUser,
+			em:      &core_proto.ManageEntityLegacy{UserId: 801, EntityId: 801},
+			count:   1,
+			query:   `select count(*) from users where user_id = 801 and is_current = true and is_deactivated = false`,
+		},
+	})
+}
+
+func TestUserMetadataCannotTakeOver(t *testing.T) {
+	err := ci.createUser(TxInfo{signer: "0xowner811"}, &core_proto.ManageEntityLegacy{
+		UserId:   811,
+		EntityId: 811,
+		Metadata: toMetadata(map[string]any{
+			"handle":      "test811",
+			"wallet":      "0xattacker",
+			"is_verified": true,
+		}),
+	})
+	assert.NoError(t, err)
+	assertCount(t, 1, `select count(*) from users where user_id = 811 and wallet = '0xowner811' and is_verified = false`)
 
+	runHandlerSteps(t, []handlerStep{
+		{
+			name:    "update someone else",
+			handler: ci.updateUser,
+			em: &core_proto.ManageEntityLegacy{UserId: 812, EntityId: 811,
+				Metadata: toMetadata(map[string]any{"name": "pwned"})},
+			wantErr: true,
+			count:   0,
+			query:   `select count(*) from users where user_id = 811 and name = 'pwned'`,
+		},
+		{
+			name:    "update cannot set the wallet",
+			handler: ci.updateUser,
+			em: &core_proto.ManageEntityLegacy{UserId: 811, EntityId: 811,
+				Metadata: toMetadata(map[string]any{"name": "renamed", "wallet": "0xattacker", "is_verified": true})},
+			count: 1,
+			query: `select count(*) from users where user_id = 811 and name = 'renamed' and wallet = '0xowner811' and is_verified = false`,
+		},
+		{
+			name:    "create without a handle",
+			handler: ci.createUser,
+			em: &core_proto.ManageEntityLegacy{UserId: 813, EntityId: 813,
+				Metadata: toMetadata(map[string]any{"name": "no handle"})},
+			wantErr: true,
+			count:   0,
+			query:   `select count(*) from users where user_id = 813`,
+		},
+	})
+}
+
+func TestCreateExistingEntity(t *testing.T) {
+	var invalid *invalidTxError
+
+	err := ci.createUser(TxInfo{txhash: "user821_create", signer: "0xowner821"}, &core_proto.ManageEntityLegacy{
+		UserId:   821,
+		EntityId: 821,
+		Metadata: toMetadata(map[string]any{"handle": "test821"}),
+	})
+	assert.NoError(t, err)
+
+	// a second create would add a current row with the attacker's wallet
+	err = ci.createUser(TxInfo{txhash: "user821_takeover", signer: "0xattacker821"}, &core_proto.ManageEntityLegacy{
+		UserId:   821,
+		EntityId: 821,
+		Metadata: toMetadata(map[string]any{"handle": "pwned821"}),
+	})
+	assert.ErrorAs(t, err, &invalid)
+	assertCount(t, 0, `select count(*) from users where user_id = 821 and wallet = '0xattacker821'`)
+	assertCount(t, 1, `select count(*) from users where user_id = 821 and is_current = true`)
+
+	err = ci.createTrack(TxInfo{txhash: "track821_create"}, &core_proto.ManageEntityLegacy{
+		UserId:   821,
+		EntityId: 821,
+		Metadata: toMetadata(map[string]any{"title": "track821"}),
+	})
+	assert.NoError(t, err)
+	err = ci.createTrack(TxInfo{txhash: "track821_takeover"}, &core_proto.ManageEntityLegacy{
+		UserId:   822,
+		EntityId: 821,
+		Metadata: toMetadata(map[string]any{"title": "pwned"}),
+	})
+	assert.ErrorAs(t, err, &invalid)
+	assertCount(t, 1, `select count(*) from tracks where track_id = 821 and is_current = true and owner_id = 821`)
+	assertCount(t, 0, `select count(*) from tracks where track_id = 821 and owner_id = 822`)
+
+	err = ci.createPlaylist(TxInfo{txhash: "playlist821_create"}, &core_proto.ManageEntityLegacy{
+		UserId:   821,
+		EntityId: 821,
+		Metadata: toMetadata(map[string]any{"playlist_name": "playlist821"}),
+	})
+	assert.NoError(t, err)
+	err = ci.createPlaylist(TxInfo{txhash: "playlist821_takeover"}, &core_proto.ManageEntityLegacy{
+		UserId:   822,
+		EntityId: 821,
+		Metadata: toMetadata(map[string]any{"playlist_name": "pwned"}),
+	})
+	assert.ErrorAs(t, err, &invalid)
+	assertCount(t, 0, `select count(*) from playlists where playlist_id = 821 and playlist_owner_id = 822`)
 }
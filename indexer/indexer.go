@@ -2,24 +2,45 @@ package indexer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"maps"
 	"strings"
 	"time"
 
 	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
-	"github.com/AudiusProject/audiusd/pkg/common"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
 )
 
 type CoreIndexer struct {
 	ctx  context.Context
 	pool *pgxpool.Pool
+	// db is what handlers write through: the pool,
+	// or the transaction of the block being indexed
+	db       indexerDB
+	source   BlockSource
+	logger   *zap.Logger
+	emDomain EntityManagerDomain
+
+	chainId      string
+	pollInterval time.Duration
 }
 
 type CoreIndexerConfig struct {
 	DbUrl string
+	// ChainId scopes the progress cursor in core_indexed_blocks
+	ChainId string
+	// BlockSource supplies blocks to index, usually an SdkBlockSource
+	BlockSource BlockSource
+	// PollInterval is how long to wait before asking for a block
+	// past the tip again. Defaults to one second.
+	PollInterval time.Duration
+	// EntityManagerDomain is the EIP-712 domain ManageEntity
+	// signatures are verified against
+	EntityManagerDomain EntityManagerDomain
+	Logger              *zap.Logger
 }
 
 func NewIndexer(config CoreIndexerConfig) (*CoreIndexer, error) {
@@ -29,50 +50,87 @@ func NewIndexer(config CoreIndexerConfig) (*CoreIndexer, error) {
 		return nil, err
 	}
 
+	logger := config.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	pollInterval := config.PollInterval
+	if pollInterval == 0 {
+		pollInterval = time.Second
+	}
+
 	ci := &CoreIndexer{
-		bg,
-		pool,
+		ctx:          bg,
+		pool:         pool,
+		db:           poolDB{pool},
+		source:       config.BlockSource,
+		emDomain:     config.EntityManagerDomain,
+		logger:       logger,
+		chainId:      config.ChainId,
+		pollInterval: pollInterval,
 	}
 
 	return ci, nil
 }
 
-func (ci *CoreIndexer) handleTx(signedTx *core_proto.SignedTransaction) error {
-
-	// txInfo contains some context around the tx:
-	// blockhash + blocknumber
-	// also need block timestamp
-	// todo: where best place to get?
-	txHash, err := common.ToTxHash(signedTx)
-	if err != nil {
-		return err
+// Start indexes blocks from the block source until the context is cancelled.
+func (ci *CoreIndexer) Start(ctx context.Context) error {
+	if ci.source == nil {
+		<-ctx.Done()
+		return ctx.Err()
 	}
 
-	txInfo := TxInfo{
-		txhash:      txHash,
-		blockhash:   "todo",
-		blocknumber: 123,
-		timestamp:   time.Now(),
+	ticker := time.NewTicker(ci.pollInterval)
+	defer ticker.Stop()
+	for {
+		height, err := ci.Sync(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			ci.logger.Error("sync failed", zap.Int64("height", height), zap.Error(err))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
+}
+
+// Applies a transaction on its own, recording it as skipped if it is invalid.
+func (ci *CoreIndexer) handleTx(txInfo TxInfo, signedTx *core_proto.SignedTransaction) error {
+	return ci.skipInvalidTx(txInfo, ci.applyTx(txInfo, signedTx))
+}
 
+// Applies a transaction, returning an *invalidTxError if it must never be
+// applied. Any other error is a failure of this node, like a database error.
+func (ci *CoreIndexer) applyTx(txInfo TxInfo, signedTx *core_proto.SignedTransaction) error {
 	switch signedTx.GetTransaction().(type) {
 	case *core_proto.SignedTransaction_Plays:
-		// play := signedTx.GetPlays()
-		// fmt.Println("PLAY", play)
+		return ci.indexPlays(txInfo, signedTx.GetPlays())
 
 	case *core_proto.SignedTransaction_ManageEntity:
 		em := signedTx.GetManageEntity()
 		action := em.Action + em.EntityType
-		var err error
 
-		// TODO: verify signature
-		// TODO: and that em.Signer is authorized for em.UserId
+		signer, err := ci.authorizeManageEntity(ci.ctx, em)
+		if err != nil {
+			return err
+		}
+		txInfo.signer = signer
 
 		switch action {
 		case "CreateUser":
 			err = ci.createUser(txInfo, em)
 		case "UpdateUser":
 			err = ci.updateUser(txInfo, em)
+		case "DeleteUser":
+			err = ci.deleteUser(txInfo, em)
+		case "RestoreUser":
+			err = ci.restoreUser(txInfo, em)
 
 		case "CreateTrack":
 			err = ci.createTrack(txInfo, em)
@@ -87,9 +145,55 @@ func (ci *CoreIndexer) handleTx(signedTx *core_proto.SignedTransaction) error {
 			err = ci.createPlaylist(txInfo, em)
 		case "UpdatePlaylist":
 			err = ci.updatePlaylist(txInfo, em)
+		case "DeletePlaylist":
+			err = ci.deletePlaylist(txInfo, em)
+		case "RestorePlaylist":
+			err = ci.restorePlaylist(txInfo, em)
 
 		case "CreateComment":
 			err = ci.createComment(txInfo, em)
+		case "UpdateComment":
+			err = ci.updateComment(txInfo, em)
+		case "DeleteComment":
+			err = ci.deleteComment(txInfo, em)
+		case "ReactComment":
+			err = ci.reactComment(txInfo, em)
+		case "UnreactComment":
+			err = ci.unreactComment(txInfo, em)
+		case "PinComment":
+			err = ci.pinComment(txInfo, em)
+		case "UnpinComment":
+			err = ci.unpinComment(txInfo, em)
+		case "ReportComment":
+			err = ci.reportComment(txInfo, em)
+
+		case "CreateGrant":
+			err = ci.createGrant(txInfo, em)
+		case "DeleteGrant":
+			err = ci.deleteGrant(txInfo, em)
+		case "ApproveGrant":
+			err = ci.approveGrant(txInfo, em)
+		case "RejectGrant":
+			err = ci.rejectGrant(txInfo, em)
+
+		case "CreateDeveloperApp":
+			err = ci.createDeveloperApp(txInfo, em)
+		case "UpdateDeveloperApp":
+			err = ci.updateDeveloperApp(txInfo, em)
+		case "DeleteDeveloperApp":
+			err = ci.deleteDeveloperApp(txInfo, em)
+
+		case "CreateEvent":
+			err = ci.createEvent(txInfo, em)
+		case "UpdateEvent":
+			err = ci.updateEvent(txInfo, em)
+		case "DeleteEvent":
+			err = ci.deleteEvent(txInfo, em)
+
+		case "AddEmailEncryptedEmail":
+			err = ci.addEmail(txInfo, em)
+		case "UpdateEmailAccess":
+			err = ci.updateEmailAccess(txInfo, em)
 
 		case "FollowUser":
 			err = ci.followUser(txInfo, em)
@@ -101,10 +205,20 @@ func (ci *CoreIndexer) handleTx(signedTx *core_proto.SignedTransaction) error {
 			err = ci.favorite(txInfo, em)
 		case "UnsaveTrack", "UnsavePlaylist":
 			err = ci.unfavorite(txInfo, em)
+		case "MuteUser":
+			err = ci.muteUser(txInfo, em)
+		case "UnmuteUser":
+			err = ci.unmuteUser(txInfo, em)
+		case "SubscribeUser":
+			err = ci.subscribeUser(txInfo, em)
+		case "UnsubscribeUser":
+			err = ci.unsubscribeUser(txInfo, em)
+		case "ShareTrack", "SharePlaylist":
+			err = ci.share(txInfo, em)
 		case "ViewNotification":
 			err = ci.viewNotification(txInfo, em)
 		default:
-			fmt.Println("no handler for ", action)
+			ci.logger.Debug("no handler for action", zap.String("action", action))
 		}
 
 		return err
@@ -121,6 +235,12 @@ type TxInfo struct {
 	blocknumber int
 	txhash      string
 	timestamp   time.Time
+	// height is the core block height, used as the slot for plays
+	height int64
+	// signer is the verified wallet that signed a ManageEntity tx
+	signer string
+	// seenPlays dedupes plays across the transactions of one block
+	seenPlays map[string]bool
 }
 
 type GenericMetadata struct {
@@ -128,6 +248,46 @@ type GenericMetadata struct {
 	Data map[string]any `json:"data"`
 }
 
+// Decodes a transaction's metadata into v.
+// Metadata is client input, so metadata that doesn't decode
+// makes the transaction invalid.
+func parseMetadata(em *core_proto.ManageEntityLegacy, v any) error {
+	if err := json.Unmarshal([]byte(em.Metadata), v); err != nil {
+		return invalidTx("invalid metadata: %s", err)
+	}
+	return nil
+}
+
+// Copies the given fields of metadata into args, ignoring the rest.
+// Metadata is client input, so handlers only take the fields it may set.
+func copyMetadata(args pgx.NamedArgs, metadata GenericMetadata, fields []string) {
+	for _, field := range fields {
+		if v, ok := metadata.Data[field]; ok {
+			args[field] = v
+		}
+	}
+}
+
+// Returns an invalidTxError if tableName already has a current row with idColumn = id.
+// Versioned tables are keyed on the id and txhash, so nothing else stops a
+// second create from adding another current row for someone else's entity.
+func (ci *CoreIndexer) requireNewEntity(tableName string, idColumn string, id any) error {
+	var exists bool
+	err := ci.db.QueryRow(ci.ctx, fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1 FROM %s
+			WHERE %s = $1 AND is_current = true
+		)
+		`, tableName, idColumn), id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return invalidTx("%s %v already exists", idColumn, id)
+	}
+	return nil
+}
+
 func (ci *CoreIndexer) doInsert(tableName string, args pgx.NamedArgs) error {
 	fields := []string{}
 	placeholders := []string{}
@@ -141,11 +301,55 @@ func (ci *CoreIndexer) doInsert(tableName string, args pgx.NamedArgs) error {
 		strings.Join(placeholders, ", "),
 	)
 
-	_, err := ci.pool.Exec(ci.ctx, stmt, args)
-	return err
+	return ci.db.QueueExec(ci.ctx, stmt, args)
+}
+
+// Users, tracks and playlists are versioned: the current row is kept
+// with is_current = false, and a copy of it with args applied is
+// inserted as the new current row for the transaction.
+func (ci *CoreIndexer) doVersionedUpdate(txInfo TxInfo, tableName string, args pgx.NamedArgs, where pgx.NamedArgs) error {
+	wheres := []string{"is_current = true"}
+	for field := range where {
+		wheres = append(wheres, fmt.Sprintf("%s = @%s", field, field))
+	}
+
+	whereClause := strings.Join(wheres, " AND ")
+	if err := ci.snapshot(txInfo, tableName, whereClause, where); err != nil {
+		return err
+	}
+
+	version := pgx.NamedArgs{}
+	maps.Copy(version, args)
+	maps.Copy(version, pgx.NamedArgs{
+		"is_current":  true,
+		"txhash":      txInfo.txhash,
+		"blockhash":   txInfo.blockhash,
+		"blocknumber": txInfo.blocknumber,
+		"updated_at":  txInfo.timestamp,
+	})
+	versionJson, err := json.Marshal(version)
+	if err != nil {
+		return err
+	}
+
+	stmt := fmt.Sprintf(`
+		WITH prev AS (
+			UPDATE %[1]s SET is_current = false
+			WHERE %[2]s
+			RETURNING *
+		)
+		INSERT INTO %[1]s
+		SELECT (jsonb_populate_record(NULL::%[1]s, to_jsonb(prev) || @version::jsonb)).*
+		FROM prev
+		`, tableName, whereClause)
+
+	queryArgs := pgx.NamedArgs{"version": versionJson}
+	maps.Copy(queryArgs, where)
+
+	return ci.db.QueueExec(ci.ctx, stmt, queryArgs)
 }
 
-func (ci *CoreIndexer) doUpdate(tableName string, args pgx.NamedArgs, where pgx.NamedArgs) error {
+func (ci *CoreIndexer) doUpdate(txInfo TxInfo, tableName string, args pgx.NamedArgs, where pgx.NamedArgs) error {
 	fields := []string{}
 	for field := range args {
 		fields = append(fields, fmt.Sprintf("%s = @%s", field, field))
@@ -156,16 +360,20 @@ func (ci *CoreIndexer) doUpdate(tableName string, args pgx.NamedArgs, where pgx.
 		wheres = append(wheres, fmt.Sprintf("%s = @%s", field, field))
 	}
 
+	whereClause := strings.Join(wheres, " AND ")
+	if err := ci.snapshot(txInfo, tableName, whereClause, where); err != nil {
+		return err
+	}
+
 	stmt := fmt.Sprintf("update %s set %s where %s",
 		tableName,
 		strings.Join(fields, ", "),
-		strings.Join(wheres, " AND "),
+		whereClause,
 	)
 
 	maps.Copy(args, where)
 
 	// fmt.Println(stmt, args)
 
-	_, err := ci.pool.Exec(ci.ctx, stmt, args)
-	return err
+	return ci.db.QueueExec(ci.ctx, stmt, args)
 }
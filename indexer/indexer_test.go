@@ -32,15 +32,28 @@ func TestMain(m *testing.M) {
 	checkErr(err)
 	ci.pool.Close()
 	ci.pool = pool
+	ci.db = poolDB{pool}
+	ci.emDomain = testEmDomain
 
 	// relax schema a bit...
 	_, err = ci.pool.Exec(ci.ctx, `
+	alter table users drop constraint users_blocknumber_fkey;
+	alter table tracks drop constraint tracks_blocknumber_fkey;
+	alter table playlists drop constraint playlists_blocknumber_fkey;
 	alter table follows drop constraint follows_blocknumber_fkey;
 	alter table reposts drop constraint reposts_blocknumber_fkey;
 	alter table saves drop constraint saves_blocknumber_fkey;
 	alter table notification_seen drop constraint notification_seen_blocknumber_fkey;
 	alter table track_downloads drop constraint track_downloads_blocknumber_fkey;
 	alter table comments drop constraint comments_blocknumber_fkey;
+	alter table comment_reactions drop constraint comment_reactions_blocknumber_fkey;
+	alter table comment_reports drop constraint comment_reports_blocknumber_fkey;
+	alter table comment_mentions drop constraint comment_mentions_blocknumber_fkey;
+	alter table muted_users drop constraint muted_users_blocknumber_fkey;
+	alter table grants drop constraint grants_blocknumber_fkey;
+	alter table developer_apps drop constraint developer_apps_blocknumber_fkey;
+	alter table subscriptions drop constraint subscriptions_blocknumber_fkey;
+	alter table events drop constraint events_blocknumber_fkey;
 	`)
 	checkErr(err)
 
@@ -85,7 +98,7 @@ func TestReadProtoFile(t *testing.T) {
 			assert.NoError(t, err)
 		}
 
-		err = ci.handleTx(&signedTx)
+		err = ci.handleTx(TxInfo{}, &signedTx)
 		assert.NoError(t, err)
 
 	}
@@ -106,3 +119,33 @@ func toMetadata(data map[string]any) string {
 	}
 	return string(b)
 }
+
+// handlerStep applies one ManageEntity handler and then checks
+// the resulting state with a count query.
+type handlerStep struct {
+	name    string
+	handler func(TxInfo, *core_proto.ManageEntityLegacy) error
+	em      *core_proto.ManageEntityLegacy
+	wantErr bool
+	count   int
+	query   string
+}
+
+// Runs steps in order, since each builds on the state the last one left.
+func runHandlerSteps(t *testing.T, steps []handlerStep) {
+	for i, step := range steps {
+		txInfo := TxInfo{txhash: fmt.Sprintf("%s_%d", t.Name(), i)}
+		err := step.handler(txInfo, step.em)
+		if step.wantErr {
+			assert.Error(t, err, step.name)
+		} else {
+			assert.NoError(t, err, step.name)
+		}
+		if step.query != "" {
+			count := -1
+			err := ci.pool.QueryRow(ci.ctx, step.query).Scan(&count)
+			assert.NoError(t, err, step.name)
+			assert.Equal(t, step.count, count, step.name)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Tables that handlers insert into with the legacy block number,
+// so a block's inserts can be found again when it is reverted.
+var revertTables = []string{
+	"users",
+	"tracks",
+	"track_downloads",
+	"playlists",
+	"follows",
+	"reposts",
+	"saves",
+	"shares",
+	"subscriptions",
+	"muted_users",
+	"notification_seen",
+	"comments",
+	"comment_mentions",
+	"comment_reactions",
+	"comment_reports",
+	"grants",
+	"developer_apps",
+	"events",
+	"skipped_transactions",
+}
+
+// Revert undoes the block at height, which must be the last block indexed.
+// Rows the block inserted are deleted, rows it changed are put back the way
+// revert_blocks recorded them, and the cursor moves back one block so the
+// next Sync indexes height again.
+//
+// Plays are removed along with their aggregate and hourly counts.
+// Listen streaks and shared emails are not reverted.
+func (ci *CoreIndexer) Revert(ctx context.Context, height int64) error {
+	tx, err := ci.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var emBlock *int
+	var playsSlot int64
+	var isTip bool
+	err = tx.QueryRow(ctx, `
+		SELECT em_block, COALESCE(plays_slot, 0), height = (
+			SELECT MAX(height) FROM core_indexed_blocks WHERE chain_id = @chain_id
+		)
+		FROM core_indexed_blocks
+		WHERE chain_id = @chain_id AND height = @height
+		`, pgx.NamedArgs{
+		"chain_id": ci.chainId,
+		"height":   height,
+	}).Scan(&emBlock, &playsSlot, &isTip)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("block %d has not been indexed", height)
+	}
+	if err != nil {
+		return err
+	}
+	if !isTip {
+		return fmt.Errorf("block %d is not the last indexed block", height)
+	}
+
+	if emBlock != nil {
+		if err := revertEmBlock(ctx, tx, *emBlock); err != nil {
+			return fmt.Errorf("error reverting legacy block %d: %w", *emBlock, err)
+		}
+	}
+
+	if playsSlot != 0 {
+		if err := revertPlays(ctx, tx, playsSlot); err != nil {
+			return fmt.Errorf("error reverting plays: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM core_indexed_blocks
+		WHERE chain_id = $1 AND height = $2
+		`, ci.chainId, height)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func revertEmBlock(ctx context.Context, tx pgx.Tx, blocknumber int) error {
+	var prevRecords map[string][]json.RawMessage
+	err := tx.QueryRow(ctx, `
+		SELECT prev_records FROM revert_blocks WHERE blocknumber = $1
+		`, blocknumber).Scan(&prevRecords)
+	if err != nil && err != pgx.ErrNoRows {
+		return err
+	}
+
+	// threads have no block number of their own
+	_, err = tx.Exec(ctx, `
+		DELETE FROM comment_threads
+		WHERE comment_id IN (SELECT comment_id FROM comments WHERE blocknumber = $1)
+		`, blocknumber)
+	if err != nil {
+		return err
+	}
+
+	for _, tableName := range revertTables {
+		stmt := fmt.Sprintf("DELETE FROM %s WHERE blocknumber = $1", pgx.Identifier{tableName}.Sanitize())
+		if _, err := tx.Exec(ctx, stmt, blocknumber); err != nil {
+			return fmt.Errorf("error deleting from %s: %w", tableName, err)
+		}
+	}
+
+	for tableName, records := range prevRecords {
+		if err := restoreRecords(ctx, tx, tableName, blocknumber, records); err != nil {
+			return fmt.Errorf("error restoring %s: %w", tableName, err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM revert_blocks WHERE blocknumber = $1`, blocknumber)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM blocks WHERE number = $1`, blocknumber)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE blocks SET is_current = true
+		WHERE number = (SELECT MAX(number) FROM blocks)
+		`)
+	return err
+}
+
+// Puts back the rows a block changed. A row changed more than once in the
+// block was recorded each time, and the first record is the original.
+// Records of rows the block itself created are skipped, since those rows
+// have already been deleted.
+func restoreRecords(ctx context.Context, tx pgx.Tx, tableName string, blocknumber int, records []json.RawMessage) error {
+	table := pgx.Identifier{tableName}.Sanitize()
+
+	primaryKey, err := tableColumns(ctx, tx, tableName, true)
+	if err != nil {
+		return err
+	}
+	columns, err := tableColumns(ctx, tx, tableName, false)
+	if err != nil {
+		return err
+	}
+	if len(primaryKey) == 0 {
+		return fmt.Errorf("%s has no primary key", tableName)
+	}
+
+	pk := strings.Join(primaryKey, ", ")
+	prev := fmt.Sprintf(`
+		WITH prev AS (
+			SELECT DISTINCT ON (%[2]s) r.*
+			FROM jsonb_array_elements($1::jsonb) WITH ORDINALITY AS e(record, idx),
+				LATERAL jsonb_populate_record(NULL::%[1]s, e.record) r
+			WHERE r.blocknumber IS DISTINCT FROM $2
+			ORDER BY %[2]s, e.idx
+		)`, table, pk)
+
+	matches := []string{}
+	sets := []string{}
+	for _, column := range primaryKey {
+		matches = append(matches, fmt.Sprintf("t.%[1]s = prev.%[1]s", column))
+	}
+	for _, column := range columns {
+		sets = append(sets, fmt.Sprintf("%[1]s = prev.%[1]s", column))
+	}
+
+	recordsJson, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	// rows that still exist are updated in place...
+	update := fmt.Sprintf(`%s
+		UPDATE %s t SET %s
+		FROM prev
+		WHERE %s`,
+		prev, table, strings.Join(sets, ", "), strings.Join(matches, " AND "))
+	if _, err := tx.Exec(ctx, update, recordsJson, blocknumber); err != nil {
+		return err
+	}
+
+	// ...and rows whose key changed in the block are inserted again
+	insert := fmt.Sprintf(`%s
+		INSERT INTO %s (%s)
+		SELECT %s FROM prev
+		WHERE NOT EXISTS (SELECT 1 FROM %s t WHERE %s)`,
+		prev, table, strings.Join(columns, ", "), strings.Join(columns, ", "), table, strings.Join(matches, " AND "))
+	_, err = tx.Exec(ctx, insert, recordsJson, blocknumber)
+	return err
+}
+
+func tableColumns(ctx context.Context, tx pgx.Tx, tableName string, primaryKeyOnly bool) ([]string, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT quote_ident(a.attname)
+		FROM pg_attribute a
+		WHERE a.attrelid = $1::regclass
+		AND a.attnum > 0
+		AND NOT a.attisdropped
+		AND a.attgenerated = ''
+		AND a.attidentity = ''
+		AND (NOT $2 OR a.attnum = ANY (
+			SELECT unnest(i.indkey) FROM pg_index i
+			WHERE i.indrelid = a.attrelid AND i.indisprimary
+		))
+		ORDER BY a.attnum
+		`, tableName, primaryKeyOnly)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// Plays carry the core block height as their slot.
+func revertPlays(ctx context.Context, tx pgx.Tx, slot int64) error {
+	_, err := tx.Exec(ctx, `
+		WITH reverted AS (
+			DELETE FROM plays WHERE slot = $1
+			RETURNING play_item_id, created_at, country
+		),
+		by_track AS (
+			UPDATE aggregate_plays
+			SET count = aggregate_plays.count - r.count
+			FROM (SELECT play_item_id, count(*) AS count FROM reverted GROUP BY 1) r
+			WHERE aggregate_plays.play_item_id = r.play_item_id
+		),
+		by_month AS (
+			UPDATE aggregate_monthly_plays
+			SET count = aggregate_monthly_plays.count - r.count
+			FROM (
+				SELECT play_item_id, date_trunc('month', created_at) AS month, coalesce(country, '') AS country, count(*) AS count
+				FROM reverted GROUP BY 1, 2, 3
+			) r
+			WHERE aggregate_monthly_plays.play_item_id = r.play_item_id
+			AND aggregate_monthly_plays.timestamp = r.month
+			AND aggregate_monthly_plays.country = r.country
+		)
+		UPDATE hourly_play_counts
+		SET play_count = hourly_play_counts.play_count - r.count
+		FROM (SELECT date_trunc('hour', created_at) AS hour, count(*) AS count FROM reverted GROUP BY 1) r
+		WHERE hourly_play_counts.hourly_timestamp = r.hour
+		`, slot)
+	return err
+}
@@ -0,0 +1,107 @@
+package indexer
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	core_proto "github.com/AudiusProject/audiusd/pkg/api/core/v1"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestRevertBlock(t *testing.T) {
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	me := func(action, entityType string, userId, entityId int64, data map[string]any) *core_proto.SignedTransaction {
+		return signedManageEntityTx(t, key, &core_proto.ManageEntityLegacy{
+			Action:     action,
+			EntityType: entityType,
+			UserId:     userId,
+			EntityId:   entityId,
+			Metadata:   toMetadata(data),
+		})
+	}
+
+	// one wallet signs for both users
+	setup := testBlock(1,
+		me("Create", "User", 1101, 1101, map[string]any{"handle": "revert1101", "name": "before"}),
+		me("Create", "User", 1102, 1102, map[string]any{"handle": "revert1102"}),
+		me("Create", "Track", 1101, 1101, map[string]any{"title": "revert before"}),
+		me("Create", "Playlist", 1101, 1101, map[string]any{"playlist_name": "revert playlist"}),
+	)
+	reverted := testBlock(2,
+		me("Update", "User", 1101, 1101, map[string]any{"name": "after"}),
+		me("Update", "Track", 1101, 1101, map[string]any{"title": "revert after"}),
+		me("Delete", "Playlist", 1101, 1101, map[string]any{}),
+		me("Follow", "User", 1101, 1102, map[string]any{}),
+		me("Save", "Track", 1102, 1101, map[string]any{}),
+		me("Repost", "Track", 1102, 1101, map[string]any{}),
+		playsTx(&core_proto.TrackPlay{UserId: "1102", TrackId: "1101", Signature: "revert play"}),
+	)
+	// one wallet signs for both users
+	ri := &CoreIndexer{
+		ctx:      ctx,
+		pool:     ci.pool,
+		source:   NewStaticBlockSource(setup, reverted),
+		logger:   zap.NewNop(),
+		emDomain: testEmDomain,
+		chainId:  "revert-chain",
+	}
+
+	height, err := ri.Sync(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), height)
+
+	assertCount(t, 1, `select count(*) from users where user_id = 1101 and name = 'after'`)
+	assertCount(t, 1, `select count(*) from tracks where track_id = 1101 and title = 'revert after'`)
+	assertCount(t, 1, `select count(*) from playlists where playlist_id = 1101 and is_delete = true`)
+	assertCount(t, 1, `select count(*) from follows where follower_user_id = 1101 and followee_user_id = 1102`)
+	assertCount(t, 1, `select count(*) from saves where user_id = 1102 and save_item_id = 1101`)
+	assertCount(t, 1, `select count(*) from reposts where user_id = 1102 and repost_item_id = 1101`)
+	assertCount(t, 1, `select count(*) from plays where play_item_id = 1101`)
+	assertCount(t, 1, `select count from aggregate_plays where play_item_id = 1101`)
+
+	var setupEmBlock int
+	err = ci.pool.QueryRow(ctx, `select em_block from core_indexed_blocks where chain_id = 'revert-chain' and height = 1`).Scan(&setupEmBlock)
+	require.NoError(t, err)
+
+	// only the tip can be reverted
+	assert.Error(t, ri.Revert(ctx, 1))
+
+	require.NoError(t, ri.Revert(ctx, 2))
+
+	// changed rows are back the way they were
+	assertCount(t, 1, `select count(*) from users where user_id = 1101 and name = 'before' and is_current = true`)
+	assertCount(t, 1, `select count(*) from tracks where track_id = 1101`)
+	assertCount(t, 1, `select count(*) from tracks where track_id = 1101 and title = 'revert before'`)
+	assertCount(t, 1, `select count(*) from playlists where playlist_id = 1101 and is_current = true and is_delete = false`)
+
+	// inserted rows are gone
+	assertCount(t, 0, `select count(*) from follows where follower_user_id = 1101 and followee_user_id = 1102`)
+	assertCount(t, 0, `select count(*) from saves where user_id = 1102 and save_item_id = 1101`)
+	assertCount(t, 0, `select count(*) from reposts where user_id = 1102 and repost_item_id = 1101`)
+	assertCount(t, 0, `select count(*) from plays where play_item_id = 1101`)
+	assertCount(t, 0, `select count from aggregate_plays where play_item_id = 1101`)
+
+	// rows from the earlier block are untouched
+	assertCount(t, 2, `select count(*) from users where user_id in (1101, 1102)`)
+
+	// bookkeeping points back at block 1
+	assertCount(t, 1, `select count(*) from core_indexed_blocks where chain_id = 'revert-chain'`)
+	assertCount(t, setupEmBlock, `select number from blocks where is_current = true`)
+	assertCount(t, 0, `select count(*) from revert_blocks where blocknumber > `+strconv.Itoa(setupEmBlock))
+
+	assert.Error(t, ri.Revert(ctx, 2), "block 2 is no longer indexed")
+
+	// and the block can be indexed again
+	height, err = ri.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), height)
+	assertCount(t, 1, `select count(*) from users where user_id = 1101 and name = 'after'`)
+	assertCount(t, 1, `select count(*) from follows where follower_user_id = 1101 and followee_user_id = 1102`)
+}
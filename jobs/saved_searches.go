@@ -0,0 +1,231 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"bridgerton.audius.co/api/searchv1"
+	"bridgerton.audius.co/config"
+	"bridgerton.audius.co/database"
+	"bridgerton.audius.co/logging"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// Saved searches are track searches a user follows.
+// Each run re-searches the ones that are due for tracks created since their
+// previous run, records them in saved_search_results,
+// and sends the owner a `saved_search` notification for each,
+// grouped by the run.
+const (
+	// How often each saved search is re-run.
+	SavedSearchInterval = 15 * time.Minute
+	// Most saved searches re-run by one job run.
+	savedSearchBatchSize = 500
+	// Most new tracks recorded for one saved search per run.
+	savedSearchMaxResults = 100
+	// Tracks reach the search index a little after they are created,
+	// so each run also looks this far back past the previous one.
+	// Tracks found twice are only recorded once.
+	savedSearchIndexLag = 5 * time.Minute
+)
+
+type savedSearch struct {
+	ID        int32                     `db:"id"`
+	UserID    int32                     `db:"user_id"`
+	Query     searchv1.TrackSearchQuery `db:"query"`
+	LastRunAt *time.Time                `db:"last_run_at"`
+}
+
+type SavedSearchJob struct {
+	pool     database.DbPool
+	esClient *elasticsearch.Client
+	logger   *zap.Logger
+
+	mutex     sync.Mutex
+	isRunning bool
+}
+
+func NewSavedSearchJob(config config.Config, pool database.DbPool, esClient *elasticsearch.Client) *SavedSearchJob {
+	return &SavedSearchJob{
+		pool:     pool,
+		esClient: esClient,
+		logger:   logging.NewZapLogger(config).Named("SavedSearchJob"),
+	}
+}
+
+// ScheduleEvery runs the job every `duration` until the context is cancelled.
+func (j *SavedSearchJob) ScheduleEvery(ctx context.Context, duration time.Duration) *SavedSearchJob {
+	go func() {
+		ticker := time.NewTicker(duration)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				j.logger.Info("Job started")
+				j.Run(ctx)
+			case <-ctx.Done():
+				j.logger.Info("Job shutting down")
+				return
+			}
+		}
+	}()
+	return j
+}
+
+// Run executes the job once
+func (j *SavedSearchJob) Run(ctx context.Context) {
+	if err := j.run(ctx); err != nil {
+		j.logger.Error("Job run failed", zap.Error(err))
+	} else {
+		j.logger.Info("Job completed successfully")
+	}
+}
+
+// Re-runs every saved search that hasn't run in the last SavedSearchInterval.
+// A search that fails is logged and retried on the next run.
+// Ensures only one instance runs at a time.
+func (j *SavedSearchJob) run(ctx context.Context) error {
+	j.mutex.Lock()
+	if j.isRunning {
+		j.mutex.Unlock()
+		return fmt.Errorf("job is already running")
+	}
+	j.isRunning = true
+	j.mutex.Unlock()
+	defer func() {
+		j.mutex.Lock()
+		j.isRunning = false
+		j.mutex.Unlock()
+	}()
+
+	// postgres keeps microseconds, and releaseSearch compares against it
+	runAt := time.Now().UTC().Truncate(time.Microsecond)
+	due, err := j.claimDueSearches(ctx, runAt)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, s := range due {
+		if err := j.runSearch(ctx, s, runAt); err != nil {
+			j.logger.Error("Saved search failed", zap.Int32("saved_search_id", s.ID), zap.Error(err))
+			failed++
+
+			if err := j.releaseSearch(ctx, s, runAt); err != nil {
+				j.logger.Error("Saved search not released", zap.Int32("saved_search_id", s.ID), zap.Error(err))
+			}
+		}
+	}
+
+	j.logger.Info("Saved searches run", zap.Int("count", len(due)), zap.Int("failed", failed))
+	return nil
+}
+
+// Claims the saved searches that haven't run in the last SavedSearchInterval
+// by moving their last_run_at up to runAt, in one statement,
+// so no transaction is held open while they are searched
+// and other instances skip them.
+// The searches are returned with their previous last_run_at.
+func (j *SavedSearchJob) claimDueSearches(ctx context.Context, runAt time.Time) ([]savedSearch, error) {
+	rows, err := j.pool.Query(ctx, `
+		WITH due AS (
+			SELECT id, last_run_at
+			FROM saved_searches
+			WHERE last_run_at IS NULL OR last_run_at < @due_before
+			ORDER BY last_run_at NULLS FIRST
+			LIMIT @limit
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE saved_searches
+		SET last_run_at = @run_at
+		FROM due
+		WHERE saved_searches.id = due.id
+		RETURNING saved_searches.id, saved_searches.user_id, saved_searches.query, due.last_run_at
+	`, pgx.NamedArgs{
+		"due_before": runAt.Add(-SavedSearchInterval),
+		"run_at":     runAt,
+		"limit":      savedSearchBatchSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[savedSearch])
+}
+
+// Puts a failed search's last_run_at back, so the next run retries it,
+// unless another instance has claimed it since.
+func (j *SavedSearchJob) releaseSearch(ctx context.Context, s savedSearch, runAt time.Time) error {
+	_, err := j.pool.Exec(ctx, `
+		UPDATE saved_searches SET last_run_at = @last_run_at
+		WHERE id = @id AND last_run_at = @run_at
+	`, pgx.NamedArgs{
+		"id":          s.ID,
+		"last_run_at": s.LastRunAt,
+		"run_at":      runAt,
+	})
+	return err
+}
+
+// Runs one claimed saved search.
+// The first run only sets where the next one starts from.
+func (j *SavedSearchJob) runSearch(ctx context.Context, s savedSearch, runAt time.Time) error {
+	if s.LastRunAt == nil {
+		return nil
+	}
+
+	query := s.Query
+	query.SortMethod = "recent"
+	query.CreatedAfter = s.LastRunAt.Add(-savedSearchIndexLag)
+
+	result, err := searchv1.Search(j.esClient, "tracks", query.DSL(), searchv1.Page{Limit: savedSearchMaxResults})
+	if err != nil {
+		return err
+	}
+
+	return recordSavedSearchResults(ctx, j.pool, s, result.Ids, runAt)
+}
+
+// Records the tracks a run found that the search hasn't found before,
+// and notifies the owner with one notification per track,
+// sharing a group_id so the run shows as one group.
+// The owner's own tracks are left out.
+func recordSavedSearchResults(ctx context.Context, pool database.DbPool, s savedSearch, trackIds []int32, runAt time.Time) error {
+	if len(trackIds) == 0 {
+		return nil
+	}
+
+	_, err := pool.Exec(ctx, `
+		WITH found AS (
+			INSERT INTO saved_search_results (saved_search_id, track_id, found_at)
+			SELECT @saved_search_id, track_id, @run_at
+			FROM tracks
+			WHERE track_id = ANY(@track_ids)
+				AND is_current = true
+				AND is_delete = false
+				AND owner_id != @user_id
+			ON CONFLICT DO NOTHING
+			RETURNING track_id
+		)
+		INSERT INTO notification (specifier, group_id, type, timestamp, data, user_ids)
+		SELECT
+			track_id::text,
+			@group_id,
+			'saved_search',
+			@run_at,
+			jsonb_build_object('saved_search_id', @saved_search_id::int, 'track_id', track_id),
+			ARRAY[@user_id::int]
+		FROM found
+		ON CONFLICT DO NOTHING
+	`, pgx.NamedArgs{
+		"saved_search_id": s.ID,
+		"user_id":         s.UserID,
+		"track_ids":       trackIds,
+		"run_at":          runAt,
+		"group_id":        fmt.Sprintf("saved_search:%d:%d", s.ID, runAt.Unix()),
+	})
+	return err
+}
@@ -0,0 +1,100 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"bridgerton.audius.co/config"
+	"bridgerton.audius.co/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedSearchJob(t *testing.T) {
+	pool := database.CreateTestDatabase(t, "test_jobs")
+	ctx := t.Context()
+
+	database.Seed(pool, database.FixtureMap{
+		"users": {
+			{"user_id": 1, "handle": "fan", "handle_lc": "fan"},
+			{"user_id": 2, "handle": "artist", "handle_lc": "artist"},
+		},
+		"tracks": {
+			{"track_id": 10, "owner_id": 2, "title": "new techno", "genre": "Techno"},
+			{"track_id": 11, "owner_id": 1, "title": "my own techno", "genre": "Techno"},
+			{"track_id": 12, "owner_id": 2, "title": "deleted techno", "genre": "Techno", "is_delete": true},
+		},
+		"saved_searches": {
+			{"id": 100, "user_id": 1, "query": `{"genres":["Techno"]}`},
+		},
+	})
+
+	job := NewSavedSearchJob(config.Cfg, pool, nil)
+
+	lastRunAt := func() *time.Time {
+		var at *time.Time
+		err := pool.QueryRow(ctx, `SELECT last_run_at FROM saved_searches WHERE id = 100`).Scan(&at)
+		require.NoError(t, err)
+		return at
+	}
+
+	// Due searches are claimed up front, with their previous run
+	claimedAt := time.Now().UTC().Truncate(time.Microsecond)
+	due, err := job.claimDueSearches(ctx, claimedAt)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Nil(t, due[0].LastRunAt)
+	require.NotNil(t, lastRunAt())
+	assert.True(t, claimedAt.Equal(*lastRunAt()))
+
+	// A claimed search isn't claimed again
+	again, err := job.claimDueSearches(ctx, claimedAt)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+
+	// A failed search is released for the next run
+	require.NoError(t, job.releaseSearch(ctx, due[0], claimedAt))
+	assert.Nil(t, lastRunAt())
+
+	// The first run only sets a baseline, so it doesn't search
+	due, err = job.claimDueSearches(ctx, claimedAt)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	require.NoError(t, job.runSearch(ctx, due[0], claimedAt))
+
+	s := savedSearch{ID: 100, UserID: 1}
+	record := func(trackIds []int32, runAt time.Time) {
+		require.NoError(t, recordSavedSearchResults(ctx, pool, s, trackIds, runAt))
+	}
+
+	results := func() []int32 {
+		rows, err := pool.Query(ctx, `SELECT track_id FROM saved_search_results WHERE saved_search_id = 100 ORDER BY track_id`)
+		require.NoError(t, err)
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+		require.NoError(t, err)
+		return ids
+	}
+
+	notifications := func() []string {
+		rows, err := pool.Query(ctx, `
+			SELECT group_id || '/' || specifier FROM notification
+			WHERE type = 'saved_search' AND user_ids = ARRAY[1]
+			ORDER BY id`)
+		require.NoError(t, err)
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		require.NoError(t, err)
+		return ids
+	}
+
+	// The owner's own tracks and deleted tracks are left out
+	runAt := time.Unix(1760000000, 0).UTC()
+	record([]int32{10, 11, 12}, runAt)
+	assert.Equal(t, []int32{10}, results())
+	assert.Equal(t, []string{"saved_search:100:1760000000/10"}, notifications())
+
+	// Tracks found again by a later run aren't notified twice
+	record([]int32{10}, runAt.Add(SavedSearchInterval))
+	assert.Equal(t, []int32{10}, results())
+	assert.Len(t, notifications(), 1)
+}
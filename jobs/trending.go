@@ -0,0 +1,369 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"bridgerton.audius.co/config"
+	"bridgerton.audius.co/database"
+	"bridgerton.audius.co/logging"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// The trending algorithm version written to the *_trending_scores tables.
+// Readers in the api package filter on this value, so bump both together
+// when changing the scoring below.
+const TrendingVersion = "pnagD"
+
+const (
+	TrendingTypeTracks    = "TRACKS"
+	TrendingTypePlaylists = "PLAYLISTS"
+)
+
+// Scoring weights, carried over from the pnagD strategy in discovery-provider.
+const (
+	trendingPlayWeight         = 1.0
+	trendingWindowRepostWeight = 50.0
+	trendingWindowSaveWeight   = 1.0
+	trendingTotalRepostWeight  = 0.25
+	trendingTotalSaveWeight    = 0.01
+	// Controls how fast scores decay once an item is older than its window.
+	trendingDecayBase = 100000.0
+	// Owners with fewer followers than this never trend.
+	trendingMinOwnerFollowers = 3
+)
+
+type trendingWindow struct {
+	timeRange string
+	days      int
+}
+
+var trackTrendingWindows = []trendingWindow{
+	{timeRange: "week", days: 7},
+	{timeRange: "month", days: 30},
+	{timeRange: "allTime", days: 100000},
+}
+
+var playlistTrendingWindows = []trendingWindow{
+	{timeRange: "week", days: 7},
+	{timeRange: "month", days: 30},
+	{timeRange: "year", days: 365},
+}
+
+type TrendingJob struct {
+	pool   database.DbPool
+	logger *zap.Logger
+
+	mutex     sync.Mutex
+	isRunning bool
+}
+
+func NewTrendingJob(config config.Config, pool database.DbPool) *TrendingJob {
+	return &TrendingJob{
+		pool:   pool,
+		logger: logging.NewZapLogger(config).Named("TrendingJob"),
+	}
+}
+
+// ScheduleEvery runs the job every `duration` until the context is cancelled.
+func (j *TrendingJob) ScheduleEvery(ctx context.Context, duration time.Duration) *TrendingJob {
+	go func() {
+		ticker := time.NewTicker(duration)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				j.logger.Info("Job started")
+				j.Run(ctx)
+			case <-ctx.Done():
+				j.logger.Info("Job shutting down")
+				return
+			}
+		}
+	}()
+	return j
+}
+
+// Run executes the job once
+func (j *TrendingJob) Run(ctx context.Context) {
+	if err := j.run(ctx); err != nil {
+		j.logger.Error("Job run failed", zap.Error(err))
+	} else {
+		j.logger.Info("Job completed successfully")
+	}
+}
+
+// Recomputes trending scores for tracks and playlists for every time range.
+// Underground trending is read from the track scores, filtered by owner.
+// Each (type, time_range) pair is replaced in its own transaction
+// so readers never observe a half-written ranking.
+// Ensures only one instance runs at a time.
+func (j *TrendingJob) run(ctx context.Context) error {
+	j.mutex.Lock()
+	if j.isRunning {
+		j.mutex.Unlock()
+		return fmt.Errorf("job is already running")
+	}
+	j.isRunning = true
+	j.mutex.Unlock()
+	defer func() {
+		j.mutex.Lock()
+		j.isRunning = false
+		j.mutex.Unlock()
+	}()
+
+	for _, window := range trackTrendingWindows {
+		start := time.Now()
+		count, err := j.updateTrackScores(ctx, window)
+		if err != nil {
+			return fmt.Errorf("error updating track %s scores: %w", window.timeRange, err)
+		}
+		j.logger.Info("Updated track trending scores",
+			zap.String("time_range", window.timeRange),
+			zap.Int64("count", count),
+			zap.Duration("took", time.Since(start)))
+	}
+
+	for _, window := range playlistTrendingWindows {
+		start := time.Now()
+		count, err := j.updatePlaylistScores(ctx, window)
+		if err != nil {
+			return fmt.Errorf("error updating playlist %s scores: %w", window.timeRange, err)
+		}
+		j.logger.Info("Updated playlist trending scores",
+			zap.String("time_range", window.timeRange),
+			zap.Int64("count", count),
+			zap.Duration("took", time.Since(start)))
+	}
+
+	return nil
+}
+
+func (j *TrendingJob) updateTrackScores(ctx context.Context, window trendingWindow) (int64, error) {
+	// Windowed plays are counted from raw plays, but allTime would be a scan
+	// of the entire table, so use the aggregate that handle_play maintains.
+	windowPlays := `
+		SELECT play_item_id AS item_id, count(*) AS play_count
+		FROM plays
+		WHERE created_at > @since
+		GROUP BY play_item_id`
+	if window.timeRange == "allTime" {
+		windowPlays = `
+		SELECT play_item_id AS item_id, count AS play_count
+		FROM aggregate_plays`
+	}
+
+	sql := `
+	WITH
+	eligible AS (
+		SELECT
+			t.track_id,
+			t.genre,
+			t.owner_id,
+			COALESCE(t.release_date, t.created_at) AS released_at
+		FROM tracks t
+		JOIN aggregate_user au ON au.user_id = t.owner_id
+		WHERE t.is_current = true
+			AND t.is_delete = false
+			AND t.is_unlisted = false
+			AND t.is_available = true
+			AND t.stem_of IS NULL
+			AND au.follower_count >= @min_followers
+	),
+	window_plays AS (` + windowPlays + `
+	),
+	window_reposts AS (
+		SELECT repost_item_id AS item_id, count(*) AS repost_count
+		FROM reposts
+		WHERE repost_type = 'track'
+			AND is_current = true
+			AND is_delete = false
+			AND created_at > @since
+		GROUP BY repost_item_id
+	),
+	window_saves AS (
+		SELECT save_item_id AS item_id, count(*) AS save_count
+		FROM saves
+		WHERE save_type = 'track'
+			AND is_current = true
+			AND is_delete = false
+			AND created_at > @since
+		GROUP BY save_item_id
+	),
+	` + karmaCTE(`'track'`) + `
+	SELECT
+		e.track_id,
+		e.genre,
+		(
+			@play_weight::float8 * COALESCE(wp.play_count, 0)
+			+ @window_repost_weight::float8 * COALESCE(wr.repost_count, 0)
+			+ @window_save_weight::float8 * COALESCE(ws.save_count, 0)
+			+ @total_repost_weight::float8 * COALESCE(agg.repost_count, 0)
+			+ @total_save_weight::float8 * COALESCE(agg.save_count, 0)
+		) * GREATEST(COALESCE(k.karma, 0), 1) * ` + decayExpr("e.released_at") + ` AS score
+	FROM eligible e
+	LEFT JOIN window_plays wp ON wp.item_id = e.track_id
+	LEFT JOIN window_reposts wr ON wr.item_id = e.track_id
+	LEFT JOIN window_saves ws ON ws.item_id = e.track_id
+	LEFT JOIN aggregate_track agg ON agg.track_id = e.track_id
+	LEFT JOIN karma k ON k.item_id = e.track_id
+	`
+
+	return j.replaceScores(ctx, `
+		DELETE FROM track_trending_scores
+		WHERE type = @type
+			AND version = @version
+			AND time_range = @time_range
+		`, `
+		INSERT INTO track_trending_scores (track_id, type, genre, version, time_range, score, created_at)
+		SELECT track_id, @type, genre, @version, @time_range, score, now()
+		FROM (`+sql+`) scores
+		WHERE score > 0
+		`, trendingArgs(TrendingTypeTracks, window))
+}
+
+func (j *TrendingJob) updatePlaylistScores(ctx context.Context, window trendingWindow) (int64, error) {
+	sql := `
+	WITH
+	eligible AS (
+		SELECT
+			p.playlist_id,
+			p.is_album,
+			COALESCE(p.release_date, p.created_at) AS released_at
+		FROM playlists p
+		JOIN aggregate_user au ON au.user_id = p.playlist_owner_id
+		WHERE p.is_current = true
+			AND p.is_delete = false
+			AND p.is_private = false
+			AND au.follower_count >= @min_followers
+	),
+	window_reposts AS (
+		SELECT repost_item_id AS item_id, count(*) AS repost_count
+		FROM reposts
+		WHERE repost_type IN ('playlist', 'album')
+			AND is_current = true
+			AND is_delete = false
+			AND created_at > @since
+		GROUP BY repost_item_id
+	),
+	window_saves AS (
+		SELECT save_item_id AS item_id, count(*) AS save_count
+		FROM saves
+		WHERE save_type IN ('playlist', 'album')
+			AND is_current = true
+			AND is_delete = false
+			AND created_at > @since
+		GROUP BY save_item_id
+	),
+	` + karmaCTE(`'playlist', 'album'`) + `
+	SELECT
+		e.playlist_id,
+		(
+			@window_repost_weight::float8 * COALESCE(wr.repost_count, 0)
+			+ @window_save_weight::float8 * COALESCE(ws.save_count, 0)
+			+ @total_repost_weight::float8 * COALESCE(ap.repost_count, 0)
+			+ @total_save_weight::float8 * COALESCE(ap.save_count, 0)
+		) * GREATEST(COALESCE(k.karma, 0), 1) * ` + decayExpr("e.released_at") + ` AS score
+	FROM eligible e
+	LEFT JOIN window_reposts wr ON wr.item_id = e.playlist_id
+	LEFT JOIN window_saves ws ON ws.item_id = e.playlist_id
+	LEFT JOIN aggregate_playlist ap ON ap.playlist_id = e.playlist_id
+	LEFT JOIN karma k ON k.item_id = e.playlist_id
+	`
+
+	return j.replaceScores(ctx, `
+		DELETE FROM playlist_trending_scores
+		WHERE type = @type
+			AND version = @version
+			AND time_range = @time_range
+		`, `
+		INSERT INTO playlist_trending_scores (playlist_id, type, version, time_range, score, created_at)
+		SELECT playlist_id, @type, @version, @time_range, score, now()
+		FROM (`+sql+`) scores
+		WHERE score > 0
+		`, trendingArgs(TrendingTypePlaylists, window))
+}
+
+// Swaps out one (type, version, time_range) slice of a scores table
+// atomically, returning the number of rows written.
+func (j *TrendingJob) replaceScores(ctx context.Context, deleteSql, insertSql string, args pgx.NamedArgs) (int64, error) {
+	tx, err := j.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, deleteSql, args); err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Exec(ctx, insertSql, args)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Karma is the total follower count of the users with complete profiles
+// who saved or reposted an item, so that engagement from established
+// accounts counts for more than engagement from fresh ones.
+func karmaCTE(itemTypes string) string {
+	return `
+	karma AS (
+		SELECT sr.item_id, sum(au.follower_count) AS karma
+		FROM (
+			SELECT user_id, repost_item_id AS item_id
+			FROM reposts
+			WHERE repost_type IN (` + itemTypes + `)
+				AND is_current = true
+				AND is_delete = false
+			UNION ALL
+			SELECT user_id, save_item_id AS item_id
+			FROM saves
+			WHERE save_type IN (` + itemTypes + `)
+				AND is_current = true
+				AND is_delete = false
+		) sr
+		JOIN users u ON u.user_id = sr.user_id AND u.is_current = true
+		JOIN aggregate_user au ON au.user_id = sr.user_id
+		WHERE (u.cover_photo IS NOT NULL OR u.cover_photo_sizes IS NOT NULL)
+			AND (u.profile_picture IS NOT NULL OR u.profile_picture_sizes IS NOT NULL)
+			AND u.bio IS NOT NULL
+		GROUP BY sr.item_id
+	)`
+}
+
+// Items older than the window decay exponentially, bottoming out at
+// 1/trendingDecayBase.
+func decayExpr(releasedAt string) string {
+	age := "(EXTRACT(EPOCH FROM (now() - " + releasedAt + ")) / 86400.0)"
+	return `
+		CASE WHEN ` + age + ` > @window_days
+			THEN GREATEST(1.0 / @decay_base::float8, power(@decay_base::float8, GREATEST(-10.0, 1.0 - ` + age + ` / @window_days::float8)))
+			ELSE 1.0
+		END`
+}
+
+func trendingArgs(trendingType string, window trendingWindow) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"type":                 trendingType,
+		"version":              TrendingVersion,
+		"time_range":           window.timeRange,
+		"since":                time.Now().AddDate(0, 0, -window.days),
+		"window_days":          float64(window.days),
+		"min_followers":        trendingMinOwnerFollowers,
+		"play_weight":          trendingPlayWeight,
+		"window_repost_weight": trendingWindowRepostWeight,
+		"window_save_weight":   trendingWindowSaveWeight,
+		"total_repost_weight":  trendingTotalRepostWeight,
+		"total_save_weight":    trendingTotalSaveWeight,
+		"decay_base":           trendingDecayBase,
+	}
+}
@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"bridgerton.audius.co/config"
+	"bridgerton.audius.co/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrendingJob(t *testing.T) {
+	pool := database.CreateTestDatabase(t, "test_jobs")
+	ctx := t.Context()
+
+	database.Seed(pool, database.FixtureMap{
+		"users": {
+			{"user_id": 1, "handle": "bigartist", "handle_lc": "bigartist"},
+			{"user_id": 2, "handle": "smallartist", "handle_lc": "smallartist"},
+			{"user_id": 3, "handle": "nobody", "handle_lc": "nobody"},
+			{"user_id": 4, "handle": "fan", "handle_lc": "fan"},
+		},
+		"aggregate_user": {
+			{"user_id": 1, "follower_count": 5000, "following_count": 10},
+			{"user_id": 2, "follower_count": 100, "following_count": 10},
+			{"user_id": 3, "follower_count": 0, "following_count": 0},
+			{"user_id": 4, "follower_count": 10, "following_count": 10},
+		},
+		"tracks": {
+			{"track_id": 1, "owner_id": 1, "title": "big hit", "genre": "Electronic"},
+			{"track_id": 2, "owner_id": 2, "title": "small hit", "genre": "Jazz"},
+			{"track_id": 3, "owner_id": 3, "title": "no followers", "genre": "Jazz"},
+			{"track_id": 4, "owner_id": 2, "title": "old", "genre": "Jazz", "created_at": time.Now().AddDate(-1, 0, 0)},
+		},
+		"playlists": {
+			{"playlist_id": 1, "playlist_owner_id": 1, "playlist_name": "big list"},
+			{"playlist_id": 2, "playlist_owner_id": 2, "playlist_name": "small list", "is_private": true},
+		},
+		"plays": {
+			{"id": 1, "play_item_id": 1, "user_id": 4},
+			{"id": 2, "play_item_id": 1, "user_id": 4},
+			{"id": 3, "play_item_id": 2, "user_id": 4},
+			{"id": 4, "play_item_id": 3, "user_id": 4},
+			{"id": 5, "play_item_id": 4, "user_id": 4},
+			{"id": 6, "play_item_id": 4, "user_id": 4, "created_at": time.Now().AddDate(0, -2, 0)},
+			{"id": 7, "play_item_id": 2, "user_id": 4},
+		},
+		"reposts": {
+			{"user_id": 4, "repost_item_id": 1, "repost_type": "track"},
+			{"user_id": 4, "repost_item_id": 1, "repost_type": "playlist"},
+		},
+		"saves": {
+			{"user_id": 4, "save_item_id": 2, "save_type": "track"},
+			{"user_id": 4, "save_item_id": 2, "save_type": "playlist"},
+		},
+	})
+
+	job := NewTrendingJob(config.Cfg, pool)
+	require.NoError(t, job.run(ctx))
+
+	trendingIds := func(table, idColumn, trendingType, timeRange string) []int32 {
+		rows, err := pool.Query(ctx, `
+			SELECT `+idColumn+` FROM `+table+`
+			WHERE type = $1 AND version = $2 AND time_range = $3
+			ORDER BY score DESC, `+idColumn+` DESC`,
+			trendingType, TrendingVersion, timeRange)
+		require.NoError(t, err)
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+		require.NoError(t, err)
+		return ids
+	}
+
+	// Track 1 has a windowed repost, which is weighted heavily.
+	// Track 3's owner has too few followers to trend.
+	// Track 4 is older than a week, so its score has decayed below track 2's.
+	assert.Equal(t, []int32{1, 2, 4}, trendingIds("track_trending_scores", "track_id", TrendingTypeTracks, "week"))
+	assert.Equal(t, []int32{1, 2, 4}, trendingIds("track_trending_scores", "track_id", TrendingTypeTracks, "allTime"))
+
+	// Private playlists never trend
+	assert.Equal(t, []int32{1}, trendingIds("playlist_trending_scores", "playlist_id", TrendingTypePlaylists, "week"))
+
+	var genre string
+	err := pool.QueryRow(ctx, `SELECT genre FROM track_trending_scores WHERE track_id = 2 AND time_range = 'week' AND type = $1`, TrendingTypeTracks).Scan(&genre)
+	require.NoError(t, err)
+	assert.Equal(t, "Jazz", genre)
+
+	// Reruns replace scores rather than accumulating them
+	require.NoError(t, job.run(ctx))
+	assert.Equal(t, []int32{1, 2, 4}, trendingIds("track_trending_scores", "track_id", TrendingTypeTracks, "week"))
+}
@@ -8,13 +8,17 @@ import (
 	"os/signal"
 	"slices"
 	"syscall"
+	"time"
 
 	"bridgerton.audius.co/api"
 	"bridgerton.audius.co/config"
 	"bridgerton.audius.co/ddl"
 	"bridgerton.audius.co/esindexer"
 	"bridgerton.audius.co/indexer"
+	"bridgerton.audius.co/jobs"
+	"bridgerton.audius.co/logging"
 	solana_indexer "bridgerton.audius.co/solana/indexer"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func main() {
@@ -37,18 +41,72 @@ func main() {
 		{
 			fmt.Println("Running indexer...")
 			ddl.RunMigrations()
-			_, err := indexer.NewIndexer(indexer.CoreIndexerConfig{
-				DbUrl: config.Cfg.WriteDbUrl,
+
+			// A block file can stand in for a live audiusd node:
+			// bridge indexer ./blocks.pb
+			var blockSource indexer.BlockSource = indexer.NewSdkBlockSource(config.Cfg.AudiusdURL)
+			if len(os.Args) > 2 {
+				fileSource, err := indexer.NewFileBlockSource(os.Args[2])
+				if err != nil {
+					fmt.Println("Error reading block file:", err)
+					os.Exit(1)
+				}
+				blockSource = fileSource
+			}
+
+			coreIndexer, err := indexer.NewIndexer(indexer.CoreIndexerConfig{
+				DbUrl:       config.Cfg.WriteDbUrl,
+				ChainId:     config.Cfg.ChainId,
+				BlockSource: blockSource,
+				EntityManagerDomain: indexer.EntityManagerDomain{
+					ChainId:         config.Cfg.AcdcChainId,
+					ContractAddress: config.Cfg.AcdcEntityManagerAddress,
+				},
+				Logger: logging.NewZapLogger(config.Cfg).Named("CoreIndexer"),
 			})
 			if err != nil {
 				fmt.Println("Error creating indexer:", err)
 				os.Exit(1)
 			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
+			defer stop()
+
+			// trending and saved search notifications are written by
+			// the one process that indexes, rather than by every api replica
+			jobsPool, err := pgxpool.New(ctx, config.Cfg.WriteDbUrl)
+			if err != nil {
+				fmt.Println("Error connecting to database:", err)
+				os.Exit(1)
+			}
+			defer jobsPool.Close()
+			go jobs.NewTrendingJob(config.Cfg, jobsPool).
+				ScheduleEvery(ctx, time.Hour).Run(ctx)
+
+			if config.Cfg.EsUrl != "" {
+				esClient, err := esindexer.Dial(config.Cfg.EsUrl)
+				if err != nil {
+					fmt.Println("Error connecting to elasticsearch:", err)
+					os.Exit(1)
+				}
+				jobs.NewSavedSearchJob(config.Cfg, jobsPool, esClient).
+					ScheduleEvery(ctx, jobs.SavedSearchInterval)
+			}
+
+			if err := coreIndexer.Start(ctx); err != nil {
+				if !errors.Is(err, context.Canceled) {
+					panic(err)
+				}
+			}
 		}
 	case "es-indexer":
 		{
 
 			collections := os.Args[2:]
+			if slices.Contains(collections, "status") {
+				esindexer.PrintStatus()
+				return
+			}
 			drop := slices.Contains(collections, "drop")
 			fmt.Printf("Reindexing ElasticSearch (collections=%s, drop=%t)...\n", collections, drop)
 			esindexer.ReindexLegacy(drop, collections...)
@@ -4667,6 +4667,44 @@ end;
 $$;
 
 
+--
+-- Name: record_search_index_change(); Type: FUNCTION; Schema: public; Owner: -
+--
+
+CREATE FUNCTION public.record_search_index_change() RETURNS trigger
+    LANGUAGE plpgsql
+    AS $$
+declare
+  coll text;
+  doc_id integer;
+begin
+  case TG_TABLE_NAME
+    when 'users', 'aggregate_user' then
+      coll := 'users';
+      doc_id := new.user_id;
+    when 'tracks', 'aggregate_track' then
+      coll := 'tracks';
+      doc_id := new.track_id;
+    when 'playlists', 'playlist_tracks' then
+      coll := 'playlists';
+      doc_id := new.playlist_id;
+    when 'follows' then
+      coll := 'socials';
+      doc_id := new.follower_user_id;
+    when 'reposts', 'saves' then
+      coll := 'socials';
+      doc_id := new.user_id;
+  end case;
+
+  insert into search_index_changes (collection, id, changed_at)
+  values (coll, doc_id, clock_timestamp())
+  on conflict (collection, id) do update set changed_at = excluded.changed_at;
+
+  return null;
+end;
+$$;
+
+
 --
 -- Name: recreate_trending_params(); Type: FUNCTION; Schema: public; Owner: -
 --
@@ -6136,7 +6174,24 @@ CREATE TABLE public.chat_message (
     user_id integer NOT NULL,
     created_at timestamp without time zone NOT NULL,
     ciphertext text,
-    blast_id text
+    blast_id text,
+    edited_at timestamp without time zone,
+    deleted_at timestamp without time zone,
+    edit_history jsonb DEFAULT '[]'::jsonb NOT NULL
+);
+
+
+--
+-- Name: chat_message_attachments; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.chat_message_attachments (
+    message_id text NOT NULL,
+    ordinal integer NOT NULL,
+    type text NOT NULL,
+    cid text,
+    mime_type text,
+    entity_id integer
 );
 
 
@@ -6319,6 +6374,30 @@ CREATE TABLE public.comments (
 );
 
 
+--
+-- Name: comms_ws_presence; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE UNLOGGED TABLE public.comms_ws_presence (
+    replica_id text NOT NULL,
+    user_id integer NOT NULL,
+    heartbeat_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    away boolean DEFAULT false NOT NULL
+);
+
+
+--
+-- Name: comms_ws_recent; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE UNLOGGED TABLE public.comms_ws_recent (
+    user_id integer NOT NULL,
+    key text NOT NULL,
+    payload text NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
 --
 -- Name: core_app_state; Type: TABLE; Schema: public; Owner: -
 --
@@ -7441,6 +7520,51 @@ CREATE TABLE public.rpc_log (
 );
 
 
+--
+-- Name: saved_search_results; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.saved_search_results (
+    saved_search_id integer NOT NULL,
+    track_id integer NOT NULL,
+    found_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+--
+-- Name: saved_searches; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.saved_searches (
+    id integer NOT NULL,
+    user_id integer NOT NULL,
+    name text,
+    query jsonb NOT NULL,
+    last_run_at timestamp without time zone,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+--
+-- Name: saved_searches_id_seq; Type: SEQUENCE; Schema: public; Owner: -
+--
+
+CREATE SEQUENCE public.saved_searches_id_seq
+    AS integer
+    START WITH 1
+    INCREMENT BY 1
+    NO MINVALUE
+    NO MAXVALUE
+    CACHE 1;
+
+
+--
+-- Name: saved_searches_id_seq; Type: SEQUENCE OWNED BY; Schema: public; Owner: -
+--
+
+ALTER SEQUENCE public.saved_searches_id_seq OWNED BY public.saved_searches.id;
+
+
 --
 -- Name: saves; Type: TABLE; Schema: public; Owner: -
 --
@@ -7460,6 +7584,17 @@ CREATE TABLE public.saves (
 );
 
 
+--
+-- Name: search_index_changes; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.search_index_changes (
+    collection text NOT NULL,
+    id integer NOT NULL,
+    changed_at timestamp with time zone NOT NULL
+);
+
+
 --
 -- Name: schema_migrations; Type: TABLE; Schema: public; Owner: -
 --
@@ -7507,7 +7642,8 @@ CREATE TABLE public.skipped_transactions (
     txhash character varying NOT NULL,
     created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
     updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
-    level public.skippedtransactionlevel DEFAULT 'node'::public.skippedtransactionlevel NOT NULL
+    level public.skippedtransactionlevel DEFAULT 'node'::public.skippedtransactionlevel NOT NULL,
+    reason text
 );
 
 
@@ -8815,6 +8951,13 @@ ALTER TABLE ONLY public.plays ALTER COLUMN id SET DEFAULT nextval('public.plays_
 ALTER TABLE ONLY public.reactions ALTER COLUMN id SET DEFAULT nextval('public.reactions_id_seq'::regclass);
 
 
+--
+-- Name: saved_searches id; Type: DEFAULT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.saved_searches ALTER COLUMN id SET DEFAULT nextval('public.saved_searches_id_seq'::regclass);
+
+
 --
 -- Name: skipped_transactions id; Type: DEFAULT; Schema: public; Owner: -
 --
@@ -9202,6 +9345,14 @@ ALTER TABLE ONLY public.chat_message
     ADD CONSTRAINT chat_message_pkey PRIMARY KEY (message_id);
 
 
+--
+-- Name: chat_message_attachments chat_message_attachments_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.chat_message_attachments
+    ADD CONSTRAINT chat_message_attachments_pkey PRIMARY KEY (message_id, ordinal);
+
+
 --
 -- Name: chat_message_reactions chat_message_reactions_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -9282,6 +9433,22 @@ ALTER TABLE ONLY public.comments
     ADD CONSTRAINT comments_pkey PRIMARY KEY (comment_id);
 
 
+--
+-- Name: comms_ws_presence comms_ws_presence_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.comms_ws_presence
+    ADD CONSTRAINT comms_ws_presence_pkey PRIMARY KEY (replica_id, user_id);
+
+
+--
+-- Name: comms_ws_recent comms_ws_recent_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.comms_ws_recent
+    ADD CONSTRAINT comms_ws_recent_pkey PRIMARY KEY (user_id, key);
+
+
 --
 -- Name: core_app_state core_app_state_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -9730,6 +9897,22 @@ ALTER TABLE ONLY public.rpc_log
     ADD CONSTRAINT rpc_log_pkey PRIMARY KEY (sig);
 
 
+--
+-- Name: saved_search_results saved_search_results_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.saved_search_results
+    ADD CONSTRAINT saved_search_results_pkey PRIMARY KEY (saved_search_id, track_id);
+
+
+--
+-- Name: saved_searches saved_searches_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.saved_searches
+    ADD CONSTRAINT saved_searches_pkey PRIMARY KEY (id);
+
+
 --
 -- Name: saves saves_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -9746,6 +9929,14 @@ ALTER TABLE ONLY public.schema_migrations
     ADD CONSTRAINT schema_migrations_pkey PRIMARY KEY (version);
 
 
+--
+-- Name: search_index_changes search_index_changes_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.search_index_changes
+    ADD CONSTRAINT search_index_changes_pkey PRIMARY KEY (collection, id);
+
+
 --
 -- Name: schema_version schema_version_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
@@ -10250,6 +10441,20 @@ CREATE INDEX chat_chat_id_idx ON public.chat USING btree (chat_id);
 CREATE INDEX chat_member_user_idx ON public.chat_member USING btree (user_id);
 
 
+--
+-- Name: comms_ws_presence_user_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX comms_ws_presence_user_id_idx ON public.comms_ws_presence USING btree (user_id, heartbeat_at);
+
+
+--
+-- Name: comms_ws_recent_created_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX comms_ws_recent_created_at_idx ON public.comms_ws_recent USING btree (created_at);
+
+
 --
 -- Name: eth_registered_endpoints_wallet_idx; Type: INDEX; Schema: public; Owner: -
 --
@@ -10369,6 +10574,13 @@ CREATE INDEX idx_challenge_disbursements_created_at ON public.challenge_disburse
 CREATE INDEX idx_challenge_disbursements_slot ON public.challenge_disbursements USING btree (slot);
 
 
+--
+-- Name: idx_chat_blast_created_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_chat_blast_created_at ON public.chat_blast USING btree (created_at);
+
+
 --
 -- Name: idx_chat_message_chat_id; Type: INDEX; Schema: public; Owner: -
 --
@@ -10383,6 +10595,13 @@ CREATE INDEX idx_chat_message_chat_id ON public.chat_message USING btree (chat_i
 CREATE INDEX idx_chat_message_reactions_message_id ON public.chat_message_reactions USING btree (message_id);
 
 
+--
+-- Name: idx_chat_message_reactions_updated_at; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX idx_chat_message_reactions_updated_at ON public.chat_message_reactions USING btree (updated_at);
+
+
 --
 -- Name: idx_chat_message_user_id; Type: INDEX; Schema: public; Owner: -
 --
@@ -11167,6 +11386,27 @@ CREATE INDEX reposts_user_idx ON public.reposts USING btree (user_id, repost_typ
 CREATE INDEX rpc_log_applied_at_idx ON public.rpc_log USING brin (applied_at);
 
 
+--
+-- Name: saved_search_results_found_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX saved_search_results_found_at_idx ON public.saved_search_results USING btree (saved_search_id, found_at);
+
+
+--
+-- Name: saved_searches_last_run_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX saved_searches_last_run_at_idx ON public.saved_searches USING btree (last_run_at NULLS FIRST);
+
+
+--
+-- Name: saved_searches_user_id_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX saved_searches_user_id_idx ON public.saved_searches USING btree (user_id);
+
+
 --
 -- Name: saves_item_idx; Type: INDEX; Schema: public; Owner: -
 --
@@ -11188,6 +11428,13 @@ CREATE INDEX saves_new_blocknumber_idx ON public.saves USING btree (blocknumber)
 CREATE INDEX saves_user_idx ON public.saves USING btree (user_id, save_type, save_item_id, is_delete);
 
 
+--
+-- Name: search_index_changes_changed_at_idx; Type: INDEX; Schema: public; Owner: -
+--
+
+CREATE INDEX search_index_changes_changed_at_idx ON public.search_index_changes USING btree (collection, changed_at);
+
+
 --
 -- Name: shares_item_idx; Type: INDEX; Schema: public; Owner: -
 --
@@ -11909,6 +12156,69 @@ CREATE TRIGGER trg_reposts AFTER INSERT OR UPDATE ON public.reposts FOR EACH ROW
 CREATE TRIGGER trg_saves AFTER INSERT OR UPDATE ON public.saves FOR EACH ROW EXECUTE FUNCTION public.on_new_row();
 
 
+--
+-- Name: aggregate_track trg_search_index_aggregate_track; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER trg_search_index_aggregate_track AFTER INSERT OR UPDATE ON public.aggregate_track FOR EACH ROW EXECUTE FUNCTION public.record_search_index_change();
+
+
+--
+-- Name: aggregate_user trg_search_index_aggregate_user; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER trg_search_index_aggregate_user AFTER INSERT OR UPDATE ON public.aggregate_user FOR EACH ROW EXECUTE FUNCTION public.record_search_index_change();
+
+
+--
+-- Name: follows trg_search_index_follows; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER trg_search_index_follows AFTER INSERT OR UPDATE ON public.follows FOR EACH ROW EXECUTE FUNCTION public.record_search_index_change();
+
+
+--
+-- Name: playlist_tracks trg_search_index_playlist_tracks; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER trg_search_index_playlist_tracks AFTER INSERT OR UPDATE ON public.playlist_tracks FOR EACH ROW EXECUTE FUNCTION public.record_search_index_change();
+
+
+--
+-- Name: playlists trg_search_index_playlists; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER trg_search_index_playlists AFTER INSERT OR UPDATE ON public.playlists FOR EACH ROW EXECUTE FUNCTION public.record_search_index_change();
+
+
+--
+-- Name: reposts trg_search_index_reposts; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER trg_search_index_reposts AFTER INSERT OR UPDATE ON public.reposts FOR EACH ROW EXECUTE FUNCTION public.record_search_index_change();
+
+
+--
+-- Name: saves trg_search_index_saves; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER trg_search_index_saves AFTER INSERT OR UPDATE ON public.saves FOR EACH ROW EXECUTE FUNCTION public.record_search_index_change();
+
+
+--
+-- Name: tracks trg_search_index_tracks; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER trg_search_index_tracks AFTER INSERT OR UPDATE ON public.tracks FOR EACH ROW EXECUTE FUNCTION public.record_search_index_change();
+
+
+--
+-- Name: users trg_search_index_users; Type: TRIGGER; Schema: public; Owner: -
+--
+
+CREATE TRIGGER trg_search_index_users AFTER INSERT OR UPDATE ON public.users FOR EACH ROW EXECUTE FUNCTION public.record_search_index_change();
+
+
 --
 -- Name: shares trg_shares; Type: TRIGGER; Schema: public; Owner: -
 --
@@ -11984,6 +12294,14 @@ ALTER TABLE ONLY public.chat_message
     ADD CONSTRAINT chat_message_chat_member_fkey FOREIGN KEY (chat_id, user_id) REFERENCES public.chat_member(chat_id, user_id) ON DELETE CASCADE;
 
 
+--
+-- Name: chat_message_attachments chat_message_attachments_message_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.chat_message_attachments
+    ADD CONSTRAINT chat_message_attachments_message_id_fkey FOREIGN KEY (message_id) REFERENCES public.chat_message(message_id) ON DELETE CASCADE;
+
+
 --
 -- Name: chat_message_reactions chat_message_reactions_message_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -12136,6 +12454,14 @@ ALTER TABLE ONLY public.revert_blocks
     ADD CONSTRAINT revert_blocks_blocknumber_fkey FOREIGN KEY (blocknumber) REFERENCES public.blocks(number) ON DELETE CASCADE;
 
 
+--
+-- Name: saved_search_results saved_search_results_saved_search_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.saved_search_results
+    ADD CONSTRAINT saved_search_results_saved_search_id_fkey FOREIGN KEY (saved_search_id) REFERENCES public.saved_searches(id) ON DELETE CASCADE;
+
+
 --
 -- Name: saves saves_blocknumber_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
@@ -1,6 +1,8 @@
 CREATE DATABASE test_api TEMPLATE postgres;
 CREATE DATABASE test_comms TEMPLATE postgres;
 CREATE DATABASE test_database TEMPLATE postgres;
+CREATE DATABASE test_esindexer TEMPLATE postgres;
 CREATE DATABASE test_hll TEMPLATE postgres;
 CREATE DATABASE test_indexer TEMPLATE postgres;
+CREATE DATABASE test_jobs TEMPLATE postgres;
 CREATE DATABASE test_solana_indexer TEMPLATE postgres;